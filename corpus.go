@@ -0,0 +1,406 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/thepudds/fzgo/fuzz"
+)
+
+var (
+	flagDistillDir string
+	flagInplace    bool
+)
+
+var distillFlagDefs = []fuzz.FlagDef{
+	{Name: "fuzz", Ptr: &flagFuzzFunc, Description: "distill the corpus of functions matching `regexp`"},
+	{Name: "fuzzdir", Ptr: &flagFuzzDir, Description: "use the corpus stored under `dir` (default GOPATH/pkg/fuzz/corpus)"},
+	{Name: "distilldir", Ptr: &flagDistillDir, Description: "write the distilled corpus to `dir`"},
+	{Name: "inplace", Ptr: &flagInplace, Description: "remove the corpus files that are not part of the distilled corpus"},
+	{Name: "timeout", Ptr: &flagTimeout, Description: "fail an individual call to a fuzz function after duration `d` (default 10s, minimum 1s)"},
+	{Name: "v", Ptr: &flagVerbose, Description: "verbose: print additional output"},
+}
+
+var addFlagDefs = []fuzz.FlagDef{
+	{Name: "fuzz", Ptr: &flagFuzzFunc, Description: "add an input for the function matching `regexp`"},
+	{Name: "fuzzdir", Ptr: &flagFuzzDir, Description: "add the input to the corpus stored under `dir` (default GOPATH/pkg/fuzz/corpus)"},
+	{Name: "json", Ptr: &flagJSON, Description: "parse each value as JSON, including for string and []byte parameters"},
+}
+
+var showFlagDefs = []fuzz.FlagDef{
+	{Name: "fuzz", Ptr: &flagFuzzFunc, Description: "decode inputs for the function matching `regexp`"},
+	{Name: "fuzzdir", Ptr: &flagFuzzDir, Description: "also look for a corpus stored under `dir`"},
+	{Name: "json", Ptr: &flagJSON, Description: "print each input as a line of JSON rather than as Go declarations"},
+	{Name: "timeout", Ptr: &flagTimeout, Description: "fail an individual call to a fuzz function after duration `d` (default 10s, minimum 1s)"},
+}
+
+// corpusMain implements the 'fzgo corpus' commands. args is os.Args[2:].
+func corpusMain(args []string) int {
+	if len(args) == 0 {
+		corpusUsage()
+		return ArgErr
+	}
+	switch args[0] {
+	case "distill":
+		return corpusDistill(args[1:])
+	case "show":
+		return corpusShow(args[1:])
+	case "add":
+		return corpusAdd(args[1:])
+	default:
+		fmt.Printf("fzgo: unknown corpus command %q\n", args[0])
+		corpusUsage()
+		return ArgErr
+	}
+}
+
+func corpusUsage() {
+	fmt.Printf("\nUsage:\n\n")
+	fmt.Printf("   fzgo corpus distill -fuzz FuzzFoo [-fuzzdir dir] [-distilldir dir | -inplace] [pkg]\n")
+	fmt.Printf("   fzgo corpus show -fuzz FuzzFoo [-fuzzdir dir] [-json] [pkg] [file...]\n")
+	fmt.Printf("   fzgo corpus add -fuzz FuzzFoo [-fuzzdir dir] [-json] [pkg] -- value...\n\n")
+	fmt.Printf("'fzgo corpus distill' finds a minimal subset of a corpus that preserves the total coverage of the corpus.\n")
+	fmt.Printf("Without -distilldir or -inplace, it only reports the files that would be removed.\n")
+	fmt.Printf("'fzgo corpus show' decodes each input into the named parameters of the fuzz function's signature\n")
+	fmt.Printf("and prints them as Go declarations, or as JSON with -json. Without files, it shows the inputs in the\n")
+	fmt.Printf("corpus and crashers directories for the fuzz function.\n")
+	fmt.Printf("'fzgo corpus add' encodes one value per parameter of the fuzz function into a corpus input that decodes\n")
+	fmt.Printf("to exactly those values. Values for string and []byte parameters are used as is, and other values are JSON,\n")
+	fmt.Printf("such as 'true' or '{\"A\": 1}'. With -json, every value is JSON, with []byte values in base64 as with encoding/json.\n\n")
+}
+
+// corpusDistill implements 'fzgo corpus distill'.
+func corpusDistill(args []string) int {
+	fs, err := fuzz.FlagSet("fzgo corpus distill", distillFlagDefs, func(fs *flag.FlagSet) func() {
+		return func() {
+			corpusUsage()
+			fs.SetOutput(os.Stdout)
+			fs.PrintDefaults()
+		}
+	})
+	if err != nil {
+		fmt.Println("fzgo:", err)
+		return OtherErr
+	}
+	pkgPattern, err := fuzz.ParseArgs(args, fs)
+	if err == flag.ErrHelp {
+		return ArgErr
+	} else if err != nil {
+		fmt.Println("fzgo:", err)
+		return ArgErr
+	}
+	if flagFuzzFunc == "" {
+		fmt.Println("fzgo: corpus distill requires the -fuzz flag")
+		return ArgErr
+	}
+	if flagDistillDir != "" && flagInplace {
+		fmt.Println("fzgo: -distilldir and -inplace cannot be used together")
+		return ArgErr
+	}
+	funcTimeout := flagTimeout
+	if funcTimeout == 0 {
+		funcTimeout = 10 * time.Second
+	}
+
+	functions, err := fuzz.FindFunc(pkgPattern, flagFuzzFunc, nil, true)
+	if err != nil {
+		fmt.Println("fzgo:", err)
+		return OtherErr
+	} else if len(functions) == 0 {
+		fmt.Printf("fzgo: failed to find fuzz function for pattern %v and func %v\n", pkgPattern, flagFuzzFunc)
+		return OtherErr
+	} else if len(functions) > 1 && flagDistillDir != "" {
+		fmt.Printf("fzgo: -distilldir requires -fuzz to match a single function, but matched %d functions\n", len(functions))
+		return ArgErr
+	}
+
+	for _, function := range functions {
+		corpusDir := filepath.Join(determineWorkDir(function, flagFuzzDir), "corpus")
+		if !fuzz.PathExists(corpusDir) {
+			fmt.Printf("fzgo: no corpus found for %s in %s\n", function.FuzzName(), corpusDir)
+			continue
+		}
+		result, err := fuzz.DistillCorpus(function, corpusDir, fuzz.DistillOptions{FuncTimeout: funcTimeout, Verbose: flagVerbose})
+		if err != nil {
+			fmt.Println("fzgo:", err)
+			return OtherErr
+		}
+		fmt.Printf("fzgo: distilled %s corpus from %d to %d files covering %d blocks in %s\n",
+			function.FuzzName(), len(result.Kept)+len(result.Dropped), len(result.Kept), result.Blocks, result.Coverpkg)
+		if len(result.Failed) > 0 {
+			fmt.Printf("fzgo: kept %d files that crashed or hung: %s\n", len(result.Failed), strings.Join(result.Failed, ", "))
+		}
+
+		switch {
+		case flagDistillDir != "":
+			if err := os.MkdirAll(flagDistillDir, os.ModePerm); err != nil {
+				fmt.Println("fzgo:", err)
+				return OtherErr
+			}
+			for _, name := range result.Kept {
+				if err := fuzz.CopyFile(filepath.Join(flagDistillDir, name), filepath.Join(corpusDir, name)); err != nil {
+					fmt.Println("fzgo:", err)
+					return OtherErr
+				}
+			}
+			fmt.Printf("fzgo: wrote distilled corpus to %s\n", flagDistillDir)
+		case flagInplace:
+			for _, name := range result.Dropped {
+				if err := os.Remove(filepath.Join(corpusDir, name)); err != nil {
+					fmt.Println("fzgo:", err)
+					return OtherErr
+				}
+			}
+			// remember what we removed, so that copying from another corpus location
+			// at the start of a later fuzzing run does not restore it.
+			if err := fuzz.RecordDropped(filepath.Dir(corpusDir), result.Dropped); err != nil {
+				fmt.Println("fzgo:", err)
+				return OtherErr
+			}
+			fmt.Printf("fzgo: removed %d files from %s\n", len(result.Dropped), corpusDir)
+		default:
+			for _, name := range result.Dropped {
+				fmt.Printf("fzgo: would remove %s\n", filepath.Join(corpusDir, name))
+			}
+		}
+	}
+	return Success
+}
+
+// corpusAdd implements 'fzgo corpus add'.
+func corpusAdd(args []string) int {
+	fs, err := fuzz.FlagSet("fzgo corpus add", addFlagDefs, func(fs *flag.FlagSet) func() {
+		return func() {
+			corpusUsage()
+			fs.SetOutput(os.Stdout)
+			fs.PrintDefaults()
+		}
+	})
+	if err != nil {
+		fmt.Println("fzgo:", err)
+		return OtherErr
+	}
+	var values []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, values = args[:i], args[i+1:]
+			break
+		}
+	}
+	pkgPattern, err := fuzz.ParseArgs(args, fs)
+	if err == flag.ErrHelp {
+		return ArgErr
+	} else if err != nil {
+		fmt.Println("fzgo:", err)
+		return ArgErr
+	}
+	if flagFuzzFunc == "" {
+		fmt.Println("fzgo: corpus add requires the -fuzz flag")
+		return ArgErr
+	}
+	if len(values) == 0 {
+		fmt.Println("fzgo: corpus add requires values for the fuzz function's parameters following '--'")
+		return ArgErr
+	}
+
+	functions, err := fuzz.FindFunc(pkgPattern, flagFuzzFunc, nil, true)
+	if err != nil {
+		fmt.Println("fzgo:", err)
+		return OtherErr
+	} else if len(functions) == 0 {
+		fmt.Printf("fzgo: failed to find fuzz function for pattern %v and func %v\n", pkgPattern, flagFuzzFunc)
+		return OtherErr
+	} else if len(functions) > 1 {
+		fmt.Printf("fzgo: corpus add requires -fuzz to match a single function, but matched %d functions\n", len(functions))
+		return ArgErr
+	}
+	function := functions[0]
+
+	var jsonArgs []json.RawMessage
+	if flagJSON {
+		for _, v := range values {
+			if !json.Valid([]byte(v)) {
+				fmt.Printf("fzgo: value is not valid JSON: %s\n", v)
+				return ArgErr
+			}
+			jsonArgs = append(jsonArgs, json.RawMessage(v))
+		}
+	} else {
+		jsonArgs, err = fuzz.TextArgs(function, values)
+		if err != nil {
+			fmt.Println("fzgo:", err)
+			return ArgErr
+		}
+	}
+	data, err := fuzz.EncodeInput(function, jsonArgs)
+	if err != nil {
+		fmt.Println("fzgo:", err)
+		return OtherErr
+	}
+	corpusDir := filepath.Join(determineWorkDir(function, flagFuzzDir), "corpus")
+	path, existed, err := fuzz.AddInput(corpusDir, data)
+	if err != nil {
+		fmt.Println("fzgo:", err)
+		return OtherErr
+	}
+	if existed {
+		fmt.Printf("fzgo: %s corpus already contains %s\n", function.FuzzName(), path)
+		return Success
+	}
+	fmt.Printf("fzgo: added %s to %s corpus\n", path, function.FuzzName())
+	return Success
+}
+
+// corpusShow implements 'fzgo corpus show'.
+func corpusShow(args []string) int {
+	fs, err := fuzz.FlagSet("fzgo corpus show", showFlagDefs, func(fs *flag.FlagSet) func() {
+		return func() {
+			corpusUsage()
+			fs.SetOutput(os.Stdout)
+			fs.PrintDefaults()
+		}
+	})
+	if err != nil {
+		fmt.Println("fzgo:", err)
+		return OtherErr
+	}
+	files, args := splitFiles(args)
+	pkgPattern, err := fuzz.ParseArgs(args, fs)
+	if err == flag.ErrHelp {
+		return ArgErr
+	} else if err != nil {
+		fmt.Println("fzgo:", err)
+		return ArgErr
+	}
+	if flagFuzzFunc == "" {
+		fmt.Println("fzgo: corpus show requires the -fuzz flag")
+		return ArgErr
+	}
+	funcTimeout := flagTimeout
+	if funcTimeout == 0 {
+		funcTimeout = 10 * time.Second
+	}
+
+	functions, err := fuzz.FindFunc(pkgPattern, flagFuzzFunc, nil, true)
+	if err != nil {
+		fmt.Println("fzgo:", err)
+		return OtherErr
+	} else if len(functions) == 0 {
+		fmt.Printf("fzgo: failed to find fuzz function for pattern %v and func %v\n", pkgPattern, flagFuzzFunc)
+		return OtherErr
+	} else if len(functions) > 1 && len(files) > 0 {
+		fmt.Printf("fzgo: corpus show with files requires -fuzz to match a single function, but matched %d functions\n", len(functions))
+		return ArgErr
+	}
+
+	for _, function := range functions {
+		paths := files
+		if len(paths) == 0 {
+			paths = inputFiles(function)
+			if len(paths) == 0 {
+				fmt.Printf("fzgo: no corpus found for %s\n", function.FuzzName())
+				continue
+			}
+		}
+		results, err := fuzz.DecodeInputs(function, paths, funcTimeout)
+		if err != nil {
+			fmt.Println("fzgo:", err)
+			return OtherErr
+		}
+		for _, result := range results {
+			if flagJSON {
+				err = printDecodedJSON(result)
+			} else {
+				printDecoded(result)
+			}
+			if err != nil {
+				fmt.Println("fzgo:", err)
+				return OtherErr
+			}
+		}
+	}
+	return Success
+}
+
+// splitFiles separates the args for 'fzgo corpus show' that name existing files
+// from the flags and package pattern.
+func splitFiles(args []string) (files []string, rest []string) {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			if info, err := os.Stat(arg); err == nil && !info.IsDir() {
+				files = append(files, arg)
+				continue
+			}
+		}
+		rest = append(rest, arg)
+	}
+	return files, rest
+}
+
+// inputFiles returns the corpus files and crashers for function in each of its work dirs.
+func inputFiles(function fuzz.Func) []string {
+	var paths []string
+	for _, workDir := range workDirsToCheck(function) {
+		entries, err := ioutil.ReadDir(filepath.Join(workDir, "corpus"))
+		if err == nil {
+			for _, entry := range entries {
+				if entry.Mode().IsRegular() {
+					paths = append(paths, filepath.Join(workDir, "corpus", entry.Name()))
+				}
+			}
+		}
+		for _, c := range fuzz.Crashers(workDir) {
+			paths = append(paths, c.Path)
+		}
+	}
+	return paths
+}
+
+// printDecoded prints an input as Go declarations for each of the fuzz function's arguments.
+func printDecoded(result fuzz.Decoded) {
+	fmt.Printf("%s:\n", result.Path)
+	if result.Err != nil {
+		fmt.Printf("\tfailed to decode: %v\n\n", result.Err)
+		return
+	}
+	for _, arg := range result.Args {
+		fmt.Printf("\t%s\n", arg.Decl())
+	}
+	fmt.Println()
+}
+
+// printDecodedJSON prints an input as a line of JSON.
+func printDecodedJSON(result fuzz.Decoded) error {
+	type jsonArg struct {
+		Name  string
+		Type  string
+		Value json.RawMessage
+	}
+	out := struct {
+		File  string
+		Args  []jsonArg `json:",omitempty"`
+		Error string    `json:",omitempty"`
+	}{File: result.Path}
+	if result.Err != nil {
+		out.Error = result.Err.Error()
+	}
+	for _, arg := range result.Args {
+		v, err := arg.Value()
+		if err != nil {
+			return err
+		}
+		out.Args = append(out.Args, jsonArg{arg.Name, arg.Type, v})
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
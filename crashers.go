@@ -0,0 +1,302 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/thepudds/fzgo/fuzz"
+)
+
+var flagTestFile string
+
+var crashersFlagDefs = []fuzz.FlagDef{
+	{Name: "fuzz", Ptr: &flagFuzzFunc, Description: "triage the crashers of functions matching `regexp` (default all fuzz functions)"},
+	{Name: "fuzzdir", Ptr: &flagFuzzDir, Description: "also look for crashers stored under `dir`"},
+}
+
+var exportFlagDefs = []fuzz.FlagDef{
+	{Name: "fuzz", Ptr: &flagFuzzFunc, Description: "export a crasher for the function matching `regexp`"},
+	{Name: "fuzzdir", Ptr: &flagFuzzDir, Description: "also look for crashers stored under `dir`"},
+	{Name: "testfile", Ptr: &flagTestFile, Description: "write the test to `file` (default <func>_crasher_<hash>_test.go in the package directory)"},
+	{Name: "timeout", Ptr: &flagTimeout, Description: "fail an individual call to a fuzz function after duration `d` (default 10s, minimum 1s)"},
+}
+
+// crashersMain implements the 'fzgo crashers' commands. args is os.Args[2:].
+func crashersMain(args []string) int {
+	if len(args) == 0 {
+		crashersUsage()
+		return ArgErr
+	}
+	switch args[0] {
+	case "list":
+		return crashersList(args[1:])
+	case "show":
+		id, rest := splitID(args[1:])
+		if id == "" {
+			fmt.Println("fzgo: crashers show requires a signature id or crasher hash")
+			crashersUsage()
+			return ArgErr
+		}
+		return crashersShow(id, rest)
+	case "export":
+		hash, rest := splitID(args[1:])
+		if hash == "" {
+			fmt.Println("fzgo: crashers export requires a crasher hash")
+			crashersUsage()
+			return ArgErr
+		}
+		return crashersExport(hash, rest)
+	default:
+		fmt.Printf("fzgo: unknown crashers command %q\n", args[0])
+		crashersUsage()
+		return ArgErr
+	}
+}
+
+func crashersUsage() {
+	fmt.Printf("\nUsage:\n\n")
+	fmt.Printf("   fzgo crashers list [-fuzz regexp] [-fuzzdir dir] [pkg]\n")
+	fmt.Printf("   fzgo crashers show <id or hash> [-fuzz regexp] [-fuzzdir dir] [pkg]\n")
+	fmt.Printf("   fzgo crashers export -fuzz FuzzFoo [-fuzzdir dir] [-testfile file] <hash> [pkg]\n\n")
+	fmt.Printf("'fzgo crashers list' groups the crashers for a package's fuzz functions by crash signature,\n")
+	fmt.Printf("where a signature is the panic message along with the top of the crashing stack.\n")
+	fmt.Printf("'fzgo crashers show' prints the details of a signature, which can be selected by a prefix of\n")
+	fmt.Printf("its id or of one of its crashers' hashes.\n")
+	fmt.Printf("'fzgo crashers export' writes a _test.go file with a regression test that calls the fuzz function\n")
+	fmt.Printf("with the crasher matching a prefix of hash, using Go literals for the arguments of a rich signature.\n\n")
+}
+
+// splitID returns the first non-flag argument, which is the id or hash for a crashers command,
+// along with the remaining args. The flags for the crashers commands that take a value
+// are assumed to be in the form '-flag value' unless they are in the form '-flag=value'.
+func splitID(args []string) (string, []string) {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case strings.HasPrefix(args[i], "-"):
+			if !strings.Contains(args[i], "=") {
+				i++
+			}
+		default:
+			rest := append(append([]string(nil), args[:i]...), args[i+1:]...)
+			return args[i], rest
+		}
+	}
+	return "", args
+}
+
+// findFuncs parses args for a crashers command and finds the matching fuzz functions.
+// If args do not include -fuzz, all fuzz functions are found.
+func findFuncs(name string, defs []fuzz.FlagDef, args []string) ([]fuzz.Func, int) {
+	fs, err := fuzz.FlagSet(name, defs, func(fs *flag.FlagSet) func() {
+		return func() {
+			crashersUsage()
+			fs.SetOutput(os.Stdout)
+			fs.PrintDefaults()
+		}
+	})
+	if err != nil {
+		fmt.Println("fzgo:", err)
+		return nil, OtherErr
+	}
+	// unlike 'fzgo test', -fuzz is optional here, but fuzz.ParseArgs only parses args with -fuzz or -run.
+	if _, _, ok := fuzz.FindTestFlag(args, []string{"fuzz"}); !ok {
+		args = append([]string{"-fuzz=."}, args...)
+	}
+	pkgPattern, err := fuzz.ParseArgs(args, fs)
+	if err == flag.ErrHelp {
+		return nil, ArgErr
+	} else if err != nil {
+		fmt.Println("fzgo:", err)
+		return nil, ArgErr
+	}
+
+	functions, err := fuzz.FindFunc(pkgPattern, flagFuzzFunc, nil, true)
+	if err != nil {
+		fmt.Println("fzgo:", err)
+		return nil, OtherErr
+	} else if len(functions) == 0 {
+		fmt.Printf("fzgo: failed to find fuzz function for pattern %v and func %v\n", pkgPattern, flagFuzzFunc)
+		return nil, OtherErr
+	}
+	return functions, Success
+}
+
+// triage finds the fuzz functions for a crashers command and records their crashers in the
+// triage database for each package, returning the databases in order of package.
+// args are the arguments following the crashers command.
+func triage(name string, args []string) ([]*fuzz.TriageDB, int) {
+	functions, status := findFuncs(name, crashersFlagDefs, args)
+	if status != Success {
+		return nil, status
+	}
+	dbs, err := updateTriage(functions)
+	if err != nil {
+		fmt.Println("fzgo:", err)
+		return nil, OtherErr
+	}
+	return dbs, Success
+}
+
+// updateTriage records the crashers for functions in the triage database for each package.
+// It is also used after fuzzing so that new crashers are recorded promptly.
+func updateTriage(functions []fuzz.Func) ([]*fuzz.TriageDB, error) {
+	var pkgs []string
+	dbs := make(map[string]*fuzz.TriageDB)
+	for _, function := range functions {
+		db := dbs[function.PkgPath]
+		if db == nil {
+			var err error
+			db, err = fuzz.LoadTriageDB(function.PkgPath)
+			if err != nil {
+				return nil, err
+			}
+			dbs[function.PkgPath] = db
+			pkgs = append(pkgs, function.PkgPath)
+		}
+		db.Update(function, workDirsToCheck(function))
+	}
+	sort.Strings(pkgs)
+	var result []*fuzz.TriageDB
+	for _, pkg := range pkgs {
+		if err := dbs[pkg].Save(); err != nil {
+			return nil, err
+		}
+		result = append(result, dbs[pkg])
+	}
+	return result, nil
+}
+
+// crashersList implements 'fzgo crashers list'.
+func crashersList(args []string) int {
+	dbs, status := triage("fzgo crashers list", args)
+	if status != Success {
+		return status
+	}
+	for _, db := range dbs {
+		if len(db.Groups) == 0 {
+			fmt.Printf("fzgo: no crashers found for %s\n", db.Package)
+			continue
+		}
+		crashers := 0
+		for _, g := range db.Groups {
+			crashers += len(g.Crashers)
+		}
+		fmt.Printf("fzgo: %d crash signatures for %d crashers in %s\n\n", len(db.Groups), crashers, db.Package)
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCOUNT\tFIRST SEEN\tFUZZ FUNCS\tSIGNATURE")
+		for _, g := range db.Groups {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", g.ID, len(g.Crashers), g.FirstSeen.Format("2006-01-02 15:04:05"),
+				strings.Join(sortedKeys(g.Counts()), ","), g.Signature())
+		}
+		w.Flush()
+		fmt.Println()
+	}
+	return Success
+}
+
+// crashersShow implements 'fzgo crashers show'.
+func crashersShow(id string, args []string) int {
+	dbs, status := triage("fzgo crashers show", args)
+	if status != Success {
+		return status
+	}
+	found := false
+	for _, db := range dbs {
+		for _, g := range db.Find(id) {
+			found = true
+			fmt.Printf("signature %s in %s\n", g.ID, db.Package)
+			fmt.Printf("  %s\n", g.Message)
+			for _, frame := range g.Frames {
+				fmt.Printf("    %s\n", frame)
+			}
+			fmt.Printf("first seen: %s\n", g.FirstSeen.Format(time.RFC3339))
+			fmt.Printf("last seen:  %s\n", g.LastSeen.Format(time.RFC3339))
+			counts := g.Counts()
+			fmt.Printf("count:      %d\n", len(g.Crashers))
+			for _, test := range sortedKeys(counts) {
+				fmt.Printf("  %s: %d\n", test, counts[test])
+			}
+			fmt.Printf("crashers:\n")
+			for _, c := range g.Crashers {
+				fmt.Printf("  %s %s (first seen %s)\n", c.Test, c.Hash, c.FirstSeen.Format(time.RFC3339))
+				for _, path := range c.Paths {
+					fmt.Printf("    %s\n", path)
+				}
+				fmt.Printf("    to run: fzgo test -fuzz=%s -run=TestCrashers/%s %s\n", c.Test, c.Hash, db.Package)
+			}
+			fmt.Println()
+		}
+	}
+	if !found {
+		fmt.Printf("fzgo: no crash signature or crasher found matching %q\n", id)
+		return OtherErr
+	}
+	return Success
+}
+
+// crashersExport implements 'fzgo crashers export'.
+func crashersExport(hash string, args []string) int {
+	if _, _, ok := fuzz.FindTestFlag(args, []string{"fuzz"}); !ok {
+		fmt.Println("fzgo: crashers export requires the -fuzz flag")
+		return ArgErr
+	}
+	functions, status := findFuncs("fzgo crashers export", exportFlagDefs, args)
+	if status != Success {
+		return status
+	}
+	if len(functions) > 1 {
+		fmt.Printf("fzgo: crashers export requires -fuzz to match a single function, but matched %d functions\n", len(functions))
+		return ArgErr
+	}
+	function := functions[0]
+	funcTimeout := flagTimeout
+	if funcTimeout == 0 {
+		funcTimeout = 10 * time.Second
+	}
+
+	crashers := fuzz.FindCrashers(workDirsToCheck(function), hash)
+	switch {
+	case len(crashers) == 0:
+		fmt.Printf("fzgo: no crasher found for %s matching %q\n", function.FuzzName(), hash)
+		return OtherErr
+	case len(crashers) > 1:
+		fmt.Printf("fzgo: %d crashers found for %s matching %q, please use a longer prefix:\n", len(crashers), function.FuzzName(), hash)
+		for _, c := range crashers {
+			fmt.Printf("  %s\n", c.Hash)
+			fmt.Printf("    %s\n", c.Path)
+		}
+		return ArgErr
+	}
+	crasher := crashers[0]
+
+	src, err := fuzz.ExportCrasher(function, crasher, funcTimeout)
+	if err != nil {
+		fmt.Println("fzgo:", err)
+		return OtherErr
+	}
+	output := flagTestFile
+	if output == "" {
+		output = fuzz.ExportFilename(function, crasher)
+	}
+	if err := ioutil.WriteFile(output, src, 0644); err != nil {
+		fmt.Println("fzgo:", err)
+		return OtherErr
+	}
+	fmt.Printf("fzgo: wrote regression test for %s %s to %s\n", function.FuzzName(), crasher.Hash, output)
+	return Success
+}
+
+func sortedKeys(m map[string]int) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
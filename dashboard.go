@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/thepudds/fzgo/fuzz"
+)
+
+// dashboard renders a compact, live view of the progress of each fuzz target
+// by redrawing a block of lines at the bottom of the terminal.
+// dashboard is also an io.Writer for any other output from the fuzzing engine,
+// which is written above the dashboard.
+type dashboard struct {
+	mu    sync.Mutex
+	w     io.Writer
+	names []string              // fuzz names in display order
+	stats map[string]fuzz.Stats // latest stats by fuzz name
+	drawn int                   // number of lines drawn by our last render
+}
+
+func newDashboard(w io.Writer, targets []fuzz.Target) *dashboard {
+	d := &dashboard{w: w, stats: make(map[string]fuzz.Stats)}
+	for _, target := range targets {
+		d.names = append(d.names, target.FuzzName())
+	}
+	return d
+}
+
+// update records new stats for a target and redraws.
+func (d *dashboard) update(s fuzz.Stats) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stats[s.FuzzName] = s
+	d.render()
+}
+
+// Write writes output from a fuzzing engine above the dashboard.
+func (d *dashboard) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clear()
+	n, err := d.w.Write(p)
+	if len(d.stats) > 0 {
+		d.render()
+	}
+	return n, err
+}
+
+// reset forgets the location of the dashboard, such as prior to other output being printed.
+// The next update draws the dashboard anew.
+func (d *dashboard) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clear()
+}
+
+// clear erases the lines from our last render.
+func (d *dashboard) clear() {
+	if d.drawn > 0 {
+		// move to the start of our first line, then clear to the end of the screen.
+		fmt.Fprintf(d.w, "\x1b[%dF\x1b[J", d.drawn)
+		d.drawn = 0
+	}
+}
+
+func (d *dashboard) render() {
+	d.clear()
+	width := 0
+	for _, name := range d.names {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	for _, name := range d.names {
+		s, ok := d.stats[name]
+		if !ok {
+			fmt.Fprintf(d.w, "%-*s  waiting\n", width, name)
+		} else {
+			fmt.Fprintf(d.w, "%-*s  corpus: %d, crashers: %d, cover: %d, execs: %d (%d/sec), uptime: %s\n",
+				width, name, s.Corpus, s.Crashers, s.Cover, s.Execs, s.ExecsPerSec, time.Duration(s.Uptime*float64(time.Second)).Truncate(time.Second))
+		}
+		d.drawn++
+	}
+}
+
+// isTerminal reports whether f appears to be a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
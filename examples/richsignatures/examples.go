@@ -2,6 +2,9 @@ package pkgname
 
 import (
 	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf8"
 
 	"github.com/thepudds/fzgo/fuzz"
 )
@@ -45,3 +48,64 @@ type ExampleType int
 func FuzzWithTargetType(e ExampleType) {
 
 }
+
+// FuzzWithFuncs uses func-typed parameters, which are filled with synthesized funcs
+// that return results drawn from the input.
+func FuzzWithFuncs(list []int, less func(i, j int) bool, visit func(n int) error, done func()) {
+	sort.Slice(list, less)
+	for _, n := range list {
+		if visit(n) != nil {
+			break
+		}
+	}
+	done()
+}
+
+// FuzzWithOptions uses a '//fzgo:randparam' directive to allow larger maps and shorter keys,
+// and to never leave the slices nil.
+//
+//fzgo:randparam maxelements=20 nilchance=0 maxstringlength=8
+func FuzzWithOptions(counts map[string][]int) {
+	for k, v := range counts {
+		if len(k) > 8 || v == nil {
+			panic("bad fuzzer options")
+		}
+	}
+}
+
+// Contact uses struct tags to select the shape of the strings filled in by the fuzzer.
+type Contact struct {
+	Name  string `fzgo:"ident"`
+	Email string `fzgo:"regexp=[a-z]+@[a-z]+\\.com"`
+	Phone string `fzgo:"number"`
+}
+
+// FuzzWithStringShapes uses the names of its parameters to select the shape of
+// the strings filled in by the fuzzer, such as valid UTF-8 for utf8Text.
+func FuzzWithStringShapes(utf8Text string, numberCount string, c Contact) {
+	if !utf8.ValidString(utf8Text) {
+		panic("invalid UTF-8")
+	}
+	for _, s := range []string{numberCount, c.Phone} {
+		if _, err := strconv.ParseInt(s, 10, 64); err != nil && err.(*strconv.NumError).Err != strconv.ErrRange {
+			panic("not a number")
+		}
+	}
+}
+
+// Counter has an invariant on its unexported fields that is normally maintained by its methods.
+type Counter struct {
+	Name  string
+	count int
+	limit int
+}
+
+// FuzzWithUnexported uses a '//fzgo:randparam' directive to also fill the unexported fields
+// of Counter, reaching states that are normally only constructed internally.
+//
+//fzgo:randparam unexported=true
+func FuzzWithUnexported(c Counter) {
+	if c.count > c.limit {
+		panic("count exceeds limit")
+	}
+}
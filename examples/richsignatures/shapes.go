@@ -0,0 +1,55 @@
+package pkgname
+
+import (
+	"errors"
+	"math"
+)
+
+// Shape is an interface defined in the same package as the fuzz target that uses it (FuzzWithShape below).
+// The fuzzer chooses among the implementations registered with '//fzgo:impl' directives.
+type Shape interface {
+	Area() float64
+}
+
+// Square implements Shape with a value receiver.
+//
+//fzgo:impl
+type Square struct {
+	Side float64
+}
+
+// Area returns the area of the square.
+func (s Square) Area() float64 { return s.Side * s.Side }
+
+// Triangle implements Shape with a pointer receiver.
+//
+//fzgo:impl
+type Triangle struct {
+	Base, Height float64
+}
+
+// Area returns the area of the triangle.
+func (t *Triangle) Area() float64 { return t.Base * t.Height / 2 }
+
+// Circle is only created via its factory function.
+type Circle struct {
+	radius float64
+}
+
+// Area returns the area of the circle.
+func (c *Circle) Area() float64 { return math.Pi * c.radius * c.radius }
+
+// NewCircle is a factory function for a Circle.
+//
+//fzgo:impl
+func NewCircle(radius float64) (*Circle, error) {
+	if radius < 0 {
+		return nil, errors.New("negative radius")
+	}
+	return &Circle{radius: radius}, nil
+}
+
+// FuzzWithShape uses an interface with implementations registered via '//fzgo:impl'.
+func FuzzWithShape(s Shape, scale int) float64 {
+	return s.Area() * float64(scale)
+}
@@ -38,56 +38,60 @@ func Gopath() string {
 }
 
 // Hash returns a string representing the hash of the files in a package, its dependencies,
-// as well as the fuzz func name, the version of go and the go-fuzz-build binary.
-func Hash(pkgPath, funcName, trimPrefix string, env []string, verbose bool) (string, error) {
+// as well as the fuzz func name, the version of go and the fuzzing engine's binaries (such as go-fuzz-build).
+// dir is the directory to run 'go list' within, which matters in module mode.
+// Filenames within a trimPrefixes directory, such as a temporary directory, do not use
+// that prefix as part of the hash.
+func Hash(pkgPath, funcName string, trimPrefixes []string, dir string, env []string, engine Engine, verbose bool) (string, error) {
 	report := func(err error) (string, error) {
 		return "", fmt.Errorf("fzgo cache hash: %v", err)
 	}
 	h := sha256.New()
 
 	// hash the contents of our package and dependencies
-	dirs, err := goListDeps(pkgPath, env)
+	dirs, err := goListDeps(pkgPath, dir, env)
 	if err != nil {
 		return report(err)
 	}
 	sort.Strings(dirs)
 	for _, dir := range dirs {
-		hd, err := hashDir(dir, trimPrefix)
+		hd, err := hashDir(dir, trimPrefixes)
 		if err != nil {
 			return report(err)
 		}
 
-		fmt.Fprintf(h, "%s  %s\n", hd, strings.TrimPrefix(dir, trimPrefix))
+		fmt.Fprintf(h, "%s  %s\n", hd, trimAny(dir, trimPrefixes))
 		if verbose {
 			fmt.Printf("%s  %s\n", hd, dir)
 		}
 	}
 
-	// hash the go-fuzz-build binary.
-	// first, check if go-fuzz seems to be installed.
-	err = checkGoFuzz()
-	if err != nil {
-		// err here suggests running 'go get' for go-fuzz
-		return report(err)
-	}
-	path, err := exec.LookPath("go-fuzz-build")
+	// hash the binaries used by our fuzzing engine, such as go-fuzz-build.
+	// first, check if the engine's tools seem to be installed.
+	eng, err := engine.impl()
 	if err != nil {
 		return report(err)
 	}
-	f, err := os.Open(path)
+	err = eng.check()
 	if err != nil {
+		// err here suggests running 'go get' for go-fuzz
 		return report(err)
 	}
-	defer f.Close()
-	hf := sha256.New()
-	_, err = io.Copy(hf, f)
-	if err != nil {
-		return report(err)
+	for _, tool := range eng.tools() {
+		s, err := hashTool(tool)
+		if err != nil {
+			return report(err)
+		}
+		fmt.Fprintf(h, "%x  %s\n", s, tool)
+		if verbose {
+			fmt.Printf("%x  %s\n", s, tool)
+		}
 	}
-	s := hf.Sum(nil)
-	fmt.Fprintf(h, "%x  %s\n", s, "go-fuzz-build")
-	if verbose {
-		fmt.Printf("%x  %s\n", s, "go-fuzz-build")
+
+	// hash the engine name. go-fuzz was historically the only engine,
+	// so we leave it out of the hash to keep existing cache entries valid.
+	if engine != GoFuzz {
+		fmt.Fprintf(h, "%s engine\n", engine)
 	}
 
 	// hash the fuzz func name
@@ -99,8 +103,27 @@ func Hash(pkgPath, funcName, trimPrefix string, env []string, verbose bool) (str
 	return fmt.Sprintf("%x", h.Sum(nil)[:10]), nil
 }
 
+// hashTool hashes the contents of a binary found in our path.
+func hashTool(name string) ([]byte, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
 // hashDir hashes files without descending into subdirectories.
-func hashDir(dir, trimPrefix string) (string, error) {
+func hashDir(dir string, trimPrefixes []string) (string, error) {
 
 	var absFiles []string
 	files, err := ioutil.ReadDir(dir)
@@ -108,6 +131,12 @@ func hashDir(dir, trimPrefix string) (string, error) {
 		return "", err
 	}
 	for _, file := range files {
+		if file.Mode()&os.ModeSymlink != 0 {
+			// a file in a temporary copy of the source tree is a symlink to the original.
+			if fi, err := os.Stat(filepath.Join(dir, file.Name())); err == nil {
+				file = fi
+			}
+		}
 		if file.IsDir() || !file.Mode().IsRegular() {
 			continue
 		}
@@ -120,14 +149,14 @@ func hashDir(dir, trimPrefix string) (string, error) {
 
 	}
 
-	return hashFiles(absFiles, trimPrefix)
+	return hashFiles(absFiles, trimPrefixes)
 }
 
 // Adapted from dirhash.Hash1. The largest difference is
-// the filenames within a trimPrefix directory won't use
-// the trimPrefix string as part of the hash.
+// the filenames within a trimPrefixes directory won't use
+// the prefix as part of the hash.
 // The file contents are still hashed.
-func hashFiles(files []string, trimPrefix string) (string, error) {
+func hashFiles(files []string, trimPrefixes []string) (string, error) {
 	h := sha256.New()
 	files = append([]string(nil), files...)
 	sort.Strings(files)
@@ -145,13 +174,24 @@ func hashFiles(files []string, trimPrefix string) (string, error) {
 		if err != nil {
 			return "", err
 		}
-		fmt.Fprintf(h, "%x  %s\n", hf.Sum(nil), strings.TrimPrefix(file, trimPrefix))
+		fmt.Fprintf(h, "%x  %s\n", hf.Sum(nil), trimAny(file, trimPrefixes))
 	}
 	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
 }
 
-// goListDeps returns a []string of dirs for all dependencies of pkg
-func goListDeps(pkg string, env []string) ([]string, error) {
+// trimAny returns s without the first of prefixes that it starts with.
+func trimAny(s string, prefixes []string) string {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return strings.TrimPrefix(s, prefix)
+		}
+	}
+	return s
+}
+
+// goListDeps returns a []string of dirs for all dependencies of pkg.
+// In module mode, dir should be within the main module for pkg.
+func goListDeps(pkg string, dir string, env []string) ([]string, error) {
 	report := func(err error) ([]string, error) {
 		return nil, fmt.Errorf("go list -deps: %v", err)
 	}
@@ -162,6 +202,7 @@ func goListDeps(pkg string, env []string) ([]string, error) {
 
 	cmd := exec.Command("go", "list", "-deps", "-f", "{{.Dir}}", buildTagsArg, pkg)
 	cmd.Env = env
+	cmd.Dir = dir
 
 	out, err := cmd.Output()
 	if err != nil {
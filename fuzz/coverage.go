@@ -0,0 +1,89 @@
+package fuzz
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// MergeCoverProfiles merges coverage profiles, such as from multiple calls to VerifyCorpus,
+// into a single profile written to dst. The counts for a block that appears
+// in more than one profile are summed. Profiles that do not exist are skipped.
+// Blocks from our temporary non-test copies of _test.go files are dropped,
+// given those copies no longer exist and are not part of the code under test.
+func MergeCoverProfiles(dst string, profiles []string) error {
+	report := func(err error) error { return fmt.Errorf("merging coverage profiles: %v", err) }
+
+	// cover.ParseProfiles merges duplicate blocks within a profile,
+	// so we concatenate the blocks from our profiles and parse the result.
+	concat, err := ioutil.TempFile("", "fzgo-cover")
+	if err != nil {
+		return report(err)
+	}
+	defer os.Remove(concat.Name())
+	w := bufio.NewWriter(concat)
+	fmt.Fprintln(w, "mode: count")
+	for _, profile := range profiles {
+		data, err := ioutil.ReadFile(profile)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			concat.Close()
+			return report(err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line == "" || strings.HasPrefix(line, "mode:") {
+				continue
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		concat.Close()
+		return report(err)
+	}
+	if err := concat.Close(); err != nil {
+		return report(err)
+	}
+
+	merged, err := cover.ParseProfiles(concat.Name())
+	if err != nil {
+		return report(err)
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return report(err)
+	}
+	w = bufio.NewWriter(out)
+	fmt.Fprintln(w, "mode: count")
+	for _, p := range merged {
+		if strings.HasPrefix(path.Base(p.FileName), testVariantPrefix) {
+			continue
+		}
+		for _, b := range p.Blocks {
+			fmt.Fprintf(w, "%s:%d.%d,%d.%d %d %d\n", p.FileName, b.StartLine, b.StartCol, b.EndLine, b.EndCol, b.NumStmt, b.Count)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		out.Close()
+		return report(err)
+	}
+	if err := out.Close(); err != nil {
+		return report(err)
+	}
+	return nil
+}
+
+// CoverHTML writes an HTML presentation of a coverage profile to htmlFile using 'go tool cover'.
+func CoverHTML(profile, htmlFile string) error {
+	args := []string{"tool", "cover", "-html=" + profile, "-o=" + htmlFile}
+	if err := ExecGo(args, nil); err != nil {
+		return fmt.Errorf("'go tool cover' failed with args %q: %v", args, err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package fuzz
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeCoverProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fzgo-coverage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	profiles := map[string]string{
+		"1.out": `mode: count
+example.com/pkg/a.go:4.2,4.31 1 3
+example.com/pkg/a.go:5.3,5.21 1 0
+example.com/pkg/fzgo_testvariant_a.go:8.2,8.15 1 8
+`,
+		"2.out": `mode: count
+example.com/pkg/a.go:4.2,4.31 1 2
+example.com/pkg/a.go:5.3,5.21 1 1
+example.com/pkg/b.go:1.1,2.2 2 0
+`,
+	}
+	var srcs []string
+	for name, content := range profiles {
+		src := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(src, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		srcs = append(srcs, src)
+	}
+	// a missing profile, such as from a 'go test' that did not run, is skipped.
+	srcs = append(srcs, filepath.Join(dir, "missing.out"))
+
+	dst := filepath.Join(dir, "merged.out")
+	if err := MergeCoverProfiles(dst, srcs); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `mode: count
+example.com/pkg/a.go:4.2,4.31 1 5
+example.com/pkg/a.go:5.3,5.21 1 1
+example.com/pkg/b.go:1.1,2.2 2 0
+`
+	if string(got) != want {
+		t.Errorf("MergeCoverProfiles() wrote:\n%s\nwant:\n%s", got, want)
+	}
+}
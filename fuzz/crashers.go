@@ -0,0 +1,93 @@
+package fuzz
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Crasher is a crashing input found while fuzzing.
+type Crasher struct {
+	Hash    string // the crasher's filename, which is typically a hash of its contents
+	Path    string // the crashing input, which can be used to reproduce the crash
+	Summary string // the first line describing the failure, such as 'panic: boom', if known
+}
+
+// Crashers returns the crashers in a workDir. A workDir without a crashers
+// directory has no crashers.
+func Crashers(workDir string) []Crasher {
+	dir := filepath.Join(workDir, "crashers")
+	var crashers []Crasher
+	for _, name := range listCrashers(dir) {
+		path := filepath.Join(dir, name)
+		crashers = append(crashers, Crasher{Hash: name, Path: path, Summary: crasherSummary(path)})
+	}
+	return crashers
+}
+
+// FindCrashers returns the crashers in workDirs whose hash starts with prefix, with one crasher
+// for each distinct hash, taken from the first workDir it appears in. A crasher whose hash equals
+// prefix is returned alone, even if other hashes also start with prefix.
+func FindCrashers(workDirs []string, prefix string) []Crasher {
+	var found []Crasher
+	seen := make(map[string]bool)
+	for _, workDir := range workDirs {
+		for _, c := range Crashers(workDir) {
+			if c.Hash == prefix {
+				return []Crasher{c}
+			}
+			if strings.HasPrefix(c.Hash, prefix) && !seen[c.Hash] {
+				seen[c.Hash] = true
+				found = append(found, c)
+			}
+		}
+	}
+	return found
+}
+
+// crasherSummary returns the first line of the panic or other fatal error
+// from the crasher's .output file, or the first non-blank line if there is no panic.
+// It returns an empty string if there is no .output file.
+func crasherSummary(path string) string {
+	output, err := ioutil.ReadFile(path + ".output")
+	if err != nil {
+		return ""
+	}
+	var first string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if first == "" {
+			first = line
+		}
+		// the native engine reports a panic in a line such as 'testing.go:1349: panic: boom'.
+		for _, prefix := range []string{"panic: ", "fatal error: "} {
+			if i := strings.Index(line, prefix); i >= 0 {
+				return line[i:]
+			}
+		}
+	}
+	return first
+}
+
+// listCrashers returns the names of the crashing inputs in a crashers directory,
+// excluding auxiliary files such as the .output files and minimized crashers.
+// A missing directory has no crashers.
+func listCrashers(dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || strings.HasSuffix(name, ".output") || strings.HasSuffix(name, ".quoted") ||
+			strings.HasSuffix(name, minSuffix) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,100 @@
+package fuzz
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCrashers(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "fzgo-crashers-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workDir)
+	if got := Crashers(workDir); len(got) != 0 {
+		t.Errorf("Crashers() without a crashers dir = %v, want none", got)
+	}
+
+	dir := filepath.Join(workDir, "crashers")
+	files := map[string]string{
+		"aaa":        "x",
+		"aaa.output": "panic: boom\n\ngoroutine 1 [running]:\n",
+		"aaa.quoted": `"x"`,
+		"bbb":        "y",
+		"bbb.output": "fuzz: elapsed: 0s\n--- FAIL: FuzzNative (0.00s)\n    testing.go:1349: panic: native boom\n",
+		"ccc":        "z",
+		"ddd":        "w",
+		"ddd.output": "\nprogram hanged (timeout 10 seconds)\n",
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []Crasher{
+		{Hash: "aaa", Path: filepath.Join(dir, "aaa"), Summary: "panic: boom"},
+		{Hash: "bbb", Path: filepath.Join(dir, "bbb"), Summary: "panic: native boom"},
+		{Hash: "ccc", Path: filepath.Join(dir, "ccc"), Summary: ""},
+		{Hash: "ddd", Path: filepath.Join(dir, "ddd"), Summary: "program hanged (timeout 10 seconds)"},
+	}
+	got := Crashers(workDir)
+	if len(got) != len(want) {
+		t.Fatalf("Crashers() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Crashers()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFindCrashers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fzgo-findcrashers-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// abc1 is in both workDirs, and abc is a prefix of the other hashes.
+	workDirs := []string{filepath.Join(dir, "testdata"), filepath.Join(dir, "gopath")}
+	for _, path := range []string{"testdata/crashers/abc1", "gopath/crashers/abc1", "gopath/crashers/abc2", "gopath/crashers/abc"} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"abc1", []string{"testdata/crashers/abc1"}},
+		{"abc2", []string{"gopath/crashers/abc2"}},
+		{"ab", []string{"testdata/crashers/abc1", "gopath/crashers/abc", "gopath/crashers/abc2"}},
+		{"abc", []string{"gopath/crashers/abc"}},
+		{"xyz", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, c := range FindCrashers(workDirs, tt.prefix) {
+			rel, err := filepath.Rel(dir, c.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, filepath.ToSlash(rel))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindCrashers(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package fuzz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thepudds/fzgo/randparam"
+)
+
+// Arg is an argument to a fuzz function that was decoded from an input.
+type Arg struct {
+	Name    string
+	Type    string
+	Literal string // Go source for the value, such as '"abc"' or '&pkgname.T{A: 1}'
+}
+
+// Decl returns a Go statement declaring the argument with its value, such as 're := "a+b"'.
+func (a Arg) Decl() string {
+	if strings.HasPrefix(a.Literal, a.Type+"{") || strings.HasPrefix(a.Literal, a.Type+"(") ||
+		defaultType(a.Literal) == a.Type {
+		// the literal's type matches the argument's type, so we don't need to state it.
+		return fmt.Sprintf("%s := %s", a.Name, a.Literal)
+	}
+	return fmt.Sprintf("var %s %s = %s", a.Name, a.Type, a.Literal)
+}
+
+// Value returns the argument's value as JSON. Composite values become JSON arrays and objects,
+// []byte values are base64 encoded as with encoding/json, and floating point values
+// that JSON cannot represent become the strings "NaN", "+Inf" and "-Inf". Negative zero is -0.
+func (a Arg) Value() (json.RawMessage, error) {
+	expr, err := parser.ParseExpr(a.Literal)
+	if err != nil {
+		return nil, fmt.Errorf("parsing literal for %s: %v", a.Name, err)
+	}
+	v, err := exprValue(expr)
+	if err != nil {
+		return nil, fmt.Errorf("converting literal for %s: %v", a.Name, err)
+	}
+	return json.Marshal(v)
+}
+
+// Decoded is an input for a fuzz function decoded into the fuzz function's arguments.
+type Decoded struct {
+	Path string
+	Args []Arg
+	Err  error // set if the input could not be decoded
+}
+
+// DecodeInputs decodes each input file into the arguments of the fuzz function.
+// For a plain 'func([]byte) int' signature, the argument is simply the content of the file.
+// For a rich signature, each input is executed via the rich signature wrapper with printArgs set,
+// which decodes the input via randparam.Fuzzer in the same way as when fuzzing.
+// An input that cannot be decoded has Err set.
+func DecodeInputs(function Func, paths []string, funcTimeout time.Duration) ([]Decoded, error) {
+	report := func(err error) ([]Decoded, error) {
+		return nil, fmt.Errorf("decode inputs for %s: %v", function.FuzzName(), err)
+	}
+	if funcTimeout < 1*time.Second {
+		return nil, fmt.Errorf("minimum allowed func timeout value is 1 second")
+	}
+
+	d, err := newArgDecoder(function, funcTimeout)
+	if err != nil {
+		return report(err)
+	}
+	defer d.close()
+
+	var results []Decoded
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return report(err)
+		}
+		args, _, err := d.decode(data)
+		results = append(results, Decoded{Path: path, Args: args, Err: err})
+	}
+	return results, nil
+}
+
+// argDecoder decodes the arguments for a fuzz function from inputs.
+type argDecoder struct {
+	params []Arg        // the names and types of the fuzz function's parameters
+	runner *inputRunner // nil for a plain signature
+}
+
+func newArgDecoder(function Func, funcTimeout time.Duration) (*argDecoder, error) {
+	sig, ok := function.TypesFunc.Type().(*types.Signature)
+	if !ok {
+		return nil, fmt.Errorf("function is not *types.Signature (%+v)", function.TypesFunc)
+	}
+	plain, err := IsPlainSig(function.TypesFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &argDecoder{}
+	if plain {
+		name := sig.Params().At(0).Name()
+		if name == "" || name == "_" {
+			name = "data"
+		}
+		d.params = []Arg{{Name: name, Type: "[]byte"}}
+		return d, nil
+	}
+	for i := 0; i < sig.Params().Len(); i++ {
+		v := sig.Params().At(i)
+		d.params = append(d.params, Arg{Name: v.Name(), Type: types.TypeString(v.Type(), externalQualifier)})
+	}
+	d.runner, err = newInputRunner(function, true, "decodetest", nil, funcTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *argDecoder) close() {
+	if d.runner != nil {
+		d.runner.close()
+	}
+}
+
+// decode returns the arguments for data, along with the output from executing the fuzz function
+// with data (which is empty for a plain signature).
+func (d *argDecoder) decode(data []byte) ([]Arg, string, error) {
+	args := append([]Arg(nil), d.params...)
+	if d.runner == nil {
+		args[0].Literal = randparam.Literal(data)
+		return args, "", nil
+	}
+	output, err := d.runner.run(data)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := parseArgLiterals(output, args); err != nil {
+		return nil, output, err
+	}
+	return args, output, nil
+}
+
+// parseArgLiterals finds the Go source for each argument in the output of a rich signature wrapper
+// created with printArgs set, which prints lines like '    name:  literal' in the order of the arguments.
+func parseArgLiterals(output string, args []Arg) error {
+	i := 0
+	for _, line := range strings.Split(output, "\n") {
+		if i == len(args) {
+			break
+		}
+		prefix := args[i].Name + ":  "
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, prefix) {
+			args[i].Literal = strings.TrimPrefix(line, prefix)
+			i++
+		}
+	}
+	if i < len(args) {
+		return fmt.Errorf("failed to decode argument %q from output:\n%s", args[i].Name, output)
+	}
+	return nil
+}
+
+// defaultType returns the type of a literal when used without a type, such as 'int' for '3',
+// or an empty string for a literal that is not an untyped constant.
+func defaultType(literal string) string {
+	switch {
+	case literal == "true" || literal == "false":
+		return "bool"
+	case strings.HasPrefix(literal, `"`):
+		return "string"
+	}
+	if _, err := strconv.ParseInt(literal, 10, 64); err == nil {
+		return "int"
+	}
+	if _, err := strconv.ParseFloat(literal, 64); err == nil {
+		return "float64"
+	}
+	return ""
+}
+
+// exprValue converts a Go expression created by randparam.Literal to a value for encoding/json.
+func exprValue(e ast.Expr) (interface{}, error) {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return exprValue(e.X)
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.STRING:
+			return strconv.Unquote(e.Value)
+		case token.INT, token.FLOAT:
+			return json.Number(e.Value), nil
+		}
+	case *ast.Ident:
+		switch e.Name {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "nil":
+			return nil, nil
+		}
+	case *ast.UnaryExpr:
+		switch e.Op {
+		case token.AND:
+			return exprValue(e.X)
+		case token.SUB:
+			if lit, ok := e.X.(*ast.BasicLit); ok {
+				return json.Number("-" + lit.Value), nil
+			}
+		}
+	case *ast.CallExpr:
+		switch fun := types.ExprString(e.Fun); {
+		case fun == "math.NaN":
+			return "NaN", nil
+		case fun == "math.Inf":
+			if strings.HasPrefix(types.ExprString(e.Args[0]), "-") {
+				return "-Inf", nil
+			}
+			return "+Inf", nil
+		case fun == "math.Copysign":
+			// negative zero.
+			return math.Copysign(0, -1), nil
+		case fun == "complex":
+			return types.ExprString(e), nil
+		case fun == "[]byte" && len(e.Args) == 1:
+			s, err := exprValue(e.Args[0])
+			if err != nil {
+				return nil, err
+			}
+			if s, ok := s.(string); ok {
+				return []byte(s), nil
+			}
+		case len(e.Args) == 1:
+			// a conversion.
+			return exprValue(e.Args[0])
+		}
+		if fl, ok := e.Fun.(*ast.FuncLit); ok && len(fl.Body.List) > 0 {
+			// our func literal for a pointer to a basic value, such as 'func() *int { var v int = 1; return &v }()'.
+			if decl, ok := fl.Body.List[0].(*ast.DeclStmt); ok {
+				if gd, ok := decl.Decl.(*ast.GenDecl); ok && len(gd.Specs) == 1 {
+					if vs, ok := gd.Specs[0].(*ast.ValueSpec); ok && len(vs.Values) == 1 {
+						return exprValue(vs.Values[0])
+					}
+				}
+			}
+		}
+		// a call to a factory function registered with '//fzgo:impl', which we describe with its source.
+		return types.ExprString(e), nil
+	case *ast.FuncLit:
+		// a synthesized func value without any results from the input.
+		return nil, nil
+	case *ast.CompositeLit:
+		if len(e.Elts) == 0 {
+			if _, ok := e.Type.(*ast.ArrayType); ok {
+				return []interface{}{}, nil
+			}
+			return orderedObject{}, nil
+		}
+		if _, ok := e.Elts[0].(*ast.KeyValueExpr); !ok {
+			var elems []interface{}
+			for _, elt := range e.Elts {
+				v, err := exprValue(elt)
+				if err != nil {
+					return nil, err
+				}
+				elems = append(elems, v)
+			}
+			return elems, nil
+		}
+		var obj orderedObject
+		for _, elt := range e.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				return nil, fmt.Errorf("mixed elements in %s", types.ExprString(e))
+			}
+			key := types.ExprString(kv.Key)
+			if lit, ok := kv.Key.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				key, _ = strconv.Unquote(lit.Value)
+			}
+			v, err := exprValue(kv.Value)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, objectField{key, v})
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("unexpected expression %s", types.ExprString(e))
+}
+
+// orderedObject is a JSON object that keeps the order of its fields, such as for struct fields.
+type orderedObject []objectField
+
+type objectField struct {
+	key   string
+	value interface{}
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("{")
+	for i, f := range o {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		key, err := json.Marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(value)
+	}
+	b.WriteString("}")
+	return b.Bytes(), nil
+}
@@ -0,0 +1,55 @@
+package fuzz
+
+import (
+	"testing"
+)
+
+func TestArgValue(t *testing.T) {
+	tests := []struct {
+		literal string
+		want    string
+	}{
+		{`"a+b"`, `"a+b"`},
+		{`[]byte("\x00x")`, `"AHg="`},
+		{`true`, `true`},
+		{`-42`, `-42`},
+		{`int8(1)`, `1`},
+		{`math.Inf(-1)`, `"-Inf"`},
+		{`math.Inf(1)`, `"+Inf"`},
+		{`float32(math.NaN())`, `"NaN"`},
+		{`math.Copysign(0, -1)`, `-0`},
+		{`[]int{1, 2}`, `[1,2]`},
+		{`map[string]int{"b": 2, "a": 1}`, `{"b":2,"a":1}`},
+		{`&pkgname.T{B: "x", A: nil}`, `{"B":"x","A":null}`},
+		{`func() *int { var v int = 7; return &v }()`, `7`},
+		{`pkgname.T{}`, `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.literal, func(t *testing.T) {
+			got, err := Arg{Name: "x", Literal: tt.literal}.Value()
+			if err != nil {
+				t.Fatalf("Value() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Value() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArgDecl(t *testing.T) {
+	tests := []struct {
+		arg  Arg
+		want string
+	}{
+		{Arg{Name: "re", Type: "string", Literal: `"a+b"`}, `re := "a+b"`},
+		{Arg{Name: "input", Type: "[]byte", Literal: `[]byte("x")`}, `input := []byte("x")`},
+		{Arg{Name: "n", Type: "int8", Literal: `1`}, `var n int8 = 1`},
+		{Arg{Name: "s", Type: "pkgname.S", Literal: `"abc"`}, `var s pkgname.S = "abc"`},
+	}
+	for _, tt := range tests {
+		if got := tt.arg.Decl(); got != tt.want {
+			t.Errorf("Decl() = %s, want %s", got, tt.want)
+		}
+	}
+}
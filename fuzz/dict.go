@@ -0,0 +1,334 @@
+package fuzz
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// dictFile is the name of the dictionary we write into the workDir for engines that support dictionaries.
+const dictFile = "fzgo.dict"
+
+// maxDictToken is the longest token we include in a dictionary.
+// libFuzzer rejects a dictionary with a longer entry.
+const maxDictToken = 64
+
+// dictionary returns tokens for fuzzing function that are extracted from the package
+// containing function, as loaded by FindFunc. For a function in an external test package,
+// the package under test is included as well.
+// The tokens include string and character literals, integer and floating point literals,
+// the values of constants, and the names from struct tags, which covers the literals used
+// in comparisons and switch cases. Integers larger than a byte are also included
+// in their little-endian binary form, which is how randparam decodes integers.
+func dictionary(function Func) []string {
+	var files []*ast.File
+	var scopes []*types.Scope
+	for _, pkg := range function.pkgs {
+		files = append(files, pkg.Syntax...)
+		if pkg.Types != nil {
+			scopes = append(scopes, pkg.Types.Scope())
+		}
+	}
+	return dictTokens(files, scopes)
+}
+
+// dictTokens returns the sorted, de-duplicated tokens from the literals and struct tags in files
+// and the constants in scopes.
+func dictTokens(files []*ast.File, scopes []*types.Scope) []string {
+	seen := make(map[string]bool)
+	add := func(toks ...string) {
+		for _, tok := range toks {
+			if tok != "" && len(tok) <= maxDictToken {
+				seen[tok] = true
+			}
+		}
+	}
+
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ImportSpec:
+			// import paths are not interesting.
+			return false
+		case *ast.Field:
+			if n.Tag != nil {
+				add(tagTokens(n.Tag.Value)...)
+			}
+			// visit the field's type, which might be a struct with its own tags, but not the raw tag.
+			ast.Inspect(n.Type, visit)
+			return false
+		case *ast.UnaryExpr:
+			if lit, ok := n.X.(*ast.BasicLit); ok && n.Op == token.SUB && lit.Kind == token.INT {
+				add(constTokens(constant.UnaryOp(token.SUB, constant.MakeFromLiteral(lit.Value, lit.Kind, 0), 0))...)
+			}
+		case *ast.BasicLit:
+			add(litTokens(n)...)
+		}
+		return true
+	}
+	for _, f := range files {
+		ast.Inspect(f, visit)
+	}
+
+	for _, scope := range scopes {
+		for _, name := range scope.Names() {
+			if c, ok := scope.Lookup(name).(*types.Const); ok {
+				add(constTokens(c.Val())...)
+			}
+		}
+	}
+
+	var result []string
+	for tok := range seen {
+		result = append(result, tok)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// litTokens returns the tokens for a literal.
+func litTokens(lit *ast.BasicLit) []string {
+	switch lit.Kind {
+	case token.STRING:
+		s, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return nil
+		}
+		return []string{s}
+	case token.CHAR:
+		r, _, _, err := strconv.UnquoteChar(lit.Value[1:len(lit.Value)-1], '\'')
+		if err != nil {
+			return nil
+		}
+		return []string{string(r)}
+	case token.INT:
+		return constTokens(constant.MakeFromLiteral(lit.Value, lit.Kind, 0))
+	case token.FLOAT:
+		return []string{lit.Value}
+	}
+	return nil
+}
+
+// constTokens returns the tokens for a string or integer constant.
+func constTokens(v constant.Value) []string {
+	switch v.Kind() {
+	case constant.String:
+		return []string{constant.StringVal(v)}
+	case constant.Int:
+		if i, ok := constant.Int64Val(v); ok {
+			return intTokens(i)
+		}
+		if u, ok := constant.Uint64Val(v); ok {
+			b := make([]byte, 8)
+			binary.LittleEndian.PutUint64(b, u)
+			return []string{strconv.FormatUint(u, 10), string(b)}
+		}
+	}
+	return nil
+}
+
+// intTokens returns the decimal form of v, along with the little-endian binary form
+// using the smallest of 2, 4 or 8 bytes that holds v. Values that fit in a byte
+// are readily found by mutation, so those only have the decimal form.
+func intTokens(v int64) []string {
+	toks := []string{strconv.FormatInt(v, 10)}
+	var width int
+	switch {
+	case v >= -0x80 && v <= 0xFF:
+		return toks
+	case v >= -0x8000 && v <= 0xFFFF:
+		width = 2
+	case v >= -0x80000000 && v <= 0xFFFFFFFF:
+		width = 4
+	default:
+		width = 8
+	}
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(v))
+	return append(toks, string(b[:width]))
+}
+
+// tagPair matches a key:"value" pair in a struct tag.
+var tagPair = regexp.MustCompile(`([^\s:"]+):("(?:[^"\\]|\\.)*")`)
+
+// tagTokens returns the tokens from a struct tag literal such as `json:"name,omitempty"`,
+// which are the comma separated parts of each value, such as "name" and "omitempty".
+func tagTokens(lit string) []string {
+	tag, err := strconv.Unquote(lit)
+	if err != nil {
+		return nil
+	}
+	var toks []string
+	for _, m := range tagPair.FindAllStringSubmatch(tag, -1) {
+		value, err := strconv.Unquote(m[2])
+		if err != nil {
+			continue
+		}
+		for _, part := range strings.Split(value, ",") {
+			if part != "-" {
+				toks = append(toks, part)
+			}
+		}
+	}
+	return toks
+}
+
+// writeTargetDict writes a dictionary to path in the format used by libFuzzer and AFL,
+// with the tokens from targetDict. It returns the number of tokens written.
+// If there are no tokens, no dictionary is written.
+func writeTargetDict(function Func, path string, userDicts []string) (int, error) {
+	toks, err := targetDict(function, userDicts)
+	if err != nil {
+		return 0, err
+	}
+	if len(toks) == 0 {
+		return 0, nil
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# dictionary for %s generated by fzgo.\n", function.FuzzName())
+	fmt.Fprintf(&b, "# it is rewritten each time fzgo starts fuzzing; use -fuzzdict to add your own tokens.\n")
+	for _, tok := range toks {
+		fmt.Fprintf(&b, "%s\n", quoteDictToken(tok))
+	}
+	if err := ioutil.WriteFile(path, b.Bytes(), 0644); err != nil {
+		return 0, err
+	}
+	return len(toks), nil
+}
+
+// targetDict returns the tokens derived from the package containing function along with
+// the tokens from the user dictionaries in userDicts, without duplicates.
+func targetDict(function Func, userDicts []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var toks []string
+	add := func(tok string) {
+		if !seen[tok] {
+			seen[tok] = true
+			toks = append(toks, tok)
+		}
+	}
+	for _, tok := range dictionary(function) {
+		add(tok)
+	}
+	for _, userDict := range userDicts {
+		userToks, err := readDict(userDict)
+		if err != nil {
+			return nil, err
+		}
+		for _, tok := range userToks {
+			add(tok)
+		}
+	}
+	return toks, nil
+}
+
+// quoteDictToken returns tok as a dictionary entry, such as "abc" or "\x01\x02".
+func quoteDictToken(tok string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(tok); i++ {
+		switch c := tok[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c >= 0x20 && c < 0x7F:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "\\x%02X", c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// readDict reads the tokens from a dictionary in the format used by libFuzzer and AFL,
+// which has one entry per line in the form '"token"' or 'name="token"', along with
+// blank lines and '#' comments. Tokens longer than libFuzzer allows are skipped.
+func readDict(path string) ([]string, error) {
+	report := func(err error) ([]string, error) {
+		return nil, fmt.Errorf("reading dictionary %s: %v", path, err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return report(err)
+	}
+	defer f.Close()
+
+	var toks []string
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		tok, err := unquoteDictEntry(s)
+		if err != nil {
+			return report(fmt.Errorf("line %d: %v", line, err))
+		}
+		if len(tok) <= maxDictToken {
+			toks = append(toks, tok)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return report(err)
+	}
+	return toks, nil
+}
+
+// unquoteDictEntry returns the token for a dictionary entry such as 'kw="abc"' or '"\x01\x02"'.
+func unquoteDictEntry(s string) (string, error) {
+	i := strings.IndexByte(s, '"')
+	if i < 0 || len(s) < i+2 || !strings.HasSuffix(s, `"`) {
+		return "", fmt.Errorf("entry %s is not a quoted token", s)
+	}
+	if i > 0 && !strings.HasSuffix(s[:i], "=") {
+		return "", fmt.Errorf("entry %s has an unexpected name", s)
+	}
+	quoted := s[i+1 : len(s)-1]
+	var b strings.Builder
+	for j := 0; j < len(quoted); j++ {
+		c := quoted[j]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		switch {
+		case j+1 < len(quoted) && (quoted[j+1] == '\\' || quoted[j+1] == '"'):
+			b.WriteByte(quoted[j+1])
+			j++
+		case j+3 < len(quoted) && quoted[j+1] == 'x':
+			v, err := strconv.ParseUint(quoted[j+2:j+4], 16, 8)
+			if err != nil {
+				return "", fmt.Errorf("entry %s has an invalid escape: %v", s, err)
+			}
+			b.WriteByte(byte(v))
+			j += 3
+		default:
+			return "", fmt.Errorf("entry %s has an invalid escape", s)
+		}
+	}
+	return b.String(), nil
+}
+
+// warnUnusedDicts notes that the user dictionaries in opts are not used by engine e.
+func warnUnusedDicts(e Engine, opts StartOptions) {
+	if len(opts.Dicts) == 0 || e == LibFuzzer || e == Native {
+		return
+	}
+	if e == "" {
+		e = GoFuzz
+	}
+	info("the %s engine does not support dictionaries, ignoring %s", e, strings.Join(opts.Dicts, ", "))
+}
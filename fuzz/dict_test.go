@@ -0,0 +1,96 @@
+package fuzz
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDictTokens(t *testing.T) {
+	src := `package p
+
+import "strings"
+
+const (
+	magic      = 0xCAFE
+	header     = "HDR" + "1"
+	big   uint = 1 << 40
+)
+
+type msg struct {
+	Kind  string ` + "`json:\"kind,omitempty\" xml:\"-\"`" + `
+	Inner struct {
+		ID int ` + "`json:\"id\"`" + `
+	}
+}
+
+func FuzzParse(data string, n int) {
+	switch {
+	case strings.HasPrefix(data, "GET "):
+	case data == "":
+	case n == -300 || n == 7:
+	}
+	if data[0] == 'x' && float64(n) > 1.5 {
+		panic("found it")
+	}
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	conf := types.Config{Importer: nil, Error: func(error) {}}
+	pkg, _ := conf.Check("p", fset, []*ast.File{f}, nil)
+
+	got := dictTokens([]*ast.File{f}, []*types.Scope{pkg.Scope()})
+	want := []string{
+		"\x00\x00\x00\x00\x00\x01\x00\x00", // big
+		",\x01",                            // 300
+		"-300", "0", "1", "1.5", "1099511627776", "300", "40", "51966", "7",
+		"GET ", "HDR", "HDR1", "found it", "id", "kind", "omitempty", "x",
+		"\xD4\xFE", // -300
+		"\xFE\xCA", // magic
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dictTokens() = %q, want %q", got, want)
+	}
+}
+
+func TestDictRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fzgo-dict-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	toks := []string{"plain", `quote"backslash\`, "\x00\x01\xFF", "tab\t"}
+	for _, tok := range toks {
+		got, err := unquoteDictEntry(quoteDictToken(tok))
+		if err != nil || got != tok {
+			t.Errorf("unquoteDictEntry(quoteDictToken(%q)) = %q, %v", tok, got, err)
+		}
+	}
+
+	path := filepath.Join(dir, "user.dict")
+	content := "# a comment\n\nkw1=\"abc\"\n \"\\x41\\x42\" \nkw@2=\"\\\"\"\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readDict(path)
+	if err != nil {
+		t.Fatalf("readDict() error = %v", err)
+	}
+	if want := []string{"abc", "AB", `"`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("readDict() = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"abc", `x"abc"`, `"\q"`, `"\xZZ"`, `"`} {
+		if _, err := unquoteDictEntry(bad); err == nil {
+			t.Errorf("unquoteDictEntry(%s) succeeded, want error", bad)
+		}
+	}
+}
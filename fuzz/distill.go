@@ -0,0 +1,228 @@
+package fuzz
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+// DistillOptions control how DistillCorpus executes the corpus.
+type DistillOptions struct {
+	FuncTimeout time.Duration // time limit for a single execution of the fuzz function
+	Verbose     bool
+}
+
+// Distilled is the result of distilling a corpus.
+type Distilled struct {
+	Kept     []string // names of the corpus files in the distilled subset
+	Dropped  []string // names of the corpus files that do not add coverage beyond the Kept files
+	Failed   []string // names of the corpus files that crashed or hung, which are also included in Kept
+	Blocks   int      // number of covered blocks, which is the same for the distilled subset and the full corpus
+	Coverpkg string   // the package we measured coverage for
+}
+
+// DistillCorpus finds a minimal subset of a corpus that preserves the total coverage of the corpus.
+// Each corpus file is executed via a coverage-instrumented build of the fuzz function,
+// recording which blocks of the fuzz function's package it covers. A subset is then chosen greedily,
+// repeatedly selecting the file that covers the most blocks that are not yet covered
+// (preferring smaller files for ties) until the subset covers every block covered by the corpus.
+// Files that crash or hang do not produce coverage and are conservatively kept.
+// DistillCorpus does not modify corpusDir.
+func DistillCorpus(function Func, corpusDir string, opts DistillOptions) (Distilled, error) {
+	report := func(err error) (Distilled, error) {
+		return Distilled{}, fmt.Errorf("distill corpus for %s: %v", function.FuzzName(), err)
+	}
+	if opts.FuncTimeout < 1*time.Second {
+		return Distilled{}, fmt.Errorf("minimum allowed func timeout value is 1 second")
+	}
+
+	entries, err := ioutil.ReadDir(corpusDir)
+	if err != nil {
+		return report(err)
+	}
+
+	coverpkg := function.PkgPath
+	runner, err := newInputRunner(function, false, "distilltest", []string{"-cover", "-covermode=set", "-coverpkg=" + coverpkg}, opts.FuncTimeout)
+	if err != nil {
+		return report(err)
+	}
+	defer runner.close()
+	profile := filepath.Join(runner.dir, "cover.out")
+
+	var inputs []coverInput
+	result := Distilled{Coverpkg: coverpkg}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(corpusDir, entry.Name()))
+		if err != nil {
+			return report(err)
+		}
+		os.Remove(profile)
+		output, err := runner.run(data, "-test.coverprofile="+profile)
+		if err != nil {
+			return report(err)
+		}
+		if crashSignature(output) != "" || !PathExists(profile) {
+			// we could not measure this input, so keep it.
+			if opts.Verbose {
+				info("corpus file %s failed:\n%s", entry.Name(), output)
+			}
+			result.Failed = append(result.Failed, entry.Name())
+			continue
+		}
+		blocks, err := coveredBlocks(profile)
+		if err != nil {
+			return report(err)
+		}
+		inputs = append(inputs, coverInput{name: entry.Name(), size: entry.Size(), blocks: blocks})
+	}
+
+	result.Kept, result.Blocks = selectCovering(inputs)
+	if result.Blocks == 0 && len(inputs) > 0 {
+		return report(fmt.Errorf("no coverage recorded for package %s", coverpkg))
+	}
+
+	kept := make(map[string]bool)
+	for _, name := range result.Kept {
+		kept[name] = true
+	}
+	for _, in := range inputs {
+		if !kept[in.name] {
+			result.Dropped = append(result.Dropped, in.name)
+		}
+	}
+	result.Kept = append(result.Kept, result.Failed...)
+	sort.Strings(result.Kept)
+	return result, nil
+}
+
+// droppedFile is the file in a work dir that lists the corpus files removed by distilling in place,
+// one name per line, which keeps CopyCorpus from restoring them from another corpus location.
+const droppedFile = "distilled-dropped"
+
+// RecordDropped records that the corpus files named by dropped were removed from the corpus in workDir
+// by distilling in place.
+func RecordDropped(workDir string, dropped []string) error {
+	if len(dropped) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(filepath.Join(workDir, droppedFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("recording distilled corpus: %v", err)
+	}
+	for _, name := range dropped {
+		fmt.Fprintln(f, name)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("recording distilled corpus: %v", err)
+	}
+	return nil
+}
+
+// CopyCorpus copies the corpus in srcWorkDir to the corpus in dstWorkDir, such as to
+// seed a corpus in testdata from the corpus in GOPATH/pkg/fuzz/corpus. Like CopyDir,
+// it leaves alone files that already exist in dstWorkDir, and it also skips files that
+// were removed from dstWorkDir's corpus by distilling in place.
+func CopyCorpus(dstWorkDir, srcWorkDir string) error {
+	report := func(err error) error {
+		return fmt.Errorf("copy corpus failed from %s to %s: %v", srcWorkDir, dstWorkDir, err)
+	}
+	srcCorpusDir := filepath.Join(srcWorkDir, "corpus")
+	dstCorpusDir := filepath.Join(dstWorkDir, "corpus")
+	files, err := ioutil.ReadDir(srcCorpusDir)
+	if err != nil {
+		return report(err)
+	}
+	dropped := make(map[string]bool)
+	if data, err := ioutil.ReadFile(filepath.Join(dstWorkDir, droppedFile)); err == nil {
+		for _, name := range strings.Fields(string(data)) {
+			dropped[name] = true
+		}
+	}
+	if err := os.MkdirAll(dstCorpusDir, 0700); err != nil {
+		return report(err)
+	}
+	for _, f := range files {
+		if dropped[f.Name()] {
+			continue
+		}
+		dstName := filepath.Join(dstCorpusDir, f.Name())
+		srcName := filepath.Join(srcCorpusDir, f.Name())
+		if f.IsDir() {
+			err = CopyDir(dstName, srcName)
+		} else {
+			err = CopyFile(dstName, srcName)
+		}
+		if err != nil {
+			return report(err)
+		}
+	}
+	return nil
+}
+
+// coverInput is a corpus file along with the blocks it covers.
+type coverInput struct {
+	name   string
+	size   int64
+	blocks []string
+}
+
+// selectCovering greedily selects inputs that together cover every block covered by all of the inputs.
+// It repeatedly selects the input that covers the most blocks not yet covered, preferring the smaller input
+// for ties. It returns the names of the selected inputs and the number of covered blocks.
+func selectCovering(inputs []coverInput) ([]string, int) {
+	var selected []string
+	used := make([]bool, len(inputs))
+	covered := make(map[string]bool)
+	for {
+		best, bestNew := -1, 0
+		for i, in := range inputs {
+			if used[i] {
+				continue
+			}
+			n := 0
+			for _, b := range in.blocks {
+				if !covered[b] {
+					n++
+				}
+			}
+			if n > bestNew || (n == bestNew && n > 0 && in.size < inputs[best].size) {
+				best, bestNew = i, n
+			}
+		}
+		if best < 0 {
+			break
+		}
+		used[best] = true
+		for _, b := range inputs[best].blocks {
+			covered[b] = true
+		}
+		selected = append(selected, inputs[best].name)
+	}
+	return selected, len(covered)
+}
+
+// coveredBlocks returns identifiers for the blocks with a non-zero count in a coverage profile.
+func coveredBlocks(profile string) ([]string, error) {
+	profiles, err := cover.ParseProfiles(profile)
+	if err != nil {
+		return nil, err
+	}
+	var blocks []string
+	for _, p := range profiles {
+		for _, b := range p.Blocks {
+			if b.Count > 0 {
+				blocks = append(blocks, fmt.Sprintf("%s:%d.%d,%d.%d", p.FileName, b.StartLine, b.StartCol, b.EndLine, b.EndCol))
+			}
+		}
+	}
+	return blocks, nil
+}
@@ -0,0 +1,81 @@
+package fuzz
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSelectCovering(t *testing.T) {
+	inputs := []coverInput{
+		{name: "a", size: 10, blocks: []string{"1", "2"}},
+		{name: "b", size: 50, blocks: []string{"1", "2", "3", "4"}},
+		{name: "c", size: 5, blocks: []string{"3", "4"}},
+		{name: "d", size: 1, blocks: []string{"4", "5"}},
+		{name: "e", size: 2, blocks: []string{"5"}},
+		{name: "f", size: 1, blocks: nil},
+	}
+	got, blocks := selectCovering(inputs)
+	sort.Strings(got)
+	if want := []string{"b", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("selectCovering() selected %v, want %v", got, want)
+	}
+	if blocks != 5 {
+		t.Errorf("selectCovering() covered %d blocks, want 5", blocks)
+	}
+
+	// ties go to the smaller input.
+	got, _ = selectCovering([]coverInput{
+		{name: "big", size: 100, blocks: []string{"1"}},
+		{name: "small", size: 1, blocks: []string{"1"}},
+	})
+	if want := []string{"small"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("selectCovering() with a tie selected %v, want %v", got, want)
+	}
+}
+
+func TestCopyCorpusAfterDistill(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "fzgo-test-copycorpus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	srcWorkDir := filepath.Join(tempDir, "gopath")
+	dstWorkDir := filepath.Join(tempDir, "testdata")
+	for _, path := range []string{"gopath/corpus/a", "gopath/corpus/b", "gopath/corpus/c", "testdata/corpus/a", "testdata/corpus/b"} {
+		path = filepath.Join(tempDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(filepath.Base(path)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// distill the corpus in testdata in place, which drops b.
+	if err := os.Remove(filepath.Join(dstWorkDir, "corpus", "b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordDropped(dstWorkDir, []string{"b"}); err != nil {
+		t.Fatalf("RecordDropped() failed: %v", err)
+	}
+
+	// a later fuzzing run copies in the cached corpus, which adds c but does not restore b.
+	if err := CopyCorpus(dstWorkDir, srcWorkDir); err != nil {
+		t.Fatalf("CopyCorpus() failed: %v", err)
+	}
+	entries, err := ioutil.ReadDir(filepath.Join(dstWorkDir, "corpus"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	if want := []string{"a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("corpus after CopyCorpus() = %v, want %v", got, want)
+	}
+}
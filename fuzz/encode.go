@@ -0,0 +1,379 @@
+package fuzz
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/tools/imports"
+)
+
+// TextArgs converts command line text for each parameter of the fuzz function to JSON for EncodeInput.
+// The text for a string or []byte parameter (or an io.Reader or similar that is filled from a []byte)
+// is used as is, and the text for other parameters must be JSON, such as 'true', '42' or '{"A": 1}'.
+func TextArgs(function Func, args []string) ([]json.RawMessage, error) {
+	sig, ok := function.TypesFunc.Type().(*types.Signature)
+	if !ok {
+		return nil, fmt.Errorf("function is not *types.Signature (%+v)", function.TypesFunc)
+	}
+	if len(args) != sig.Params().Len() {
+		return nil, fmt.Errorf("%s has %d parameters, but %d values were supplied", function.FuzzName(), sig.Params().Len(), len(args))
+	}
+	var result []json.RawMessage
+	for i, arg := range args {
+		var err error
+		var b []byte
+		switch encodedKind(sig.Params().At(i).Type()) {
+		case "string":
+			b, err = json.Marshal(arg)
+		case "bytes":
+			b, err = json.Marshal([]byte(arg))
+		default:
+			if !json.Valid([]byte(arg)) {
+				return nil, fmt.Errorf("value for %s is not valid JSON: %s", sig.Params().At(i).Name(), arg)
+			}
+			b = []byte(arg)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, b)
+	}
+	return result, nil
+}
+
+// encodedKind reports how a parameter of type t is filled by a rich signature wrapper:
+// "string" for a string type, "bytes" for a []byte type or an interface filled from a []byte,
+// "none" for an interface or func that is not filled from the input, and otherwise "json".
+// The JSON for a func is the results it returns, such as '[{"R1": true}, {"R1": false}]'.
+func encodedKind(t types.Type) string {
+	switch InterfaceImpl[types.TypeString(t, externalQualifier)] {
+	case "bytes.Reader", "bytes.Buffer", "ioutil.NopCloser":
+		return "bytes"
+	case "ioutil.Discard", "context.Background":
+		return "none"
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if u.Info()&types.IsString != 0 {
+			return "string"
+		}
+	case *types.Slice:
+		if b, ok := u.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			return "bytes"
+		}
+	case *types.Signature:
+		if _, ok := funcResultsType(u); !ok {
+			return "none"
+		}
+	}
+	return "json"
+}
+
+// EncodeInput returns a corpus input for the fuzz function that decodes to args,
+// which are JSON values for each parameter in the form used by encoding/json.
+// For a plain 'func([]byte) int' signature, the input is simply the []byte argument.
+// For a rich signature, args are unmarshaled into the parameter types in a generated test
+// that is built with the user's package, and then encoded via randparam.Encode,
+// using any options set by a '//fzgo:randparam' directive on the fuzz function.
+// The values for parameters that are not filled from the input, such as a context.Context, are ignored.
+func EncodeInput(function Func, args []json.RawMessage) ([]byte, error) {
+	report := func(err error) ([]byte, error) {
+		return nil, fmt.Errorf("encode input for %s: %v", function.FuzzName(), err)
+	}
+	sig, ok := function.TypesFunc.Type().(*types.Signature)
+	if !ok {
+		return report(fmt.Errorf("function is not *types.Signature (%+v)", function.TypesFunc))
+	}
+	if len(args) != sig.Params().Len() {
+		return report(fmt.Errorf("%d parameters, but %d values were supplied", sig.Params().Len(), len(args)))
+	}
+	plain, err := IsPlainSig(function.TypesFunc)
+	if err != nil {
+		return report(err)
+	}
+	if plain {
+		var data []byte
+		if err := json.Unmarshal(args[0], &data); err != nil {
+			return report(err)
+		}
+		return data, nil
+	}
+
+	if err := checkEncodable(function); err != nil {
+		return report(err)
+	}
+	input, err := json.Marshal(args)
+	if err != nil {
+		return report(err)
+	}
+	opts, err := FuzzerOptions(function)
+	if err != nil {
+		return report(err)
+	}
+	data, err := runEncodeTest(function, input, func(w *bytes.Buffer) { createEncodeTest(w, function, opts) })
+	if err != nil {
+		return report(err)
+	}
+	return data, nil
+}
+
+// SeedInputs returns the corpus inputs for the seeds returned by the fuzz function's seeds function,
+// such as FuzzFooSeeds for FuzzFoo. Each seed is a []interface{} with one value per parameter
+// of the fuzz function, similar to the arguments to testing.F.Add, and the type of each value must
+// match the type of its parameter. A parameter that is filled from a []byte by a rich signature wrapper,
+// such as an io.Reader, takes a []byte, a func parameter takes the results it returns,
+// such as a []struct{ R1 bool }, and a parameter that is not filled from the input,
+// such as a context.Context, takes any value, which is ignored.
+// The inputs are computed by a generated test that calls the seeds function and encodes each seed
+// via randparam.Encode, except that the input for a plain 'func([]byte) int' is simply the []byte.
+func SeedInputs(function Func) ([][]byte, error) {
+	report := func(err error) ([][]byte, error) {
+		return nil, fmt.Errorf("encode seeds from %s for %s: %v", function.SeedsFunc, function.FuzzName(), err)
+	}
+	if function.SeedsFunc == "" {
+		return nil, nil
+	}
+	plain, err := IsPlainSig(function.TypesFunc)
+	if err != nil {
+		return report(err)
+	}
+	if !plain {
+		if err := checkEncodable(function); err != nil {
+			return report(err)
+		}
+	}
+	opts, err := FuzzerOptions(function)
+	if err != nil {
+		return report(err)
+	}
+	output, err := runEncodeTest(function, nil, func(w *bytes.Buffer) { createSeedsTest(w, function, plain, opts) })
+	if err != nil {
+		return report(err)
+	}
+	var inputs [][]byte
+	if err := json.Unmarshal(output, &inputs); err != nil {
+		return report(err)
+	}
+	return inputs, nil
+}
+
+// runEncodeTest creates and runs a test generated by src, which is built with the user's package.
+// The test can read input from the file named by FZGO_INPUT and should write its result
+// to the file named by FZGO_OUTPUT, which is returned.
+func runEncodeTest(function Func, input []byte, src func(w *bytes.Buffer)) ([]byte, error) {
+	target, err := newTarget(function, false)
+	if err != nil {
+		return nil, err
+	}
+	defer target.removeTemp()
+	dir, err := createHarness(target, "encodetest", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	var b bytes.Buffer
+	src(&b)
+	out, err := imports.Process(filepath.Join(dir, "encode_test.go"), b.Bytes(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed adjusting imports: %v\n%s", err, b.String())
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "encode_test.go"), out, 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "input"), input, 0644); err != nil {
+		return nil, err
+	}
+
+	env := target.wrapperEnv
+	if len(env) == 0 {
+		env = os.Environ()
+	}
+	env = append(env, "FZGO_INPUT="+filepath.Join(dir, "input"), "FZGO_OUTPUT="+filepath.Join(dir, "output"))
+	var output bytes.Buffer
+	args := []string{"test", buildTagsArg, "-count=1", "-run=^TestEncode$", "."}
+	if err := execCmdOutput("go", args, env, dir, 0, &output, &output); err != nil {
+		return nil, fmt.Errorf("%v\n%s", err, output.String())
+	}
+	return ioutil.ReadFile(filepath.Join(dir, "output"))
+}
+
+// createEncodeTest emits a test that unmarshals the JSON args in the file named by FZGO_INPUT
+// into variables of the same types that a rich signature wrapper fills for function,
+// and writes their encoding to the file named by FZGO_OUTPUT.
+func createEncodeTest(w *bytes.Buffer, function Func, opts []string) {
+	sig := function.TypesFunc.Type().(*types.Signature)
+	encodeTestImports(w, function)
+	fmt.Fprintf(w, `
+// TestEncode is an automatically generated test that encodes the arguments for %s.
+func TestEncode(t *testing.T) {
+	data, err := ioutil.ReadFile(os.Getenv("FZGO_INPUT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var args []json.RawMessage
+	if err := json.Unmarshal(data, &args); err != nil {
+		t.Fatal(err)
+	}
+
+`, function.FuzzName())
+
+	var names []string
+	for i := 0; i < sig.Params().Len(); i++ {
+		v := sig.Params().At(i)
+		typ, ok := encodedType(v)
+		if !ok {
+			continue
+		}
+		name := fmt.Sprintf("__fzgoArg%d", i+1)
+		fmt.Fprintf(w, "\tvar %s %s\n", name, typ)
+		fmt.Fprintf(w, "\tif err := json.Unmarshal(args[%d], &%s); err != nil {\n", i, name)
+		fmt.Fprintf(w, "\t\tt.Fatalf(\"%s: %%v\", err)\n\t}\n", v.Name())
+		arg := shapedArg(name, v)
+		fmt.Fprintf(w, "\tif _, err := %s; err != nil {\n", encodeCall([]string{arg}, opts))
+		fmt.Fprintf(w, "\t\tt.Fatalf(\"%s: %%v\", err)\n\t}\n", v.Name())
+		names = append(names, arg)
+	}
+	fmt.Fprintf(w, `
+	out, err := %s
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(os.Getenv("FZGO_OUTPUT"), out, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+`, encodeCall(names, opts))
+}
+
+// createSeedsTest emits a test that calls the seeds function for function,
+// and writes the encoding of each seed to the file named by FZGO_OUTPUT as JSON.
+func createSeedsTest(w *bytes.Buffer, function Func, plain bool, opts []string) {
+	f := function.TypesFunc
+	sig := f.Type().(*types.Signature)
+	encodeTestImports(w, function)
+	fmt.Fprintf(w, `
+// TestEncode is an automatically generated test that encodes the seeds from %s.%s.
+func TestEncode(t *testing.T) {
+	var inputs [][]byte
+	for i, seed := range %s.%s() {
+		if len(seed) != %d {
+			t.Fatalf("seed %%d: got %%d values, want %d", i, len(seed))
+		}
+`, f.Pkg().Name(), function.SeedsFunc, f.Pkg().Name(), function.SeedsFunc, sig.Params().Len(), sig.Params().Len())
+
+	var names []string
+	for i := 0; i < sig.Params().Len(); i++ {
+		v := sig.Params().At(i)
+		typ, ok := encodedType(v)
+		if !ok {
+			continue
+		}
+		name := fmt.Sprintf("__fzgoArg%d", i+1)
+		fmt.Fprintf(w, "\t\t%s, ok := seed[%d].(%s)\n", name, i, typ)
+		fmt.Fprintf(w, "\t\tif !ok {\n")
+		fmt.Fprintf(w, "\t\t\tt.Fatalf(\"seed %%d: %s: got %%T, want %s\", i, seed[%d])\n\t\t}\n", v.Name(), typ, i)
+		if !plain {
+			name = shapedArg(name, v)
+		}
+		names = append(names, name)
+	}
+	if plain {
+		fmt.Fprintf(w, "\t\tinputs = append(inputs, %s)\n", names[0])
+	} else {
+		fmt.Fprintf(w, `		out, err := %s
+		if err != nil {
+			t.Fatalf("seed %%d: %%v", i, err)
+		}
+		inputs = append(inputs, out)
+`, encodeCall(names, opts))
+	}
+	fmt.Fprintf(w, `	}
+	data, err := json.Marshal(inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(os.Getenv("FZGO_OUTPUT"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+`)
+}
+
+// shapedArg returns the Go source for the argument to randparam.Encode for the variable name
+// holding the value of parameter v, which is a randparam.Shaped if v has a shape.
+func shapedArg(name string, v *types.Var) string {
+	shape := encodedShape(v)
+	if shape == "" {
+		return name
+	}
+	return fmt.Sprintf("randparam.Shaped{Value: %s, Shape: %s}", name, shape)
+}
+
+// encodeTestImports emits the start of a generated test that uses function's package.
+func encodeTestImports(w *bytes.Buffer, function Func) {
+	fmt.Fprintf(w, "\npackage encodetest\n")
+	if !function.XTest {
+		fmt.Fprintf(w, "\nimport \"%s\"\n", function.PkgPath)
+	} else {
+		// see createWrapper.
+		fmt.Fprintf(w, "\nimport %s \"%s\"\n", function.TypesFunc.Pkg().Name(), function.buildPkgPath())
+	}
+	fmt.Fprintf(w, "\nimport \"github.com/thepudds/fzgo/randparam\"\n")
+}
+
+// checkEncodable reports an error if the fuzz function has an interface parameter with implementations
+// registered with '//fzgo:impl', which are chosen and constructed from the input in a way we cannot encode.
+func checkEncodable(function Func) error {
+	impls, err := FindImpls(function.pkgs)
+	if err != nil {
+		return err
+	}
+	sig := function.TypesFunc.Type().(*types.Signature)
+	for i := 0; i < sig.Params().Len(); i++ {
+		v := sig.Params().At(i)
+		if len(implsFor(v.Type(), impls)) > 0 {
+			return fmt.Errorf("%s: values cannot be encoded for an interface with %s implementations", v.Name(), implDirective)
+		}
+	}
+	return nil
+}
+
+// encodedType returns the type of the value that a rich signature wrapper fills for parameter v,
+// or false if v is not filled from the input.
+func encodedType(v *types.Var) (string, bool) {
+	typ := types.TypeString(v.Type(), externalQualifier)
+	switch encodedKind(v.Type()) {
+	case "none":
+		return "", false
+	case "bytes":
+		if _, ok := InterfaceImpl[typ]; ok {
+			// the wrapper fills a []byte for an interface like io.Reader.
+			return "[]byte", true
+		}
+	}
+	if sig, ok := v.Type().Underlying().(*types.Signature); ok {
+		// the wrapper fills the results for a func.
+		return funcResultsType(sig)
+	}
+	return typ, true
+}
+
+// AddInput writes data to corpusDir using the sha1 of data as the filename, as go-fuzz does,
+// and returns the path of the input. It also reports whether the input was already in corpusDir.
+func AddInput(corpusDir string, data []byte) (string, bool, error) {
+	path := filepath.Join(corpusDir, fmt.Sprintf("%x", sha1.Sum(data)))
+	if PathExists(path) {
+		return path, true, nil
+	}
+	if err := os.MkdirAll(corpusDir, os.ModePerm); err != nil {
+		return "", false, err
+	}
+	return path, false, writeHashed(corpusDir, data, "")
+}
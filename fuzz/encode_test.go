@@ -0,0 +1,84 @@
+package fuzz
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTextArgs(t *testing.T) {
+	functions, err := FindFunc("github.com/thepudds/fzgo/examples/richsignatures", "FuzzWithBasicTypes", nil, false)
+	if err != nil {
+		t.Fatalf("FindFunc() error = %v", err)
+	}
+	got, err := TextArgs(functions[0], []string{"a+b", "aab", "true"})
+	if err != nil {
+		t.Fatalf("TextArgs() error = %v", err)
+	}
+	want := []json.RawMessage{
+		json.RawMessage(`"a+b"`),
+		json.RawMessage(`"YWFi"`),
+		json.RawMessage(`true`),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("TextArgs() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := TextArgs(functions[0], []string{"a+b", "aab", "yes"}); err == nil {
+		t.Errorf("TextArgs() with invalid JSON for a bool succeeded, want error")
+	}
+	if _, err := TextArgs(functions[0], []string{"a+b"}); err == nil {
+		t.Errorf("TextArgs() with too few values succeeded, want error")
+	}
+}
+
+func TestCreateSeedsTest(t *testing.T) {
+	functions, err := FindFunc("github.com/thepudds/fzgo/examples/richsignatures", "FuzzWithBasicTypes", nil, false)
+	if err != nil {
+		t.Fatalf("FindFunc() error = %v", err)
+	}
+	function := functions[0]
+	function.SeedsFunc = "FuzzWithBasicTypesSeeds"
+
+	var b bytes.Buffer
+	createSeedsTest(&b, function, false, nil)
+	if _, err := parser.ParseFile(token.NewFileSet(), "", b.Bytes(), 0); err != nil {
+		t.Fatalf("generated test does not parse: %v\n%s", err, b.String())
+	}
+	for _, want := range []string{
+		"for i, seed := range pkgname.FuzzWithBasicTypesSeeds() {",
+		"if len(seed) != 3 {",
+		"__fzgoArg1, ok := seed[0].(string)",
+		"__fzgoArg2, ok := seed[1].([]byte)",
+		"__fzgoArg3, ok := seed[2].(bool)",
+		"out, err := randparam.Encode(__fzgoArg1, __fzgoArg2, __fzgoArg3)",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("generated test missing %q:\n%s", want, b.String())
+		}
+	}
+	b.Reset()
+	createSeedsTest(&b, function, false, []string{"randparam.MaxElements(20)"})
+	want := "out, err := randparam.EncodeWithOptions([]interface{}{__fzgoArg1, __fzgoArg2, __fzgoArg3}, randparam.MaxElements(20))"
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("generated test with options missing %q:\n%s", want, b.String())
+	}
+	shapes, err := FindFunc("github.com/thepudds/fzgo/examples/richsignatures", "FuzzWithStringShapes", nil, false)
+	if err != nil {
+		t.Fatalf("FindFunc() error = %v", err)
+	}
+	function = shapes[0]
+	function.SeedsFunc = "FuzzWithStringShapesSeeds"
+	b.Reset()
+	createSeedsTest(&b, function, false, nil)
+	want = "out, err := randparam.Encode(randparam.Shaped{Value: __fzgoArg1, Shape: randparam.UTF8}, " +
+		"randparam.Shaped{Value: __fzgoArg2, Shape: randparam.Number}, __fzgoArg3)"
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("generated test with shapes missing %q:\n%s", want, b.String())
+	}
+}
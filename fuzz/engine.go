@@ -0,0 +1,139 @@
+package fuzz
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Engine identifies the fuzzing engine used to instrument and run a fuzz target.
+type Engine string
+
+// The supported fuzzing engines.
+const (
+	GoFuzz    Engine = "go-fuzz"   // dvyukov/go-fuzz, via go-fuzz-build and go-fuzz
+	Native    Engine = "native"    // Go 1.18+ native fuzzing, via a synthesized testing.F harness and 'go test -fuzz'
+	LibFuzzer Engine = "libfuzzer" // libFuzzer, via 'go-fuzz-build -libfuzzer' and clang
+)
+
+// ParseEngine returns the Engine for a name such as "go-fuzz", "native" or "libfuzzer".
+// An empty name selects the default of go-fuzz.
+func ParseEngine(s string) (Engine, error) {
+	if s == "" {
+		return GoFuzz, nil
+	}
+	e := Engine(s)
+	if _, ok := engines[e]; !ok {
+		var names []string
+		for _, name := range engineNames {
+			names = append(names, string(name))
+		}
+		return "", fmt.Errorf("unknown fuzzing engine %q, supported engines are: %s", s, strings.Join(names, ", "))
+	}
+	return e, nil
+}
+
+// engine is implemented by each fuzzing engine.
+type engine interface {
+	// check lightly validates that any required tools seem to be installed.
+	check() error
+	// tools returns the names of binaries that should be part of our cache hash.
+	tools() []string
+	// artifact returns the filename of the instrumented artifact within the cache dir.
+	artifact() string
+	// prepare readies the target for build, prior to computing the target's cache hash
+	// so that the hash covers any changes, such as to the go.mod of a temporary wrapper module.
+	prepare(t Target) error
+	// build creates the instrumented artifact for the target at outFile.
+	build(t Target, outFile string, verbose bool) error
+	// run fuzzes using the instrumented artifact, with corpus and crashers in workDir.
+	run(t Target, artifactPath, workDir string, opts runOptions) error
+}
+
+// runOptions are the options that control a fuzzing run.
+type runOptions struct {
+	StartOptions
+	stdout, stderr io.Writer // where the engine's output should go
+}
+
+var engines = map[Engine]engine{
+	GoFuzz:    goFuzzEngine{},
+	Native:    nativeEngine{},
+	LibFuzzer: libFuzzerEngine{},
+}
+
+// engineNames lists our engines in a stable order for messages.
+var engineNames = []Engine{GoFuzz, Native, LibFuzzer}
+
+// impl returns the implementation for an Engine. The zero value is go-fuzz.
+func (e Engine) impl() (engine, error) {
+	if e == "" {
+		e = GoFuzz
+	}
+	eng, ok := engines[e]
+	if !ok {
+		return nil, fmt.Errorf("unknown fuzzing engine %q", string(e))
+	}
+	return eng, nil
+}
+
+// goFuzzEngine uses dvyukov/go-fuzz.
+type goFuzzEngine struct{}
+
+func (goFuzzEngine) check() error { return checkGoFuzz() }
+
+func (goFuzzEngine) tools() []string { return []string{"go-fuzz-build"} }
+
+func (goFuzzEngine) artifact() string { return "fuzz.zip" }
+
+func (goFuzzEngine) prepare(t Target) error { return prepareGoFuzzBuild(t) }
+
+func (goFuzzEngine) build(t Target, outFile string, verbose bool) error {
+	// to support experimentation, initial args for go-fuzz-build are
+	// populated by the optional FZGOFLAGSBUILD env var
+	// (or an empty slice if FZGOFLAGSBUILD is not set).
+	args := fzgoEnvFlags("FZGOFLAGSBUILD")
+	if !t.hasWrapper {
+		args = append(args,
+			"-func="+t.UserFunc.FuncName,
+			"-o="+outFile,
+			// "-race", // TODO: make a flag
+			buildTagsArg,
+			t.UserFunc.buildPkgPath(),
+		)
+	} else {
+		args = append(args,
+			"-func="+t.wrapperFunc.FuncName,
+			"-o="+outFile,
+			// "-race", // TODO: make a flag
+			buildTagsArg,
+			t.wrapperFunc.PkgPath,
+		)
+	}
+
+	err := execCmd("go-fuzz-build", args, t.wrapperEnv, t.dir(), 0)
+	if err != nil {
+		return fmt.Errorf("go-fuzz-build failed with args %q: %v", args, err)
+	}
+	return nil
+}
+
+func (goFuzzEngine) run(t Target, artifactPath, workDir string, opts runOptions) error {
+	verboseLevel := 0
+	if opts.Verbose {
+		verboseLevel = 1
+	}
+
+	// to support experimentation, initial args for go-fuzz are
+	// populated by the optional FZGOFLAGSFUZZ env var
+	// (or an empty slice if FZGOFLAGSFUZZ is not set).
+	runArgs := fzgoEnvFlags("FZGOFLAGSFUZZ")
+	runArgs = append(runArgs,
+		fmt.Sprintf("-bin=%s", artifactPath),
+		fmt.Sprintf("-workdir=%s", workDir),
+		fmt.Sprintf("-procs=%d", opts.Parallel),
+		fmt.Sprintf("-timeout=%d", int(opts.FuncTimeout.Seconds())), // this is not total run time
+		fmt.Sprintf("-v=%d", verboseLevel),
+	)
+	return execCmdOutput("go-fuzz", runArgs, nil, "", opts.MaxDuration, opts.stdout, opts.stderr)
+}
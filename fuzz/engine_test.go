@@ -0,0 +1,59 @@
+package fuzz
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEngine(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Engine
+		wantErr bool
+	}{
+		{"", GoFuzz, false},
+		{"go-fuzz", GoFuzz, false},
+		{"native", Native, false},
+		{"libfuzzer", LibFuzzer, false},
+		{"afl", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEngine(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseEngine(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseEngine(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLibFuzzerArtifactOutputs(t *testing.T) {
+	output := `INFO: Seed: 1
+#2	INITED cov: 3 ft: 3 corp: 1/1b exec/s: 0 rss: 30Mb
+panic: first boom
+
+goroutine 1 [running]:
+==10== ERROR: libFuzzer: deadly signal
+artifact_prefix='/w/crashers/'; Test unit written to /w/crashers/crash-aaa
+#100	NEW    cov: 4 ft: 4 corp: 2/3b
+INFO: log from the inner process:
+panic: second boom
+artifact_prefix='/w/crashers/'; Test unit written to /w/crashers/crash-bbb
+#200	DONE   cov: 4 ft: 4 corp: 2/3b
+`
+	got := libFuzzerArtifactOutputs(output)
+	if len(got) != 2 {
+		t.Fatalf("libFuzzerArtifactOutputs() returned %d outputs, want 2: %q", len(got), got)
+	}
+	if s := got["crash-aaa"]; !strings.HasPrefix(s, "INFO: Seed: 1\n") || !strings.Contains(s, "panic: first boom") ||
+		!strings.HasSuffix(s, "crash-aaa\n") {
+		t.Errorf("output for crash-aaa = %q", s)
+	}
+	if s := got["crash-bbb"]; !strings.HasPrefix(s, "#100") || strings.Contains(s, "first boom") ||
+		crashSignature(s) != "panic: second boom" {
+		t.Errorf("output for crash-bbb = %q", s)
+	}
+}
@@ -0,0 +1,174 @@
+package fuzz
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Event is a machine-readable record of fzgo's progress, emitted as a stream of JSON objects
+// (one per line) by 'fzgo test -fuzz -json'. Event is similar in spirit to the events
+// emitted by 'go test -json' (see 'go doc test2json'), with Package holding the import path
+// and Test holding the fuzz function name.
+type Event struct {
+	Time    time.Time
+	Action  string
+	Package string  `json:",omitempty"`
+	Test    string  `json:",omitempty"`
+	Elapsed float64 `json:",omitempty"` // seconds
+	Output  string  `json:",omitempty"`
+	Path    string  `json:",omitempty"` // the instrumented binary for build and cached, or the input for crash
+	Stats   *Stats  `json:",omitempty"`
+}
+
+// The Actions of an Event.
+const (
+	ActionDiscover = "discover" // a fuzz function was found
+	ActionBuild    = "build"    // building an instrumented binary started
+	ActionCached   = "cached"   // a cached instrumented binary is being used
+	ActionStart    = "start"    // fuzzing a target started
+	ActionStats    = "stats"    // the fuzzing engine reported its periodic status
+	ActionCrash    = "crash"    // a new crasher was found
+	ActionStop     = "stop"     // fuzzing a target stopped
+	ActionOutput   = "output"   // fzgo or a fuzzing engine printed output
+)
+
+var events struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// SetEventOutput directs a stream of JSON Events to w, or disables Events if w is nil.
+// While Events are enabled, fzgo's informational messages and the output of the
+// fuzzing engine are emitted as output Events rather than being printed directly.
+func SetEventOutput(w io.Writer) {
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	if w == nil {
+		events.enc = nil
+	} else {
+		events.enc = json.NewEncoder(w)
+	}
+}
+
+// EventsEnabled reports whether Events are enabled.
+func EventsEnabled() bool {
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	return events.enc != nil
+}
+
+// EmitEvent writes e to the stream of Events, if enabled.
+// If e.Time is not set, it is set to the current time.
+func EmitEvent(e Event) {
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	if events.enc == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	events.enc.Encode(e)
+}
+
+// FuncEvent returns an Event with the supplied action for a fuzz function.
+func FuncEvent(action string, function Func) Event {
+	return Event{Action: action, Package: function.PkgPath, Test: function.FuncName}
+}
+
+// eventWriter is an io.Writer that emits each Write as an output Event.
+type eventWriter struct {
+	function Func // may be the zero value for output not specific to a fuzz function
+}
+
+func (ew eventWriter) Write(p []byte) (int, error) {
+	e := FuncEvent(ActionOutput, ew.function)
+	e.Output = string(p)
+	EmitEvent(e)
+	return len(p), nil
+}
+
+// eventOptions returns opts adjusted so that the fuzzing engine's output and stats
+// for targets are emitted as Events, along with a crash Event for each new crasher in the
+// targets' workDirs. The returned func emits any remaining crash Events once fuzzing stops.
+// If Events are not enabled, eventOptions returns opts unchanged.
+func eventOptions(opts StartOptions, targets []Target, workDirs []string) (StartOptions, func()) {
+	if !EventsEnabled() {
+		return opts, func() {}
+	}
+
+	watches := make(map[string]*crasherWatch)
+	for i, target := range targets {
+		watches[target.FuzzName()] = newCrasherWatch(target.UserFunc, workDirs[i])
+	}
+
+	onStats := opts.OnStats
+	opts.OnStats = func(s Stats) {
+		cw := watches[s.FuzzName]
+		if cw != nil {
+			e := FuncEvent(ActionStats, cw.function)
+			e.Stats = &s
+			EmitEvent(e)
+			cw.update(s.Crashers)
+		}
+		if onStats != nil {
+			onStats(s)
+		}
+	}
+	if len(targets) == 1 {
+		opts.Output = eventWriter{function: targets[0].UserFunc}
+	}
+	return opts, func() {
+		for _, target := range targets {
+			watches[target.FuzzName()].check()
+		}
+	}
+}
+
+// crasherWatch emits a crash Event for each new crasher that appears in a workDir.
+type crasherWatch struct {
+	function Func
+	dir      string
+
+	mu       sync.Mutex
+	seen     map[string]bool
+	crashers int // the latest count of crashers reported by the fuzzing engine
+}
+
+func newCrasherWatch(function Func, workDir string) *crasherWatch {
+	cw := &crasherWatch{function: function, dir: filepath.Join(workDir, "crashers"), seen: make(map[string]bool)}
+	// crashers that exist prior to fuzzing are not new.
+	for _, name := range listCrashers(cw.dir) {
+		cw.seen[name] = true
+	}
+	return cw
+}
+
+// update checks for new crashers if the count reported by the fuzzing engine has increased.
+func (cw *crasherWatch) update(crashers int) {
+	cw.mu.Lock()
+	increased := crashers > cw.crashers
+	cw.crashers = crashers
+	cw.mu.Unlock()
+	if increased {
+		cw.check()
+	}
+}
+
+// check emits a crash Event for any crashers we have not yet seen.
+func (cw *crasherWatch) check() {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	for _, name := range listCrashers(cw.dir) {
+		if cw.seen[name] {
+			continue
+		}
+		cw.seen[name] = true
+		e := FuncEvent(ActionCrash, cw.function)
+		e.Path = filepath.Join(cw.dir, name)
+		EmitEvent(e)
+	}
+}
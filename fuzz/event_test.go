@@ -0,0 +1,84 @@
+package fuzz
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEventOptions(t *testing.T) {
+	var buf bytes.Buffer
+	SetEventOutput(&buf)
+	defer SetEventOutput(nil)
+
+	workDir, err := ioutil.TempDir("", "fzgo-event-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workDir)
+	crashersDir := filepath.Join(workDir, "crashers")
+	if err := os.MkdirAll(crashersDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile := func(name string) {
+		if err := ioutil.WriteFile(filepath.Join(crashersDir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a crasher from a prior run is not reported.
+	writeFile("old")
+
+	target := Target{UserFunc: Func{FuncName: "FuzzFoo", PkgName: "pkg", PkgPath: "example.com/pkg"}}
+	opts, checkCrashers := eventOptions(StartOptions{}, []Target{target}, []string{workDir})
+	stdout, _, flush := opts.outputWriters(target.FuzzName())
+
+	stdout.Write([]byte("some output\n"))
+	writeFile("new1")
+	writeFile("new1.output")
+	stdout.Write([]byte("2019/01/01 12:00:00 workers: 1, corpus: 5 (now), crashers: 1, restarts: 1/0, execs: 0 (0/sec), cover: 0, uptime: 3s\n"))
+	writeFile("new2")
+	flush()
+	checkCrashers()
+
+	lines := strings.Split(buf.String(), "\n")
+	var got []Event
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e Event
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Time.IsZero() || e.Package != "example.com/pkg" || e.Test != "FuzzFoo" {
+			t.Errorf("unexpected event: %+v", e)
+		}
+		got = append(got, e)
+	}
+
+	want := []struct {
+		action, output, path string
+	}{
+		{ActionOutput, "some output\n", ""},
+		{ActionStats, "", ""},
+		{ActionCrash, "", filepath.Join(crashersDir, "new1")},
+		{ActionCrash, "", filepath.Join(crashersDir, "new2")},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Action != want[i].action || got[i].Output != want[i].output || got[i].Path != want[i].path {
+			t.Errorf("event %d = %+v, want action %q output %q path %q", i, got[i], want[i].action, want[i].output, want[i].path)
+		}
+	}
+	if got[1].Stats == nil || got[1].Stats.Corpus != 5 || got[1].Stats.Crashers != 1 {
+		t.Errorf("stats event has Stats %+v, want corpus 5 and crashers 1", got[1].Stats)
+	}
+	// the uptime is encoded in seconds.
+	if want := `"Uptime":3}`; !strings.Contains(lines[1], want) {
+		t.Errorf("stats event encoded as %s, want it to contain %s", lines[1], want)
+	}
+}
@@ -2,6 +2,7 @@ package fuzz
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -9,18 +10,22 @@ import (
 	"time"
 )
 
-// Instrument builds the instrumented binary and fuzz.zip if they do not already
-// exist in the fzgo cache. If instead there is a cache hit, Instrument prints to stderr
-// that the cached is being used.
+// Instrument builds the instrumented binary (such as a go-fuzz fuzz.zip) using the requested
+// fuzzing engine if it does not already exist in the fzgo cache. If instead there is a cache hit,
+// Instrument prints to stderr that the cached is being used.
 // cacheDir is the location for the instrumented binary, and would typically be something like:
 //     GOPATH/pkg/fuzz/linux_amd64/619f7d77e9cd5d7433f8/fmt.FuzzFmt
-func Instrument(function Func, verbose bool) (Target, error) {
+func Instrument(function Func, engine Engine, verbose bool) (Target, error) {
 	report := func(err error) (Target, error) {
 		return Target{}, fmt.Errorf("instrument %s.%s error: %v", function.PkgName, function.FuncName, err)
 	}
 
-	// check if go-fuzz and go-fuzz-build seem to be in our path
-	err := checkGoFuzz()
+	// check if the engine's tools (e.g., go-fuzz and go-fuzz-build) seem to be in our path
+	eng, err := engine.impl()
+	if err != nil {
+		return report(err)
+	}
+	err = eng.check()
 	if err != nil {
 		return report(err)
 	}
@@ -29,30 +34,23 @@ func Instrument(function Func, verbose bool) (Target, error) {
 		return report(fmt.Errorf("unexpected fuzz function: %#v", function))
 	}
 
-	// check if we have a plain data []byte signature, vs. a rich signature
-	plain, err := IsPlainSig(function.TypesFunc)
+	// create our target, including a wrapper function if needed to handle a rich signature.
+	// When fuzzing, we do not want to print our arguments.
+	printArgs := false
+	target, err := newTarget(function, printArgs)
 	if err != nil {
 		return report(err)
 	}
-
-	var target Target
-	if plain {
-		// create our initial target struct using the actual func supplied by the user.
-		target = Target{UserFunc: function}
-	} else {
-		info("detected rich signature for %v.%v", function.PkgName, function.FuncName)
-		// create a wrapper function to handle the rich signature.
-		// When fuzzing, we do not want to print our arguments.
-		printArgs := false
-		target, err = CreateRichSigWrapper(function, printArgs)
-		if err != nil {
-			return report(err)
-		}
-		// CreateRichSigWrapper was successful, which means it populated the temp dir with the wrapper func.
-		// By the time we leave our current function, we are done with the temp dir
-		// that CreateRichSigWrapper created, so delete via a defer.
-		// (We can't delete it immediately because we haven't yet run go-fuzz-build on it).
-		defer os.RemoveAll(target.wrapperTempDir)
+	// By the time we leave our current function, we are done with any temp dir
+	// for a rich signature wrapper, so delete via a defer.
+	// (We can't delete it immediately because we haven't yet built it).
+	defer target.removeTemp()
+	target.engine = engine
+
+	// let the engine prepare the target, such as adding go-fuzz-dep to a temporary wrapper module.
+	// We do this prior to computing our hash so that the hash covers the final go.mod.
+	if err := eng.prepare(target); err != nil {
+		return report(err)
 	}
 
 	// Determine where our cacheDir is.
@@ -68,58 +66,64 @@ func Instrument(function Func, verbose bool) (Target, error) {
 		return report(fmt.Errorf("creating cache dir failed: %v", err))
 	}
 
-	// check if our instrumented zip already exists in our cache (in which case we trust it).
-	finalZipPath, err := target.zipPath(verbose)
+	// check if our instrumented artifact already exists in our cache (in which case we trust it).
+	finalPath, err := target.artifactPath(verbose)
 	if err != nil {
-		return report(fmt.Errorf("zip path failed: %v", err))
+		return report(fmt.Errorf("artifact path failed: %v", err))
 	}
-	if _, err = os.Stat(finalZipPath); os.IsNotExist(err) {
+	if _, err = os.Stat(finalPath); os.IsNotExist(err) {
 		info("building instrumented binary for %v.%v", function.PkgName, function.FuncName)
-		outFile := filepath.Join(cacheDir, "fuzz.zip.partial")
-
-		// to support experimentation, initial args for go-fuzz-build are
-		// populated by the optional FZGOFLAGSBUILD env var
-		// (or an empty slice if FZGOFLAGSBUILD is not set).
-		args := fzgoEnvFlags("FZGOFLAGSBUILD")
-		if !target.hasWrapper {
-			args = append(args,
-				"-func="+target.UserFunc.FuncName,
-				"-o="+outFile,
-				// "-race", // TODO: make a flag
-				buildTagsArg,
-				target.UserFunc.PkgPath,
-			)
-		} else {
-			args = append(args,
-				"-func="+target.wrapperFunc.FuncName,
-				"-o="+outFile,
-				// "-race", // TODO: make a flag
-				buildTagsArg,
-				target.wrapperFunc.PkgPath,
-			)
-		}
+		e := FuncEvent(ActionBuild, function)
+		e.Path = finalPath
+		EmitEvent(e)
+		outFile := finalPath + ".partial"
 
-		err = execCmd("go-fuzz-build", args, target.wrapperEnv, 0)
+		err = eng.build(target, outFile, verbose)
 		if err != nil {
-			return report(fmt.Errorf("go-fuzz-build failed with args %q: %v", args, err))
+			return report(err)
 		}
 
-		err = os.Rename(outFile, finalZipPath)
+		err = os.Rename(outFile, finalPath)
 		if err != nil {
 			return report(err)
 		}
 	} else {
 		info("using cached instrumented binary for %v.%v", function.PkgName, function.FuncName)
+		e := FuncEvent(ActionCached, function)
+		e.Path = finalPath
+		EmitEvent(e)
 	}
 	return target, nil
 }
 
-// Start begins fuzzing by invoking 'go-fuzz'.
+// StartOptions control how Start and Schedule fuzz.
+type StartOptions struct {
+	MaxDuration time.Duration // 0 means no max time is enforced
+	Parallel    int           // number of fuzzing processes
+	FuncTimeout time.Duration // time limit for a single execution of the fuzz function (minimum 1s)
+	Verbose     bool
+
+	// Dicts are user dictionaries in the libFuzzer and AFL format that are added to the dictionary
+	// fzgo derives from the package under test. The libfuzzer engine uses the dictionary as is,
+	// the native engine adds its tokens as seed inputs, and the go-fuzz engine does not use it.
+	Dicts []string
+
+	// OnStats, if non-nil, is called with the periodic status reported by the fuzzing engine.
+	// When used with Schedule, OnStats may be called concurrently for different targets.
+	OnStats func(Stats)
+
+	// Output, if non-nil, receives the fuzzing engine's output, excluding any status lines
+	// that were parsed into Stats. If nil, all output goes to stdout and stderr.
+	Output io.Writer
+}
+
+// Start begins fuzzing by invoking the fuzzing engine used to instrument the target,
+// such as 'go-fuzz'.
 // cacheDir contains the instrumented binary, and would typically be something like:
 //     GOPATH/pkg/fuzz/linux_amd64/619f7d77e9cd5d7433f8/fmt.FuzzFmt
 // workDir contains the corpus, and would typically be something like:
 //     GOPATH/src/github.com/user/proj/testdata/fuzz/fmt.FuzzFmt
-func Start(target Target, workDir string, maxDuration time.Duration, parallel int, funcTimeout time.Duration, v bool) error {
+func Start(target Target, workDir string, opts StartOptions) error {
 	report := func(err error) error {
 		return fmt.Errorf("start fuzzing %s error: %v", target.FuzzName(), err)
 	}
@@ -127,38 +131,36 @@ func Start(target Target, workDir string, maxDuration time.Duration, parallel in
 	info("starting fuzzing %s", target.FuzzName())
 	info("output in %s", workDir)
 
-	// check if go-fuzz and go-fuzz-build seem to be in our path
-	err := checkGoFuzz()
+	// check if the engine's tools seem to be in our path
+	eng, err := target.engine.impl()
+	if err != nil {
+		return report(err)
+	}
+	err = eng.check()
 	if err != nil {
 		return report(err)
 	}
 
-	// prepare our args
-	if funcTimeout < 1*time.Second {
+	if opts.FuncTimeout < 1*time.Second {
 		return fmt.Errorf("minimum allowed func timeout value is 1 second")
 	}
-	verboseLevel := 0
-	if v {
-		verboseLevel = 1
-	}
 
-	zipPath, err := target.zipPath(v)
+	artifactPath, err := target.artifactPath(opts.Verbose)
 	if err != nil {
-		return report(fmt.Errorf("zip path failed: %v", err))
-	}
-
-	// to support experimentation, initial args for go-fuzz are
-	// populated by the optional FZGOFLAGSFUZZ env var
-	// (or an empty slice if FZGOFLAGSFUZZ is not set).
-	runArgs := fzgoEnvFlags("FZGOFLAGSFUZZ")
-	runArgs = append(runArgs,
-		fmt.Sprintf("-bin=%s", zipPath),
-		fmt.Sprintf("-workdir=%s", workDir),
-		fmt.Sprintf("-procs=%d", parallel),
-		fmt.Sprintf("-timeout=%d", int(funcTimeout.Seconds())), // this is not total run time
-		fmt.Sprintf("-v=%d", verboseLevel),
-	)
-	err = execCmd("go-fuzz", runArgs, nil, maxDuration)
+		return report(fmt.Errorf("artifact path failed: %v", err))
+	}
+
+	warnUnusedDicts(target.engine, opts)
+	opts, checkCrashers := eventOptions(opts, []Target{target}, []string{workDir})
+	stdout, stderr, flush := opts.outputWriters(target.FuzzName())
+	started := time.Now()
+	EmitEvent(FuncEvent(ActionStart, target.UserFunc))
+	err = eng.run(target, artifactPath, workDir, runOptions{StartOptions: opts, stdout: stdout, stderr: stderr})
+	flush()
+	checkCrashers()
+	e := FuncEvent(ActionStop, target.UserFunc)
+	e.Elapsed = time.Since(started).Seconds()
+	EmitEvent(e)
 	if err != nil {
 		return report(err)
 	}
@@ -172,11 +174,49 @@ func Start(target Target, workDir string, maxDuration time.Duration, parallel in
 type Target struct {
 	UserFunc      Func   // the user's original function
 	savedCacheDir string // the cacheDir relies on a content hash, so remember the answer
+	engine        Engine // the fuzzing engine used to instrument and run this target
 
 	hasWrapper     bool
 	wrapperFunc    Func     // synthesized wrapper function, only used if user's func has rich signatures
-	wrapperEnv     []string // env with GOPATH set up to include the temporary
-	wrapperTempDir string
+	wrapperEnv     []string // env with GOPATH set up to include any temporary gopath (nil in module mode)
+	wrapperTempDir string   // the wrapper's package directory (also the root of the temporary module in module mode)
+}
+
+// newTarget creates a Target for a user's function, including creating a wrapper function
+// in a temp dir if the function has a rich signature. The caller should call removeTemp
+// when done with the target.
+func newTarget(function Func, printArgs bool) (Target, error) {
+	// check if we have a plain data []byte signature, vs. a rich signature
+	plain, err := IsPlainSig(function.TypesFunc)
+	if err != nil {
+		return Target{}, err
+	}
+
+	// if the function is in a _test.go file, we need our non-test copies of the
+	// test files in place for as long as we are building against the function.
+	if function.InTestFile {
+		function.variant, err = materializeTestVariant(function)
+		if err != nil {
+			return Target{}, err
+		}
+	}
+
+	var target Target
+	if plain {
+		// create our initial target struct using the actual func supplied by the user.
+		target = Target{UserFunc: function, wrapperEnv: function.buildEnv()}
+	} else {
+		info("detected rich signature for %v.%v", function.PkgName, function.FuncName)
+		// create a wrapper function to handle the rich signature.
+		target, err = CreateRichSigWrapper(function, printArgs)
+		if err != nil {
+			if function.variant != nil {
+				function.variant.remove()
+			}
+			return Target{}, err
+		}
+	}
+	return target, nil
 }
 
 // FuzzName returns the '<pkg>.<OrigFuzzFunc>' string.
@@ -187,26 +227,60 @@ func (t *Target) FuzzName() string {
 	return t.UserFunc.FuzzName()
 }
 
-func (t *Target) zipPath(verbose bool) (string, error) {
+// dir returns the directory to use when invoking the go tool (or tools like go-fuzz-build
+// that invoke the go tool) for this target. In module mode, this places us
+// within the temporary wrapper module if we have a wrapper, or otherwise within the user's module.
+func (t *Target) dir() string {
+	if t.hasWrapper {
+		return t.wrapperTempDir
+	}
+	return t.UserFunc.buildDir()
+}
+
+// removeTemp removes any temporary directory created for a rich signature wrapper,
+// as well as any copy of the source tree with non-test copies of _test.go files.
+func (t *Target) removeTemp() {
+	if t.hasWrapper {
+		os.RemoveAll(t.wrapperTempDir)
+	}
+	if t.UserFunc.variant != nil {
+		t.UserFunc.variant.remove()
+	}
+}
+
+// artifactPath returns the location of the instrumented artifact in our cache,
+// such as the fuzz.zip for go-fuzz.
+func (t *Target) artifactPath(verbose bool) (string, error) {
+	eng, err := t.engine.impl()
+	if err != nil {
+		return "", err
+	}
 	cacheDir, err := t.cacheDir(verbose)
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(cacheDir, "fuzz.zip"), nil
+	return filepath.Join(cacheDir, eng.artifact()), nil
 }
 
 func (t *Target) cacheDir(verbose bool) (string, error) {
 	if t.savedCacheDir == "" {
-		// generate a hash covering the package, its dependencies, and some items like go-fuzz-build binary and go version
+		// generate a hash covering the package, its dependencies, and some items like the fuzzing engine's binaries and go version
 		// TODO: pass verbose flag around?
 		var err error
 		var h string
+		// any copy of the source tree for a test variant is also in a temp dir.
+		var trimVariant []string
+		if t.UserFunc.variant != nil {
+			trimVariant = []string{t.UserFunc.variant.tempDir}
+		}
 		if !t.hasWrapper {
 			// use everything directly from the original user function
-			h, err = Hash(t.UserFunc.PkgPath, t.UserFunc.FuncName, t.UserFunc.PkgDir, nil, verbose)
+			trim := append([]string{t.UserFunc.PkgDir}, trimVariant...)
+			h, err = Hash(t.UserFunc.buildPkgPath(), t.UserFunc.FuncName, trim, t.dir(), t.wrapperEnv, t.engine, verbose)
 		} else {
 			// we have a wrapper function, so target that for our hash.
-			h, err = Hash(t.wrapperFunc.PkgPath, t.wrapperFunc.FuncName, t.wrapperFunc.PkgDir, t.wrapperEnv, verbose)
+			trim := append([]string{t.wrapperFunc.PkgDir}, trimVariant...)
+			h, err = Hash(t.wrapperFunc.PkgPath, t.wrapperFunc.FuncName, trim, t.dir(), t.wrapperEnv, t.engine, verbose)
 		}
 		if err != nil {
 			return "", err
@@ -231,17 +305,28 @@ func ExecGo(args []string, env []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to find \"go\" command in path. error: %v", err)
 	}
-	return execCmd("go", args, env, 0)
+	return execCmd("go", args, env, "", 0)
 }
 
 // A maxDuration of 0 means no max time is enforced.
-func execCmd(name string, args []string, env []string, maxDuration time.Duration) error {
+// An empty dir means the command runs in the current directory.
+// If Events are enabled, the command's output is emitted as output Events.
+func execCmd(name string, args []string, env []string, dir string, maxDuration time.Duration) error {
+	if EventsEnabled() {
+		return execCmdOutput(name, args, env, dir, maxDuration, eventWriter{}, eventWriter{})
+	}
+	return execCmdOutput(name, args, env, dir, maxDuration, os.Stdout, os.Stderr)
+}
+
+// execCmdOutput is like execCmd, but with the command's stdout and stderr going to the supplied writers.
+func execCmdOutput(name string, args []string, env []string, dir string, maxDuration time.Duration, stdout, stderr io.Writer) error {
 	report := func(err error) error { return fmt.Errorf("exec %v error: %v", name, err) }
 
 	cmd := exec.Command(name, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 	cmd.Stdin = os.Stdin
+	cmd.Dir = dir
 	if len(env) > 0 {
 		cmd.Env = env
 	}
@@ -300,5 +385,10 @@ func info(s string, args ...interface{}) {
 	//    All test output and summary lines are printed to the go command's standard output,
 	//    even if the test printed them to its own standard error.
 	//    (The go command's standard error is reserved for printing errors building the tests.)
-	fmt.Println("fzgo:", fmt.Sprintf(s, args...))
+	msg := fmt.Sprintf("fzgo: %s\n", fmt.Sprintf(s, args...))
+	if EventsEnabled() {
+		EmitEvent(Event{Action: ActionOutput, Output: msg})
+		return
+	}
+	fmt.Print(msg)
 }
@@ -0,0 +1,197 @@
+package fuzz
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build/constraint"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/imports"
+)
+
+// ExportCrasher returns the source of a standalone _test.go file for the fuzz function's package
+// with a unit test that calls the fuzz function with a crasher's input, which can serve as a regression test.
+// For a rich signature, the arguments are decoded from the crasher by executing the rich signature
+// wrapper with printArgs set, and appear in the test as Go literals rather than raw bytes.
+// The test is in the external test package so that it only relies on the exported API of the package,
+// unless the fuzz function itself is in the external test package.
+func ExportCrasher(function Func, crasher Crasher, funcTimeout time.Duration) ([]byte, error) {
+	report := func(err error) ([]byte, error) {
+		return nil, fmt.Errorf("export crasher %s for %s: %v", crasher.Hash, function.FuzzName(), err)
+	}
+	if funcTimeout < 1*time.Second {
+		return nil, fmt.Errorf("minimum allowed func timeout value is 1 second")
+	}
+
+	data, err := ioutil.ReadFile(crasher.Path)
+	if err != nil {
+		return report(err)
+	}
+	d, err := newArgDecoder(function, funcTimeout)
+	if err != nil {
+		return report(err)
+	}
+	defer d.close()
+	args, output, err := d.decode(data)
+	if err != nil {
+		return report(err)
+	}
+	summary := crasher.Summary
+	if s := crashSignature(output); s != "" {
+		summary = s
+	}
+
+	src, err := exportSource(function, crasher, summary, args)
+	if err != nil {
+		return report(err)
+	}
+	return src, nil
+}
+
+// ExportFilename returns the default filename in the fuzz function's package directory
+// for the regression test exported for a crasher.
+func ExportFilename(function Func, crasher Crasher) string {
+	name := fmt.Sprintf("%s_crasher_%s_test.go", strings.ToLower(function.FuncName), strings.ToLower(identSuffix(crasher.Hash)))
+	return filepath.Join(function.PkgDir, name)
+}
+
+// exportSource returns the formatted source for an exported regression test.
+func exportSource(function Func, crasher Crasher, summary string, args []Arg) ([]byte, error) {
+	f := function.TypesFunc
+	pkgName := f.Pkg().Name()
+	testPkgName := pkgName
+	if !function.XTest {
+		testPkgName = pkgName + "_test"
+	}
+
+	var b bytes.Buffer
+	// fuzz functions are often only built with a tag such as 'gofuzz', in which case
+	// our test needs the same build constraint in order to call the fuzz function.
+	if expr := buildConstraint(function); expr != "" {
+		fmt.Fprintf(&b, "//go:build %s\n\n", expr)
+	}
+	fmt.Fprintf(&b, "package %s\n\n", testPkgName)
+	fmt.Fprintf(&b, "import (\n\t\"testing\"\n\n\t%q\n)\n\n", function.PkgPath)
+
+	testName := "Test" + f.Name() + "Crasher" + identSuffix(crasher.Hash)
+	fmt.Fprintf(&b, "// %s reproduces crasher %s found by fuzzing %s.\n", testName, crasher.Hash, f.Name())
+	if summary != "" {
+		fmt.Fprintf(&b, "// The crasher failed with '%s'.\n", summary)
+	}
+	fmt.Fprintf(&b, "// It was generated by 'fzgo crashers export'.\n")
+	tName := "t"
+	var names []string
+	for _, arg := range args {
+		if arg.Name == tName {
+			// we do not otherwise use our *testing.T, so avoid a conflict with an argument.
+			tName = "_"
+		}
+	}
+	fmt.Fprintf(&b, "func %s(%s *testing.T) {\n", testName, tName)
+	for _, arg := range args {
+		fmt.Fprintf(&b, "\t%s\n", arg.Decl())
+		names = append(names, arg.Name)
+	}
+	fmt.Fprintf(&b, "\n\t%s.%s(%s)\n}\n", pkgName, f.Name(), strings.Join(names, ", "))
+
+	// our literals and types are qualified by package name, including for an external
+	// test package, so remove the qualifier for any identifiers in our own package.
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", b.Bytes(), parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing generated test: %v\n%s", err, b.String())
+	}
+	astutil.Apply(file, nil, func(c *astutil.Cursor) bool {
+		if sel, ok := c.Node().(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Name == testPkgName {
+				c.Replace(sel.Sel)
+			}
+		}
+		return true
+	})
+	var out bytes.Buffer
+	if err := format.Node(&out, fset, file); err != nil {
+		return nil, err
+	}
+
+	// add imports for any other packages used by the literals, and remove unused imports.
+	// the filename lets imports resolve packages relative to the user's package.
+	src, err := imports.Process(filepath.Join(function.PkgDir, "fzgo_export_test.go"), out.Bytes(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed adjusting imports: %v", err)
+	}
+	return src, nil
+}
+
+// buildConstraint returns the build constraint expression of the file that declares the fuzz function,
+// or an empty string if there is no constraint or the file is not found.
+func buildConstraint(function Func) string {
+	files, _ := filepath.Glob(filepath.Join(function.PkgDir, "*.go"))
+	fset := token.NewFileSet()
+	for _, filename := range files {
+		if strings.HasPrefix(filepath.Base(filename), testVariantPrefix) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+		if err != nil || file.Name.Name != function.TypesFunc.Pkg().Name() {
+			continue
+		}
+		found := false
+		for _, decl := range file.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv == nil && fd.Name.Name == function.FuncName {
+				found = true
+			}
+		}
+		if !found {
+			continue
+		}
+		var expr constraint.Expr
+		for _, group := range file.Comments {
+			if group.Pos() > file.Package {
+				break
+			}
+			for _, c := range group.List {
+				if !constraint.IsGoBuild(c.Text) && !constraint.IsPlusBuild(c.Text) {
+					continue
+				}
+				x, err := constraint.Parse(c.Text)
+				if err != nil {
+					continue
+				}
+				if expr == nil {
+					expr = x
+				} else {
+					expr = &constraint.AndExpr{X: expr, Y: x}
+				}
+			}
+		}
+		if expr == nil {
+			return ""
+		}
+		return expr.String()
+	}
+	return ""
+}
+
+// identSuffix returns up to the first 10 letters and digits of a crasher hash, for use in an identifier.
+func identSuffix(hash string) string {
+	var s []rune
+	for _, r := range hash {
+		if len(s) == 10 {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			s = append(s, r)
+		}
+	}
+	return string(s)
+}
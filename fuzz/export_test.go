@@ -0,0 +1,56 @@
+package fuzz
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExportSource(t *testing.T) {
+	functions, err := FindFunc("github.com/thepudds/fzgo/examples/richsignatures", "FuzzWithBasicTypes", nil, false)
+	if err != nil {
+		t.Fatalf("FindFunc() error = %v", err)
+	}
+	output := `=== RUN   TestInput
+                          re:  "a(b"
+                       input:  []byte("\x00x")
+                       posix:  true
+--- FAIL: TestInput (0.00s)
+`
+	args := []Arg{
+		{Name: "re", Type: "string"},
+		{Name: "input", Type: "[]byte"},
+		{Name: "posix", Type: "bool"},
+	}
+	if err := parseArgLiterals(output, args); err != nil {
+		t.Fatalf("parseArgLiterals() error = %v", err)
+	}
+	crasher := Crasher{Hash: "0123456789abcdef"}
+	got, err := exportSource(functions[0], crasher, "panic: boom", args)
+	if err != nil {
+		t.Fatalf("exportSource() error = %v", err)
+	}
+
+	want := `package pkgname_test
+
+import (
+	"testing"
+
+	pkgname "github.com/thepudds/fzgo/examples/richsignatures"
+)
+
+// TestFuzzWithBasicTypesCrasher0123456789 reproduces crasher 0123456789abcdef found by fuzzing FuzzWithBasicTypes.
+// The crasher failed with 'panic: boom'.
+// It was generated by 'fzgo crashers export'.
+func TestFuzzWithBasicTypesCrasher0123456789(t *testing.T) {
+	re := "a(b"
+	input := []byte("\x00x")
+	posix := true
+
+	pkgname.FuzzWithBasicTypes(re, input, posix)
+}
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("exportSource() mismatch (-want +got):\n%s", diff)
+	}
+}
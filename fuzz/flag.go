@@ -55,7 +55,6 @@ var IncompatibleTestFlags = []string{
 	"cpuprofile",           // -cpuprofile cpu.out  (Write a CPU profile to the specified file before exiting.)
 	"exec",                 // -exec xprog  (Run the test binary using xprog. The behavior is the same as...)
 	"failfast",             // -failfast  (Do not start new tests after the first test failure.)
-	"json",                 // -json  (Convert test output to JSON suitable for automated processing.)
 	"list",                 // -list regexp  (List tests, benchmarks, or examples matching the regular expression.)
 	"memprofile",           // -memprofile mem.out  (Write an allocation profile to the file after all tests have passed.)
 	"memprofilerate",       // -memprofilerate n  (Enable more precise (and expensive) memory allocation profiles by...)
@@ -73,12 +72,12 @@ var IncompatibleTestFlags = []string{
 // but which are not currently implemented in this simple 'fzgo' prototype. These will
 // currently cause an error if used with 'fzgo test -fuzz'.
 var UnimplementedTestFlags = []string{
-	"coverprofile", // -coverprofile cover.out  (Write a coverage profile to the file after all tests have passed.)
-	"i",            // -i  (Install packages that are dependencies of the test.)
+	"i", // -i  (Install packages that are dependencies of the test.)
 }
 
-// supportedBools is a list of allowed boolean flags for 'fzgo test -fuzz'.
-var supportedBools = []string{"c", "i", "v"}
+// supportedBools is a list of allowed boolean flags for 'fzgo test -fuzz'
+// and the other fzgo commands such as 'fzgo corpus distill'.
+var supportedBools = []string{"c", "i", "inplace", "json", "v"}
 
 // FlagDef holds the definition of an arg we will interpret.
 type FlagDef struct {
@@ -26,7 +26,7 @@ func TestParseArgs(t *testing.T) {
 
 		{"incompatible test flag", "-fuzz=fuzzfunc -benchtime=10s", "", "", true},
 		{"incompatible build flag", "-fuzz=fuzzfunc -gccgoflags=foo", "", "", true},
-		{"not yet implemented fuzzing arg", "-fuzz=fuzzfunc -coverprofile=foo", "", "", true},
+		{"not yet implemented fuzzing arg", "-fuzz=fuzzfunc -i", "", "", true},
 
 		{"no -fuzz", "-fuzznot=fuzzfunc", "", "", false},
 		{"empty args", "", "", "", false},
@@ -0,0 +1,142 @@
+package fuzz
+
+import (
+	"fmt"
+	"go/types"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// fillFunc emits the code to set the func variable name to a synthesized func value,
+// such as for a 'less func(i, j int) bool' parameter to a sort.Slice-style API.
+// The results for the calls are drawn from the input before the fuzz function is called,
+// as a slice of structs with a field for each result. Each call returns the next results,
+// cycling back to the start, or zero values if no results were drawn.
+// Results that the fuzzer cannot fill, such as an error or other interface, are always zero values.
+// n is a unique suffix for temporary variables.
+// If printArgs is set, each call is recorded by printing its arguments and results,
+// and the returned expression is Go source for a func literal that replays the same results.
+func fillFunc(w io.Writer, name string, n int, sig *types.Signature, printArgs bool) string {
+	// example:
+	//   var __fzgoResults1 []struct{ R1 bool }
+	//   fuzzer.Fuzz(&__fzgoResults1)
+	//   var __fzgoCalls1 int
+	//   less = func(__fzgoP1 int, __fzgoP2 int) (__fzgoR1 bool) {
+	//   	if len(__fzgoResults1) > 0 {
+	//   		__fzgoR := __fzgoResults1[__fzgoCalls1%len(__fzgoResults1)]
+	//   		__fzgoR1 = __fzgoR.R1
+	//   	}
+	//   	__fzgoCalls1++
+	//   	return
+	//   }
+	resultsType, hasResults := funcResultsType(sig)
+	results := fmt.Sprintf("__fzgoResults%d", n)
+	calls := fmt.Sprintf("__fzgoCalls%d", n)
+	if hasResults {
+		fmt.Fprintf(w, "\tvar %s %s\n", results, resultsType)
+		fmt.Fprintf(w, "\tfuzzer.Fuzz(&%s)\n", results)
+	}
+	fmt.Fprintf(w, "\tvar %s int\n", calls)
+
+	var params, paramTypes, paramLits []string
+	for i := 0; i < sig.Params().Len(); i++ {
+		typ := funcParamType(sig, i)
+		params = append(params, fmt.Sprintf("__fzgoP%d %s", i+1, typ))
+		paramTypes = append(paramTypes, typ)
+		paramLits = append(paramLits, fmt.Sprintf("randparam.Literal(__fzgoP%d)", i+1))
+	}
+	var resultDecls, replayDecls, resultLits, assigns, replayAssigns []string
+	for i := 0; i < sig.Results().Len(); i++ {
+		typ := types.TypeString(sig.Results().At(i).Type(), externalQualifier)
+		resultDecls = append(resultDecls, fmt.Sprintf("__fzgoR%d %s", i+1, typ))
+		replayDecls = append(replayDecls, fmt.Sprintf("r%d %s", i+1, typ))
+		resultLits = append(resultLits, fmt.Sprintf("randparam.Literal(__fzgoR%d)", i+1))
+		if fillableResult(sig.Results().At(i).Type()) {
+			assigns = append(assigns, fmt.Sprintf("__fzgoR%d = __fzgoR.R%d", i+1, i+1))
+			replayAssigns = append(replayAssigns, fmt.Sprintf("r%d = r.R%d", i+1, i+1))
+		}
+	}
+
+	fmt.Fprintf(w, "\t%s = func(%s)", name, strings.Join(params, ", "))
+	if len(resultDecls) > 0 {
+		fmt.Fprintf(w, " (%s)", strings.Join(resultDecls, ", "))
+	}
+	fmt.Fprintf(w, " {\n")
+	if hasResults {
+		fmt.Fprintf(w, "\t\tif len(%s) > 0 {\n", results)
+		fmt.Fprintf(w, "\t\t\t__fzgoR := %s[%s%%len(%s)]\n", results, calls, results)
+		for _, assign := range assigns {
+			fmt.Fprintf(w, "\t\t\t%s\n", assign)
+		}
+		fmt.Fprintf(w, "\t\t}\n")
+	}
+	fmt.Fprintf(w, "\t\t%s++\n", calls)
+	if printArgs {
+		// example:
+		//   fmt.Printf("                        less   call %d: (%s) -> (%s)\n", __fzgoCalls1,
+		//   	randparam.Literal(__fzgoP1)+", "+randparam.Literal(__fzgoP2), randparam.Literal(__fzgoR1))
+		fmt.Fprintf(w, "\t\tfmt.Printf(\"        %20s   call %%d: (%%s) -> (%%s)\\n\", %s,\n\t\t\t%s, %s)\n",
+			name, calls, joinLiterals(paramLits), joinLiterals(resultLits))
+	}
+	fmt.Fprintf(w, "\t\treturn\n\t}\n")
+
+	// the Go source for a func literal that replays the same results, such as:
+	//   func() func(int, int) bool { var results []struct{ R1 bool } = <literal>; calls := 0;
+	//   	return func(int, int) (r1 bool) { if len(results) > 0 { r := results[calls%len(results)]; r1 = r.R1 }; calls++; return } }()
+	funcType := fmt.Sprintf("func(%s)", strings.Join(paramTypes, ", "))
+	if sig.Results().Len() > 0 {
+		funcType += fmt.Sprintf(" (%s)", strings.Join(replayDecls, ", "))
+	}
+	if !hasResults {
+		return strconv.Quote(funcType + " { return }")
+	}
+	prefix := fmt.Sprintf("func() %s { var results %s = ", funcType, resultsType)
+	suffix := fmt.Sprintf("; calls := 0; return func(%s) (%s) { if len(results) > 0 { r := results[calls%%len(results)]; %s }; calls++; return } }()",
+		strings.Join(paramTypes, ", "), strings.Join(replayDecls, ", "), strings.Join(replayAssigns, "; "))
+	return fmt.Sprintf("%s + randparam.Literal(%s) + %s", strconv.Quote(prefix), results, strconv.Quote(suffix))
+}
+
+// funcResultsType returns the type of the slice of results drawn from the input for
+// a func value with signature sig, such as '[]struct{ R1 bool }',
+// or false if sig does not have any results that the fuzzer can fill.
+func funcResultsType(sig *types.Signature) (string, bool) {
+	var fields []string
+	for i := 0; i < sig.Results().Len(); i++ {
+		t := sig.Results().At(i).Type()
+		if fillableResult(t) {
+			fields = append(fields, fmt.Sprintf("R%d %s", i+1, types.TypeString(t, externalQualifier)))
+		}
+	}
+	if len(fields) == 0 {
+		return "", false
+	}
+	return fmt.Sprintf("[]struct{ %s }", strings.Join(fields, "; ")), true
+}
+
+// fillableResult reports whether the result of a synthesized func value is drawn from the input.
+func fillableResult(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Interface, *types.Signature, *types.Chan:
+		return false
+	}
+	return true
+}
+
+// funcParamType returns the type of the ith parameter of sig for use in a func literal,
+// such as '...int' for a final variadic parameter.
+func funcParamType(sig *types.Signature, i int) string {
+	t := sig.Params().At(i).Type()
+	if sig.Variadic() && i == sig.Params().Len()-1 {
+		return "..." + types.TypeString(t.(*types.Slice).Elem(), externalQualifier)
+	}
+	return types.TypeString(t, externalQualifier)
+}
+
+// joinLiterals returns an expression in the wrapper that joins the Go source in literals with commas.
+func joinLiterals(literals []string) string {
+	if len(literals) == 0 {
+		return `""`
+	}
+	return strings.Join(literals, ` + ", " + `)
+}
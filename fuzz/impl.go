@@ -0,0 +1,230 @@
+package fuzz
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"io"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// implDirective registers an implementation to use when fuzzing interface parameters
+// in rich signatures. It can be placed in the doc comment of an exported type, such as:
+//
+//   //fzgo:impl
+//   type MemStore struct { ... }
+//
+// in which case the type (or a pointer to the type) is filled by the fuzzer and used for any
+// interface parameter that it implements, or in the doc comment of an exported factory function, such as:
+//
+//   //fzgo:impl
+//   func NewFileStore(name string, limit int) (Store, error) { ... }
+//
+// in which case the factory's parameters are filled by the fuzzer and its result is used for any
+// interface parameter the result can be assigned to. A factory may also return an error,
+// in which case the input is skipped if the error is non-nil.
+// If there are multiple implementations for a parameter, the fuzzer chooses among them using the input.
+// Registered implementations are found in the package containing the fuzz function (including any
+// _test.go files if the fuzz function resides in one), and for a fuzz function in an external test
+// package, in the package under test as well.
+const implDirective = "//fzgo:impl"
+
+// Impl is an implementation of interfaces registered with a '//fzgo:impl' directive.
+type Impl struct {
+	Type    types.Type  // the annotated type, or the first result type of the factory
+	Factory *types.Func // the annotated factory function, or nil if the type is annotated
+
+	factoryErr bool // the factory's second result is an error
+}
+
+// FindImpls returns the implementations registered with '//fzgo:impl' directives in pkgs.
+func FindImpls(pkgs []*packages.Package) ([]Impl, error) {
+	var result []Impl
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				switch decl := decl.(type) {
+				case *ast.FuncDecl:
+					if !hasImplDirective(decl.Doc) {
+						continue
+					}
+					impl, err := factoryImpl(pkg, decl)
+					if err != nil {
+						return nil, fmt.Errorf("%s: %v", pkg.Fset.Position(decl.Pos()), err)
+					}
+					result = append(result, impl)
+				case *ast.GenDecl:
+					for _, spec := range decl.Specs {
+						ts, ok := spec.(*ast.TypeSpec)
+						if !ok || !(hasImplDirective(ts.Doc) || len(decl.Specs) == 1 && hasImplDirective(decl.Doc)) {
+							continue
+						}
+						if !ts.Name.IsExported() {
+							return nil, fmt.Errorf("%s: %s type %s is not exported", pkg.Fset.Position(ts.Pos()), implDirective, ts.Name.Name)
+						}
+						obj := pkg.TypesInfo.Defs[ts.Name]
+						if obj == nil {
+							continue
+						}
+						if types.IsInterface(obj.Type()) {
+							return nil, fmt.Errorf("%s: %s type %s is an interface", pkg.Fset.Position(ts.Pos()), implDirective, ts.Name.Name)
+						}
+						result = append(result, Impl{Type: obj.Type()})
+					}
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// factoryImpl returns the Impl for a factory function annotated with a '//fzgo:impl' directive.
+func factoryImpl(pkg *packages.Package, decl *ast.FuncDecl) (Impl, error) {
+	if decl.Recv != nil {
+		return Impl{}, fmt.Errorf("%s method %s is not supported, only functions", implDirective, decl.Name.Name)
+	}
+	if !decl.Name.IsExported() {
+		return Impl{}, fmt.Errorf("%s function %s is not exported", implDirective, decl.Name.Name)
+	}
+	f, ok := pkg.TypesInfo.Defs[decl.Name].(*types.Func)
+	if !ok {
+		return Impl{}, fmt.Errorf("%s function %s not found", implDirective, decl.Name.Name)
+	}
+	results := f.Type().(*types.Signature).Results()
+	switch {
+	case results.Len() == 1:
+		return Impl{Type: results.At(0).Type(), Factory: f}, nil
+	case results.Len() == 2 && types.TypeString(results.At(1).Type(), nil) == "error":
+		return Impl{Type: results.At(0).Type(), Factory: f, factoryErr: true}, nil
+	}
+	return Impl{}, fmt.Errorf("%s function %s must return a single value, optionally followed by an error", implDirective, decl.Name.Name)
+}
+
+// hasImplDirective reports whether a doc comment contains a '//fzgo:impl' directive.
+func hasImplDirective(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == implDirective {
+			return true
+		}
+	}
+	return false
+}
+
+// implChoice is an Impl that can be used for a particular interface.
+type implChoice struct {
+	Impl
+	ptr bool // use a pointer to the annotated type
+}
+
+// ImplsFor returns the implementations in impls that can be used for a parameter of type t,
+// which must be a non-empty interface.
+func ImplsFor(t types.Type, impls []Impl) []Impl {
+	var result []Impl
+	for _, c := range implsFor(t, impls) {
+		result = append(result, c.Impl)
+	}
+	return result
+}
+
+func implsFor(t types.Type, impls []Impl) []implChoice {
+	iface, ok := t.Underlying().(*types.Interface)
+	if !ok || iface.Empty() {
+		return nil
+	}
+	var result []implChoice
+	for _, impl := range impls {
+		switch {
+		case impl.Factory != nil:
+			if types.AssignableTo(impl.Type, t) {
+				result = append(result, implChoice{Impl: impl})
+			}
+		case types.Implements(impl.Type, iface):
+			result = append(result, implChoice{Impl: impl})
+		case types.Implements(types.NewPointer(impl.Type), iface):
+			result = append(result, implChoice{Impl: impl, ptr: true})
+		}
+	}
+	return result
+}
+
+// fillImpl emits the code to set the interface variable name to one of choices,
+// where the choice is made by the fuzzer using the input.
+// n is a unique suffix for temporary variables.
+// If printArgs is set, it returns the name of a variable holding the Go source for the value.
+func fillImpl(w io.Writer, name string, n int, choices []implChoice, printArgs bool) string {
+	// example:
+	//   var __fzgoLit1 string
+	//   switch fuzzer.Choose(2) {
+	//   case 0:
+	//   	var __fzgoImpl1 pkgname.MemStore
+	//   	fuzzer.Fuzz(&__fzgoImpl1)
+	//   	s = &__fzgoImpl1
+	//   	__fzgoLit1 = randparam.Literal(&__fzgoImpl1)
+	//   case 1:
+	//   	var __fzgoArg1_1 string
+	//   	fuzzer.Fuzz(&__fzgoArg1_1)
+	//   	__fzgoImpl1, __fzgoErr1 := pkgname.NewFileStore(__fzgoArg1_1)
+	//   	if __fzgoErr1 != nil {
+	//   		return
+	//   	}
+	//   	s = __fzgoImpl1
+	//   	__fzgoLit1 = "pkgname.NewFileStore(" + randparam.Literal(__fzgoArg1_1) + ")"
+	//   }
+	lit := fmt.Sprintf("__fzgoLit%d", n)
+	if printArgs {
+		fmt.Fprintf(w, "\tvar %s string\n", lit)
+	}
+	fmt.Fprintf(w, "\tswitch fuzzer.Choose(%d) {\n", len(choices))
+	for j, c := range choices {
+		fmt.Fprintf(w, "\tcase %d:\n", j)
+		impl := fmt.Sprintf("__fzgoImpl%d", n)
+		var literal string
+		if c.Factory == nil {
+			fmt.Fprintf(w, "\t\tvar %s %s\n", impl, types.TypeString(c.Type, externalQualifier))
+			fmt.Fprintf(w, "\t\tfuzzer.Fuzz(&%s)\n", impl)
+			if c.ptr {
+				impl = "&" + impl
+			}
+			fmt.Fprintf(w, "\t\t%s = %s\n", name, impl)
+			literal = fmt.Sprintf("randparam.Literal(%s)", impl)
+		} else {
+			sig := c.Factory.Type().(*types.Signature)
+			var args, literals []string
+			for k := 0; k < sig.Params().Len(); k++ {
+				tmp := fmt.Sprintf("%d_%d", n, k+1)
+				arg := "__fzgoArg" + tmp
+				typ := types.TypeString(sig.Params().At(k).Type(), externalQualifier)
+				fmt.Fprintf(w, "\t\tvar %s %s\n", arg, typ)
+				argLiteral := fillVar(w, "\t\t", arg, typ, tmp, paramShape(sig.Params().At(k).Name()))
+				if sig.Variadic() && k == sig.Params().Len()-1 {
+					arg += "..."
+					argLiteral += ` + "..."`
+				}
+				literals = append(literals, argLiteral)
+				args = append(args, arg)
+			}
+			call := fmt.Sprintf("%s.%s(%s)", c.Factory.Pkg().Name(), c.Factory.Name(), strings.Join(args, ", "))
+			if c.factoryErr {
+				fmt.Fprintf(w, "\t\t%s, __fzgoErr%d := %s\n", impl, n, call)
+				fmt.Fprintf(w, "\t\tif __fzgoErr%d != nil {\n\t\t\treturn\n\t\t}\n", n)
+				fmt.Fprintf(w, "\t\t%s = %s\n", name, impl)
+			} else {
+				fmt.Fprintf(w, "\t\t%s = %s\n", name, call)
+			}
+			literal = fmt.Sprintf(`"%s.%s(" + %s + ")"`, c.Factory.Pkg().Name(), c.Factory.Name(), strings.Join(literals, ` + ", " + `))
+			if len(literals) == 0 {
+				literal = fmt.Sprintf(`"%s.%s()"`, c.Factory.Pkg().Name(), c.Factory.Name())
+			}
+		}
+		if printArgs {
+			fmt.Fprintf(w, "\t\t%s = %s\n", lit, literal)
+		}
+	}
+	fmt.Fprintf(w, "\t}\n")
+	return lit
+}
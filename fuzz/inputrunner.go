@@ -0,0 +1,120 @@
+package fuzz
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+)
+
+// inputRunner executes a fuzz function with one input at a time, via a test binary
+// built from inputTestSrc. It is used when minimizing crashers and distilling a corpus.
+type inputRunner struct {
+	target  Target
+	dir     string // our harness dir, which also holds our test binary and current input
+	bin     string
+	input   string // the file used to pass the current input to our test binary
+	env     []string
+	timeout time.Duration
+	execs   int
+}
+
+// newInputRunner creates a harness for function in a temp dir named after name, and
+// builds it with 'go test -c', passing along any buildArgs (such as '-cover').
+// If printArgs is set, a rich signature wrapper prints each argument as Go source.
+// The caller should call close when done.
+func newInputRunner(function Func, printArgs bool, name string, buildArgs []string, timeout time.Duration) (*inputRunner, error) {
+	target, err := newTarget(function, printArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgPath, funcName string
+	if target.hasWrapper {
+		pkgPath = target.wrapperFunc.PkgPath
+		funcName = target.wrapperFunc.FuncName
+	} else {
+		pkgPath = target.UserFunc.buildPkgPath()
+		funcName = target.UserFunc.FuncName
+	}
+	buf := new(bytes.Buffer)
+	vals := map[string]string{"pkgName": name, "pkgPath": pkgPath, "funcName": funcName}
+	if err := inputTestSrc.Execute(buf, vals); err != nil {
+		target.removeTemp()
+		return nil, fmt.Errorf("could not execute template: %v", err)
+	}
+	dir, err := createHarness(target, name, map[string][]byte{"input_test.go": buf.Bytes()})
+	if err != nil {
+		target.removeTemp()
+		return nil, err
+	}
+
+	r := &inputRunner{
+		target:  target,
+		dir:     dir,
+		bin:     filepath.Join(dir, name+".test"+exeSuffix()),
+		input:   filepath.Join(dir, "input"),
+		env:     target.wrapperEnv,
+		timeout: timeout,
+	}
+	if len(r.env) == 0 {
+		r.env = os.Environ()
+	}
+	r.env = append(r.env, "FZGO_INPUT="+r.input)
+
+	// build our test binary once, and then execute it for each input.
+	args := []string{"test", "-c", "-o", r.bin, buildTagsArg}
+	args = append(args, buildArgs...)
+	args = append(args, ".")
+	if err := execCmd("go", args, target.wrapperEnv, dir, 0); err != nil {
+		r.close()
+		return nil, fmt.Errorf("building %s harness failed with args %q: %v", name, args, err)
+	}
+	return r, nil
+}
+
+func (r *inputRunner) close() {
+	os.RemoveAll(r.dir)
+	r.target.removeTemp()
+}
+
+// run executes the fuzz function with data, passing any args to our test binary,
+// and returns the combined output. A non-zero exit is expected if data causes a crash,
+// so the caller should rely on the output rather than an exit status. If the fuzz function
+// hangs, it is stopped after our timeout.
+func (r *inputRunner) run(data []byte, args ...string) (string, error) {
+	if err := ioutil.WriteFile(r.input, data, 0644); err != nil {
+		return "", err
+	}
+	r.execs++
+	var output bytes.Buffer
+	args = append([]string{"-test.run=^TestInput$"}, args...)
+	_ = execCmdOutput(r.bin, args, r.env, r.dir, r.timeout, &output, &output)
+	return output.String(), nil
+}
+
+// inputTestSrc provides a test function that executes a fuzzing function
+// with the input in the file named by the FZGO_INPUT environment variable.
+var inputTestSrc = template.Must(template.New("InputTest").Parse(`
+package {{.pkgName}}
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	fuzzer "{{.pkgPath}}"
+)
+
+// TestInput executes a fuzzing function against a single input.
+func TestInput(t *testing.T) {
+	dat, err := ioutil.ReadFile(os.Getenv("FZGO_INPUT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fuzzer.{{.funcName}}(dat)
+}
+`))
@@ -0,0 +1,196 @@
+package fuzz
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// libFuzzerEngine uses 'go-fuzz-build -libfuzzer' to create an archive
+// that is then linked with clang's -fsanitize=fuzzer into a libFuzzer binary.
+// The resulting binary is cached in the same way as a go-fuzz zip.
+type libFuzzerEngine struct{}
+
+func (libFuzzerEngine) check() error {
+	err := checkGoFuzz()
+	if err != nil {
+		return err
+	}
+	_, err = exec.LookPath("clang")
+	if err != nil {
+		return fmt.Errorf("failed to find \"clang\" command in path, which is required for -fuzzengine=libfuzzer. error: %v", err)
+	}
+	return nil
+}
+
+func (libFuzzerEngine) tools() []string { return []string{"go-fuzz-build", "clang"} }
+
+func (libFuzzerEngine) artifact() string { return "libfuzzer" + exeSuffix() }
+
+func (libFuzzerEngine) prepare(t Target) error { return prepareGoFuzzBuild(t) }
+
+func (libFuzzerEngine) build(t Target, outFile string, verbose bool) error {
+	// first, build our archive with go-fuzz-build.
+	// to support experimentation, initial args for go-fuzz-build are
+	// populated by the optional FZGOFLAGSBUILD env var
+	// (or an empty slice if FZGOFLAGSBUILD is not set).
+	archive := outFile + ".a"
+	defer os.Remove(archive)
+	args := fzgoEnvFlags("FZGOFLAGSBUILD")
+	if !t.hasWrapper {
+		args = append(args,
+			"-libfuzzer",
+			"-func="+t.UserFunc.FuncName,
+			"-o="+archive,
+			buildTagsArg,
+			t.UserFunc.buildPkgPath(),
+		)
+	} else {
+		args = append(args,
+			"-libfuzzer",
+			"-func="+t.wrapperFunc.FuncName,
+			"-o="+archive,
+			buildTagsArg,
+			t.wrapperFunc.PkgPath,
+		)
+	}
+	err := execCmd("go-fuzz-build", args, t.wrapperEnv, t.dir(), 0)
+	if err != nil {
+		return fmt.Errorf("go-fuzz-build failed with args %q: %v", args, err)
+	}
+
+	// second, link our archive into a libFuzzer binary.
+	clangArgs := []string{"-fsanitize=fuzzer", archive, "-o", outFile}
+	err = execCmd("clang", clangArgs, nil, "", 0)
+	if err != nil {
+		return fmt.Errorf("clang failed with args %q: %v", clangArgs, err)
+	}
+	return nil
+}
+
+func (libFuzzerEngine) run(t Target, artifactPath, workDir string, opts runOptions) error {
+	corpusDir := filepath.Join(workDir, "corpus")
+	crashersDir := filepath.Join(workDir, "crashers")
+	for _, dir := range []string{corpusDir, crashersDir} {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return err
+		}
+	}
+
+	// to support experimentation, initial args for libFuzzer are
+	// populated by the optional FZGOFLAGSLIBFUZZER env var
+	// (or an empty slice if FZGOFLAGSLIBFUZZER is not set).
+	runArgs := fzgoEnvFlags("FZGOFLAGSLIBFUZZER")
+	runArgs = append(runArgs,
+		// libFuzzer writes crashes, timeouts, etc. using this prefix.
+		"-artifact_prefix="+crashersDir+string(filepath.Separator),
+		fmt.Sprintf("-timeout=%d", int(opts.FuncTimeout.Seconds())), // this is not total run time
+	)
+	if opts.MaxDuration > 0 {
+		// -max_total_time=0 means unlimited for libFuzzer, so round up to at least 1 second.
+		secs := int(opts.MaxDuration.Seconds())
+		if secs < 1 {
+			secs = 1
+		}
+		runArgs = append(runArgs, fmt.Sprintf("-max_total_time=%d", secs))
+	}
+	if opts.Parallel > 1 {
+		// in fork mode, libFuzzer runs the fuzzing in child processes and prints a child's output
+		// when it crashes. -ignore_crashes keeps fuzzing after a crash, as go-fuzz does.
+		runArgs = append(runArgs,
+			fmt.Sprintf("-fork=%d", opts.Parallel),
+			"-ignore_crashes=1",
+		)
+	}
+	if opts.Verbose {
+		runArgs = append(runArgs, "-verbosity=2")
+	}
+	// libFuzzer mutates using the tokens in our dictionary, if any.
+	dictPath := filepath.Join(workDir, dictFile)
+	n, err := writeTargetDict(t.UserFunc, dictPath, opts.Dicts)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		if opts.Verbose {
+			info("dictionary with %d tokens in %s", n, dictPath)
+		}
+		runArgs = append(runArgs, "-dict="+dictPath)
+	}
+	// libFuzzer reads the corpus from and writes new inputs to our corpus dir.
+	runArgs = append(runArgs, corpusDir)
+
+	// libFuzzer exits with a non-zero status after finding a crash.
+	// We treat that as a successful run (as with go-fuzz, crashers are left in the workDir),
+	// but otherwise report the error.
+	before, err := ioutil.ReadDir(crashersDir)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for _, fi := range before {
+		existing[fi.Name()] = true
+	}
+	// we keep a copy of the output for the .output file of any new crashers.
+	var output bytes.Buffer
+	stdout := io.MultiWriter(opts.stdout, &output)
+	stderr := io.MultiWriter(opts.stderr, &output)
+	runErr := execCmdOutput(artifactPath, runArgs, nil, workDir, 0, stdout, stderr)
+
+	after, err := ioutil.ReadDir(crashersDir)
+	if err != nil {
+		return err
+	}
+	outputs := libFuzzerArtifactOutputs(output.String())
+	added := 0
+	for _, fi := range after {
+		name := fi.Name()
+		if existing[name] || fi.IsDir() || strings.HasSuffix(name, ".output") {
+			continue
+		}
+		out, ok := outputs[name]
+		if !ok {
+			out = output.String()
+		}
+		if err := ioutil.WriteFile(filepath.Join(crashersDir, name+".output"), []byte(out), 0644); err != nil {
+			return err
+		}
+		added++
+	}
+	if added > 0 {
+		info("%d new crasher(s) in %s", added, crashersDir)
+		return nil
+	}
+	return runErr
+}
+
+// libFuzzerArtifactOutputs splits libFuzzer's output into the output for each artifact it reports
+// writing, such as a crash-<sha1> file, keyed by the artifact's filename. The output for an artifact
+// is the output since the prior artifact, which in fork mode includes the log of the child process
+// that crashed.
+func libFuzzerArtifactOutputs(output string) map[string]string {
+	const written = "Test unit written to "
+	result := make(map[string]string)
+	start := 0
+	for i := 0; i < len(output); {
+		end := strings.IndexByte(output[i:], '\n')
+		if end < 0 {
+			end = len(output)
+		} else {
+			end += i + 1
+		}
+		line := output[i:end]
+		if j := strings.Index(line, written); j >= 0 {
+			path := strings.TrimSpace(line[j+len(written):])
+			result[filepath.Base(path)] = output[start:end]
+			start = end
+		}
+		i = end
+	}
+	return result
+}
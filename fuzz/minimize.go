@@ -0,0 +1,275 @@
+package fuzz
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MinimizeOptions control how Minimize shrinks crashers.
+type MinimizeOptions struct {
+	Run         string        // selects the crashers to minimize, such as 'TestCrashers/<hash>'
+	MaxDuration time.Duration // 0 means no max time is enforced for each crasher
+	FuncTimeout time.Duration // time limit for a single execution of the fuzz function
+	Verbose     bool
+}
+
+// Minimized is the result of minimizing a crasher.
+type Minimized struct {
+	Crasher   Crasher
+	Path      string // the minimized input, which is written next to the original crasher
+	Signature string // the panic signature shared by the original and minimized inputs
+	OrigSize  int
+	Size      int
+}
+
+// minSuffix is the suffix for the file containing a minimized crasher.
+const minSuffix = ".min"
+
+// Minimize shrinks the crashers in a workDir that match opts.Run. It builds a harness similar to
+// the one used by VerifyCrashers that executes the fuzz function with a single input, and then
+// repeatedly mutates a crasher by deleting chunks and simplifying bytes, re-running each candidate
+// through the harness. A candidate is kept if it is smaller or simpler and still triggers
+// the same panic signature (the panic message along with the function that panicked).
+// The smallest input is written next to the original crasher with a '.min' suffix.
+func Minimize(function Func, workDir string, opts MinimizeOptions) ([]Minimized, error) {
+	report := func(err error) ([]Minimized, error) {
+		return nil, fmt.Errorf("minimize %s: %v", function.FuzzName(), err)
+	}
+	if opts.FuncTimeout < 1*time.Second {
+		return nil, fmt.Errorf("minimum allowed func timeout value is 1 second")
+	}
+
+	// find the crashers selected by our -run regexp, such as 'TestCrashers/<hash>'.
+	runFields := strings.SplitN(opts.Run, "/", 2)
+	ok, err := regexp.MatchString(runFields[0], "TestCrashers")
+	if err != nil {
+		return report(fmt.Errorf("invalid regexp %q for -run: %v", opts.Run, err))
+	}
+	if !ok {
+		return report(fmt.Errorf("-run=%s does not select any crashers. use a form like -run=TestCrashers/<hash>", opts.Run))
+	}
+	re := regexp.MustCompile(".")
+	if len(runFields) > 1 {
+		re, err = regexp.Compile(runFields[1])
+		if err != nil {
+			return report(fmt.Errorf("invalid regexp %q for -run: %v", opts.Run, err))
+		}
+	}
+	var crashers []Crasher
+	for _, crasher := range Crashers(workDir) {
+		if re.MatchString(crasher.Hash) {
+			crashers = append(crashers, crasher)
+		}
+	}
+	if len(crashers) == 0 {
+		return nil, nil
+	}
+
+	runner, err := newInputRunner(function, false, "minimizetest", nil, opts.FuncTimeout)
+	if err != nil {
+		return report(err)
+	}
+	defer runner.close()
+	m := &minimizer{inputRunner: runner, opts: opts}
+
+	var results []Minimized
+	for _, crasher := range crashers {
+		result, err := m.minimizeFile(crasher)
+		if err != nil {
+			return report(err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// minimizer tries candidate inputs via an inputRunner.
+type minimizer struct {
+	*inputRunner
+	opts     MinimizeOptions
+	deadline time.Time
+}
+
+// minimizeFile minimizes one crasher, writing the result next to the original.
+func (m *minimizer) minimizeFile(crasher Crasher) (Minimized, error) {
+	data, err := ioutil.ReadFile(crasher.Path)
+	if err != nil {
+		return Minimized{}, err
+	}
+	sig, output, err := m.signature(data)
+	if err != nil {
+		return Minimized{}, err
+	}
+	if sig == "" {
+		if m.opts.Verbose {
+			fmt.Print(output)
+		}
+		return Minimized{}, fmt.Errorf("crasher %s did not reproduce a panic", crasher.Hash)
+	}
+	info("minimizing %s (%d bytes) with signature %q", crasher.Hash, len(data), sig)
+
+	m.deadline = time.Time{}
+	if m.opts.MaxDuration > 0 {
+		m.deadline = time.Now().Add(m.opts.MaxDuration)
+	}
+	m.execs = 0
+	min, err := m.minimize(data, sig)
+	if err != nil {
+		return Minimized{}, err
+	}
+
+	path := crasher.Path + minSuffix
+	if err := ioutil.WriteFile(path, min, 0644); err != nil {
+		return Minimized{}, err
+	}
+	return Minimized{Crasher: crasher, Path: path, Signature: sig, OrigSize: len(data), Size: len(min)}, nil
+}
+
+// minimize repeatedly tries deleting chunks of data and then simplifying individual bytes,
+// keeping any candidate that still crashes with sig, until no further progress is made
+// or our deadline is reached.
+func (m *minimizer) minimize(data []byte, sig string) ([]byte, error) {
+	// try returns true if candidate crashes with the same signature.
+	try := func(candidate []byte) (bool, error) {
+		got, _, err := m.signature(candidate)
+		return got == sig, err
+	}
+	expired := func() bool {
+		return !m.deadline.IsZero() && time.Now().After(m.deadline)
+	}
+
+	for progress := true; progress && !expired(); {
+		progress = false
+
+		// first, delete chunks, starting with the entire input and halving the chunk size each pass.
+		for size := len(data); size >= 1 && !expired(); size /= 2 {
+			for i := 0; i+size <= len(data) && !expired(); {
+				candidate := make([]byte, 0, len(data)-size)
+				candidate = append(candidate, data[:i]...)
+				candidate = append(candidate, data[i+size:]...)
+				ok, err := try(candidate)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					data = candidate
+					progress = true
+					if m.opts.Verbose {
+						info("minimized to %d bytes after %d execs", len(data), m.execs)
+					}
+				} else {
+					i += size
+				}
+			}
+		}
+
+		// second, replace individual bytes with '0', which is a simple and printable value.
+		for i := 0; i < len(data) && !expired(); i++ {
+			if data[i] == '0' {
+				continue
+			}
+			candidate := append([]byte(nil), data...)
+			candidate[i] = '0'
+			ok, err := try(candidate)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				data = candidate
+				progress = true
+			}
+		}
+	}
+	return data, nil
+}
+
+// signature executes data, and returns the resulting panic signature
+// (or an empty string if there was no panic) along with the output.
+func (m *minimizer) signature(data []byte) (string, string, error) {
+	output, err := m.run(data)
+	if err != nil {
+		return "", "", err
+	}
+	return crashSignature(output), output, nil
+}
+
+var (
+	// signatureNumbersRe matches numbers in a panic message, such as in 'index out of range [5] with length 3',
+	// which are often specific to the input rather than to the bug.
+	signatureNumbersRe = regexp.MustCompile(`0x[0-9a-fA-F]+|\d+`)
+
+	// signatureFrameSkip are the prefixes of stack frames that are not interesting for a signature.
+	signatureFrameSkip = []string{"runtime.", "runtime/", "testing.", "panic(", "created by "}
+
+	// signatureFrameStop are the prefixes of stack frames from the harness that calls the fuzz function,
+	// which differs between fuzzing engines and between fuzzing and minimizing or replaying a crasher.
+	signatureFrameStop = []string{"fzgo.tmp/", "go-fuzz-dep.", "github.com/dvyukov/go-fuzz/", "reflect.", "main.main("}
+)
+
+// crashSignature returns a signature for a crash based on the output of a Go program,
+// which is the panic message or fatal error with any numbers normalized, along with the
+// first function in the stack that is not part of the runtime or testing packages.
+// It returns an empty string if the output does not contain a panic or fatal error.
+func crashSignature(output string) string {
+	msg, frames := parseCrash(output)
+	if msg == "" || len(frames) == 0 {
+		return msg
+	}
+	return msg + " in " + frames[0]
+}
+
+// parseCrash returns the panic message or fatal error from the output of a Go program
+// with any numbers normalized, along with the functions in the stack of the crashing goroutine.
+// The functions exclude the runtime and testing packages as well as fzgo's harness, and
+// the copies of _test.go files that fzgo creates are reported using their original package name.
+// parseCrash handles the indented output reported by the native engine, such as
+// 'testing.go:1349: panic: boom' followed by an indented stack.
+// It returns an empty msg if the output does not contain a panic or fatal error.
+func parseCrash(output string) (msg string, frames []string) {
+	inStack := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " ")
+		switch {
+		case msg == "":
+			for _, prefix := range []string{"panic: ", "fatal error: "} {
+				if i := strings.Index(line, prefix); i >= 0 && msg == "" {
+					msg = line[i:]
+					if i := strings.Index(msg, " [recovered"); i > 0 {
+						msg = msg[:i]
+					}
+				}
+			}
+		case strings.HasPrefix(line, "goroutine ") && strings.HasSuffix(line, ":"):
+			if inStack {
+				// we only want the first goroutine.
+				return signatureNumbersRe.ReplaceAllString(msg, "N"), frames
+			}
+			inStack = true
+		case inStack && line != "" && !strings.HasPrefix(line, "\t"):
+			for _, prefix := range signatureFrameStop {
+				if strings.HasPrefix(line, prefix) {
+					return signatureNumbersRe.ReplaceAllString(msg, "N"), frames
+				}
+			}
+			skip := false
+			for _, prefix := range signatureFrameSkip {
+				if strings.HasPrefix(line, prefix) {
+					skip = true
+				}
+			}
+			if !skip {
+				if i := strings.LastIndex(line, "("); i > 0 {
+					line = line[:i]
+				}
+				line = strings.Replace(line, "/"+xtestDir+".", "_test.", 1)
+				frames = append(frames, line)
+			}
+		}
+	}
+	return signatureNumbersRe.ReplaceAllString(msg, "N"), frames
+}
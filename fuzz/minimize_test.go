@@ -0,0 +1,72 @@
+package fuzz
+
+import "testing"
+
+func TestCrashSignature(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			name: "recovered panic in test",
+			output: `--- FAIL: TestInput (0.00s)
+panic: easy boom [recovered]
+	panic: easy boom
+
+goroutine 7 [running]:
+testing.tRunner.func1.2({0x5b1f40, 0x6423f0})
+	/usr/local/go/src/testing/testing.go:1396 +0x24e
+testing.tRunner.func1()
+	/usr/local/go/src/testing/testing.go:1399 +0x39f
+panic({0x5b1f40, 0x6423f0})
+	/usr/local/go/src/runtime/panic.go:884 +0x212
+example.com/pkg.FuzzEasy(...)
+	/tmp/pkg/easy.go:5
+fzgo.tmp/richsigwrapper.fuzzOne(0xc000130000)
+`,
+			want: "panic: easy boom in example.com/pkg.FuzzEasy",
+		},
+		{
+			name: "runtime error with numbers",
+			output: `panic: runtime error: index out of range [5] with length 3
+
+goroutine 1 [running]:
+example.com/pkg.parse(...)
+	/tmp/pkg/parse.go:10
+example.com/pkg.Fuzz({0xc000012345, 0x3, 0x3})
+`,
+			want: "panic: runtime error: index out of range [N] with length N in example.com/pkg.parse",
+		},
+		{
+			name: "nil pointer",
+			output: `panic: runtime error: invalid memory address or nil pointer dereference
+[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x4553a2]
+
+goroutine 1 [running]:
+runtime.panicmem()
+	/usr/local/go/src/runtime/panic.go:260 +0x2c
+example.com/pkg.(*T).Method(0x0)
+	/tmp/pkg/t.go:7 +0x22
+`,
+			want: "panic: runtime error: invalid memory address or nil pointer dereference in example.com/pkg.(*T).Method",
+		},
+		{
+			name:   "fatal error without stack",
+			output: "fatal error: out of memory\n",
+			want:   "fatal error: out of memory",
+		},
+		{
+			name:   "no crash",
+			output: "PASS\n",
+			want:   "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := crashSignature(tt.output); got != tt.want {
+				t.Errorf("crashSignature() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
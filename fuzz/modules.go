@@ -0,0 +1,191 @@
+package fuzz
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// fzgoModPath is the module path of fzgo itself. A generated wrapper imports
+// fzgo/randparam, so a temporary module needs to be able to resolve this module.
+const fzgoModPath = "github.com/thepudds/fzgo"
+
+// goFuzzDepPkg is the package that go-fuzz-build inserts into instrumented code.
+// In module mode, it must be resolvable from the main module.
+const goFuzzDepPkg = "github.com/dvyukov/go-fuzz/go-fuzz-dep"
+
+// InModule reports whether the function was found in module mode (rather than GOPATH mode).
+func (f *Func) InModule() bool {
+	return f.ModPath != ""
+}
+
+// createTempModule creates a go.mod in dir for a temporary module named modPath that
+// depends on the module containing function. The go.mod has a replace directive
+// pointing at the on-disk location of the user's module, which means the user's
+// code is used as is, without needing to be published or be in GOPATH.
+// Any replace directives from the user's go.mod are carried over
+// (replace directives only apply in the main module, which will be our temporary module).
+//
+// The source files for the temporary module should already be written to dir
+// prior to calling createTempModule, because 'go mod tidy' is then run to fill in
+// any missing requirements (e.g., fzgo/randparam for a rich signature wrapper).
+func createTempModule(dir, modPath string, function Func) error {
+	report := func(err error) error {
+		return fmt.Errorf("creating temporary module %s: %v", modPath, err)
+	}
+	if !function.InModule() {
+		return report(fmt.Errorf("%s is not in a module", function.FuzzName()))
+	}
+
+	f := new(modfile.File)
+	f.AddModuleStmt(modPath)
+	if err := f.AddRequire(function.ModPath, "v0.0.0"); err != nil {
+		return report(err)
+	}
+	modDir := function.buildModDir()
+	if function.variant != nil {
+		// our copy of the user's module is in a temp dir, so we use a relative path
+		// when we can, which keeps the go.mod (and hence our cache hash) stable across runs.
+		if rel, err := filepath.Rel(dir, modDir); err == nil && strings.HasPrefix(rel, "..") {
+			modDir = filepath.ToSlash(rel)
+		}
+	}
+	if err := f.AddReplace(function.ModPath, "", modDir, ""); err != nil {
+		return report(err)
+	}
+
+	// carry over any replace directives from the user's go.mod.
+	userGoMod := filepath.Join(function.ModDir, "go.mod")
+	data, err := ioutil.ReadFile(userGoMod)
+	if err != nil {
+		return report(err)
+	}
+	userMod, err := modfile.Parse(userGoMod, data, nil)
+	if err != nil {
+		return report(err)
+	}
+	for _, r := range userMod.Replace {
+		newPath := r.New.Path
+		if r.New.Version == "" && !filepath.IsAbs(newPath) {
+			// a relative filesystem path, which is relative to the user's module root.
+			newPath = filepath.Join(function.ModDir, newPath)
+		}
+		if err := f.AddReplace(r.Old.Path, r.Old.Version, newPath, r.New.Version); err != nil {
+			return report(err)
+		}
+	}
+
+	// fzgo/randparam needs to be resolvable. If the user's module does not already depend on fzgo,
+	// we prefer the version of fzgo that is currently running if we can determine it.
+	if function.ModPath != fzgoModPath {
+		if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Path == fzgoModPath &&
+			bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			if err := f.AddRequire(fzgoModPath, bi.Main.Version); err != nil {
+				return report(err)
+			}
+		}
+	}
+
+	out, err := f.Format()
+	if err != nil {
+		return report(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), out, 0700); err != nil {
+		return report(err)
+	}
+
+	// start from the user's go.sum if present, which avoids needing to look up checksums for
+	// modules the user already depends on.
+	userGoSum := filepath.Join(function.ModDir, "go.sum")
+	if PathExists(userGoSum) {
+		if err := CopyFile(filepath.Join(dir, "go.sum"), userGoSum); err != nil {
+			return report(err)
+		}
+	}
+
+	if err := goModCmd(dir, "mod", "tidy"); err != nil {
+		return report(err)
+	}
+	return nil
+}
+
+// requireGoFuzzDep adds go-fuzz-dep as a requirement of the module in dir,
+// which go-fuzz-build requires when operating in module mode.
+func requireGoFuzzDep(dir string) error {
+	if err := goModCmd(dir, "get", goFuzzDepPkg); err != nil {
+		return fmt.Errorf("adding %s requirement: %v", goFuzzDepPkg, err)
+	}
+	return nil
+}
+
+// prepareGoFuzzBuild readies a target for go-fuzz-build, which needs go-fuzz-dep
+// to be part of the temporary wrapper module in module mode.
+func prepareGoFuzzBuild(t Target) error {
+	if t.hasWrapper && t.UserFunc.InModule() {
+		return requireGoFuzzDep(t.wrapperTempDir)
+	}
+	return nil
+}
+
+// goModCmd runs a 'go' command within dir, such as 'go mod tidy',
+// including stderr in any returned error.
+func goModCmd(dir string, args ...string) error {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=on")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// createHarness creates a directory containing a generated package that calls
+// the target's fuzz function (or the target's wrapper function for a rich signature),
+// such as our synthetic corpus_test.go. files maps filenames to contents.
+// In module mode with a wrapper, the harness is a new package named name within the
+// temporary wrapper module, which is already set up to find the user's module.
+// In module mode without a wrapper, the harness is a new temporary module that can find the user's module.
+// In GOPATH mode, the harness is a plain temp directory, and the target's wrapperEnv should be used.
+// The caller is responsible for removing the returned directory.
+func createHarness(target Target, name string, files map[string][]byte) (string, error) {
+	report := func(err error) (string, error) {
+		return "", fmt.Errorf("creating %s harness: %v", name, err)
+	}
+
+	var dir string
+	var err error
+	if target.hasWrapper && target.UserFunc.InModule() {
+		dir = filepath.Join(target.wrapperTempDir, name)
+		err = os.Mkdir(dir, 0700)
+	} else {
+		dir, err = ioutil.TempDir("", "fzgo-"+name)
+	}
+	if err != nil {
+		return report(fmt.Errorf("failed to create temp dir: %v", err))
+	}
+
+	for filename, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, filename), src, 0700); err != nil {
+			os.RemoveAll(dir)
+			return report(fmt.Errorf("failed to create temporary %s: %v", filename, err))
+		}
+	}
+
+	if !target.hasWrapper && target.UserFunc.InModule() {
+		// we need our own temporary module that can find the user's module.
+		if err := createTempModule(dir, "fzgo.tmp/"+name, target.UserFunc); err != nil {
+			os.RemoveAll(dir)
+			return report(err)
+		}
+	}
+	return dir, nil
+}
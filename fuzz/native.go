@@ -0,0 +1,347 @@
+package fuzz
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// nativeFuzzName is the name of the testing.F fuzz function in our synthesized harness.
+const nativeFuzzName = "FuzzNative"
+
+// nativeHeader is the first line of a corpus file in the format used by 'go test -fuzz'.
+const nativeHeader = "go test fuzz v1"
+
+// nativeEngine uses the native fuzzing support added in Go 1.18.
+// We synthesize a 'func FuzzNative(f *testing.F)' harness that calls the user's
+// Fuzz function (or our rich signature wrapper) with the []byte from the native fuzzer,
+// and build it with 'go test -c -fuzz'. When running, the corpus in the fzgo workDir is
+// converted to and from the native corpus file format, which means the workDir
+// layout stays the same regardless of engine.
+// The native fuzzer does not have an equivalent to go-fuzz's per-execution -timeout,
+// so the fzgo -timeout flag is not used with this engine.
+type nativeEngine struct{}
+
+func (nativeEngine) check() error {
+	_, err := exec.LookPath("go")
+	if err != nil {
+		return fmt.Errorf("failed to find \"go\" command in path. error: %v", err)
+	}
+	return nil
+}
+
+func (nativeEngine) tools() []string { return []string{"go"} }
+
+func (nativeEngine) artifact() string { return "native.test" + exeSuffix() }
+
+func (nativeEngine) prepare(t Target) error { return nil }
+
+func (nativeEngine) build(t Target, outFile string, verbose bool) error {
+	var pkgPath, funcName string
+	if t.hasWrapper {
+		pkgPath = t.wrapperFunc.PkgPath
+		funcName = t.wrapperFunc.FuncName
+	} else {
+		pkgPath = t.UserFunc.buildPkgPath()
+		funcName = t.UserFunc.FuncName
+	}
+
+	vals := map[string]string{"pkgPath": pkgPath, "funcName": funcName, "nativeFuzzName": nativeFuzzName}
+	buf := new(bytes.Buffer)
+	if err := nativeHarnessSrc.Execute(buf, vals); err != nil {
+		return fmt.Errorf("could not execute template: %v", err)
+	}
+	dir, err := createHarness(t, "fzgonative", map[string][]byte{"fzgo_native_test.go": buf.Bytes()})
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{
+		"test",
+		"-c",
+		"-fuzz=^" + nativeFuzzName + "$",
+		"-o=" + outFile,
+		buildTagsArg,
+		".",
+	}
+	err = execCmd("go", args, t.wrapperEnv, dir, 0)
+	if err != nil {
+		return fmt.Errorf("go test -c failed with args %q: %v", args, err)
+	}
+	return nil
+}
+
+func (nativeEngine) run(t Target, artifactPath, workDir string, opts runOptions) error {
+	report := func(err error) error {
+		return fmt.Errorf("native fuzzing: %v", err)
+	}
+	corpusDir := filepath.Join(workDir, "corpus")
+	crashersDir := filepath.Join(workDir, "crashers")
+
+	// the native fuzzer reads its seed corpus from testdata/fuzz/<name> relative to the
+	// current directory, and writes any new failing inputs there as well.
+	// we stage our workDir corpus into a temp dir in the native format.
+	stageDir, err := ioutil.TempDir("", "fzgo-native")
+	if err != nil {
+		return report(fmt.Errorf("failed to create temp dir: %v", err))
+	}
+	defer os.RemoveAll(stageDir)
+	seedDir := filepath.Join(stageDir, "testdata", "fuzz", nativeFuzzName)
+	cacheDir := filepath.Join(stageDir, "cache")
+	for _, dir := range []string{seedDir, cacheDir, corpusDir, crashersDir} {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return report(err)
+		}
+	}
+
+	seeds, err := ioutil.ReadDir(corpusDir)
+	if err != nil {
+		return report(err)
+	}
+	staged := make(map[string]bool)
+	for _, seed := range seeds {
+		if !seed.Mode().IsRegular() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(corpusDir, seed.Name()))
+		if err != nil {
+			return report(err)
+		}
+		err = ioutil.WriteFile(filepath.Join(seedDir, seed.Name()), marshalNative(data), 0644)
+		if err != nil {
+			return report(err)
+		}
+		staged[seed.Name()] = true
+	}
+
+	// the native fuzzer does not accept a dictionary, so we add each token from our dictionary
+	// to the seed corpus for this run, without adding them to our workDir corpus.
+	// Any new inputs the fuzzer derives from them are copied back as usual.
+	toks, err := targetDict(t.UserFunc, opts.Dicts)
+	if err != nil {
+		return report(err)
+	}
+	dictSeeds := make(map[string][]byte)
+	for _, tok := range toks {
+		name := fmt.Sprintf("%x", sha1.Sum([]byte(tok)))
+		if staged[name] {
+			continue
+		}
+		err = ioutil.WriteFile(filepath.Join(seedDir, name), marshalNative([]byte(tok)), 0644)
+		if err != nil {
+			return report(err)
+		}
+		staged[name] = true
+		dictSeeds[name] = []byte(tok)
+	}
+	if opts.Verbose && len(dictSeeds) > 0 {
+		info("added %d dictionary tokens to the seed corpus", len(dictSeeds))
+	}
+
+	args := []string{
+		"-test.run=^" + nativeFuzzName + "$",
+		"-test.fuzz=^" + nativeFuzzName + "$",
+		"-test.fuzzcachedir=" + cacheDir,
+		fmt.Sprintf("-test.parallel=%d", opts.Parallel),
+	}
+	if opts.MaxDuration > 0 {
+		args = append(args, fmt.Sprintf("-test.fuzztime=%s", opts.MaxDuration))
+	}
+	if opts.Verbose {
+		args = append(args, "-test.v")
+	}
+
+	// we keep a copy of the output in case we need it for a crasher's .output file.
+	var output bytes.Buffer
+	cmd := exec.Command(artifactPath, args...)
+	cmd.Dir = stageDir
+	cmd.Stdout = io.MultiWriter(opts.stdout, &output)
+	cmd.Stderr = io.MultiWriter(opts.stderr, &output)
+	cmd.Stdin = os.Stdin
+	if len(t.wrapperEnv) > 0 {
+		cmd.Env = t.wrapperEnv
+	}
+	runErr := cmd.Run()
+
+	// copy anything new in the fuzzing cache back into our corpus.
+	newInputs, err := collectNative(cacheDir, nil)
+	if err != nil {
+		return report(err)
+	}
+	for _, data := range newInputs {
+		if err := writeHashed(corpusDir, data, ""); err != nil {
+			return report(err)
+		}
+	}
+
+	// copy any new failing inputs into our crashers.
+	crashers, err := collectNative(seedDir, staged)
+	if err != nil {
+		return report(err)
+	}
+	// the native fuzzer does not write a new file for a failing seed, which could be one
+	// of our dictionary tokens that is not otherwise in our corpus.
+	if name := failingSeed(output.String()); dictSeeds[name] != nil {
+		crashers = append(crashers, dictSeeds[name])
+	}
+	for _, data := range crashers {
+		if err := writeHashed(crashersDir, data, output.String()); err != nil {
+			return report(err)
+		}
+	}
+	if len(crashers) > 0 {
+		info("%d new crasher(s) in %s", len(crashers), crashersDir)
+		return nil
+	}
+
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		return report(runErr)
+	} else if runErr != nil {
+		return report(fmt.Errorf("%s exited with no new crashers: %v", filepath.Base(artifactPath), runErr))
+	}
+	return nil
+}
+
+// failingSeed returns the name of the seed corpus file that failed according to
+// the native fuzzer's output, or an empty string if no seed failed.
+func failingSeed(output string) string {
+	const failure = "failure while testing seed corpus entry: " + nativeFuzzName + "/"
+	i := strings.Index(output, failure)
+	if i < 0 {
+		return ""
+	}
+	name := output[i+len(failure):]
+	if end := strings.IndexAny(name, " \r\n"); end >= 0 {
+		name = name[:end]
+	}
+	return name
+}
+
+// exeSuffix returns the suffix for executables on the current OS.
+func exeSuffix() string {
+	if runtime.GOOS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+// collectNative walks dir and decodes any native corpus files,
+// skipping any file names in skip as well as any files that are not native corpus files.
+func collectNative(dir string, skip map[string]bool) ([][]byte, error) {
+	var result [][]byte
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() || skip[fi.Name()] {
+			return nil
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(b, []byte(nativeHeader)) {
+			return nil
+		}
+		data, err := unmarshalNative(b)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		result = append(result, data)
+		return nil
+	})
+	return result, err
+}
+
+// writeHashed writes data into dir using the sha1 of data as the filename,
+// matching how go-fuzz names its corpus and crasher files.
+// If output is not empty, it is written to an accompanying .output file.
+func writeHashed(dir string, data []byte, output string) error {
+	name := filepath.Join(dir, fmt.Sprintf("%x", sha1.Sum(data)))
+	if err := ioutil.WriteFile(name, data, 0644); err != nil {
+		return err
+	}
+	if output != "" {
+		return ioutil.WriteFile(name+".output", []byte(output), 0644)
+	}
+	return nil
+}
+
+// marshalNative encodes data in the corpus file format used by 'go test -fuzz'
+// for a fuzz function taking a single []byte.
+func marshalNative(data []byte) []byte {
+	return []byte(fmt.Sprintf("%s\n[]byte(%q)\n", nativeHeader, data))
+}
+
+// unmarshalNative decodes a corpus file in the format used by 'go test -fuzz'
+// that contains a single []byte value.
+func unmarshalNative(b []byte) ([]byte, error) {
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 || strings.TrimSpace(lines[0]) != nativeHeader {
+		return nil, fmt.Errorf("unexpected native corpus file format")
+	}
+	expr, err := parser.ParseExpr(lines[1])
+	if err != nil {
+		return nil, fmt.Errorf("parsing native corpus file: %v", err)
+	}
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return nil, fmt.Errorf("expected a single []byte value in native corpus file, found %q", lines[1])
+	}
+	arr, ok := call.Fun.(*ast.ArrayType)
+	if !ok || arr.Len != nil {
+		return nil, fmt.Errorf("expected a single []byte value in native corpus file, found %q", lines[1])
+	}
+	if elt, ok := arr.Elt.(*ast.Ident); !ok || (elt.Name != "byte" && elt.Name != "uint8") {
+		return nil, fmt.Errorf("expected a single []byte value in native corpus file, found %q", lines[1])
+	}
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return nil, fmt.Errorf("expected a string literal in native corpus file, found %q", lines[1])
+	}
+	s, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return nil, fmt.Errorf("unquoting native corpus file: %v", err)
+	}
+	return []byte(s), nil
+}
+
+// nativeHarnessSrc provides a testing.F fuzz function that calls our fuzz function.
+// This template needs three string variables to be supplied:
+//   1. an import path to the fuzzer, such as:
+//        github.com/dvyukov/go-fuzz-corpus/png
+//   2. the fuzz function name, such as:
+//        Fuzz
+//   3. the name of the testing.F fuzz function to create, such as:
+//        FuzzNative
+var nativeHarnessSrc = template.Must(template.New("NativeHarness").Parse(`
+package fzgonative
+
+import (
+	"testing"
+
+	fuzzer "{{.pkgPath}}"
+)
+
+// {{.nativeFuzzName}} calls a go-fuzz style fuzzing function from a native Go fuzz test.
+func {{.nativeFuzzName}}(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzer.{{.funcName}}(data)
+	})
+}
+`))
@@ -0,0 +1,72 @@
+package fuzz
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNativeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"ascii", []byte("hello")},
+		{"quotes and newlines", []byte("a\"b\nc`d")},
+		{"binary", []byte{0x00, 0xff, 0x80, 0x7f}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := unmarshalNative(marshalNative(tt.data))
+			if err != nil {
+				t.Fatalf("unmarshalNative() failed: %v", err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Errorf("unmarshalNative(marshalNative()) = %q, want %q", got, tt.data)
+			}
+		})
+	}
+}
+
+func TestUnmarshalNative(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []byte
+		wantErr bool
+	}{
+		{"written by go test", "go test fuzz v1\n[]byte(\"abc\")\n", []byte("abc"), false},
+		{"raw string", "go test fuzz v1\n[]byte(`a\\b`)\n", []byte(`a\b`), false},
+		{"uint8", "go test fuzz v1\n[]uint8(\"x\")\n", []byte("x"), false},
+		{"missing header", "[]byte(\"abc\")\n", nil, true},
+		{"wrong type", "go test fuzz v1\nstring(\"abc\")\n", nil, true},
+		{"multiple values", "go test fuzz v1\n[]byte(\"abc\")\nint(1)\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := unmarshalNative([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unmarshalNative() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("unmarshalNative() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailingSeed(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"fuzz: elapsed: 0s\nfailure while testing seed corpus entry: FuzzNative/abc123\n--- FAIL: FuzzNative (0.01s)\n", "abc123"},
+		{"failure while testing seed corpus entry: FuzzNative/seed#0\n", "seed#0"},
+		{"fuzz: elapsed: 3s, execs: 100\nPASS\n", ""},
+	}
+	for _, tt := range tests {
+		if got := failingSeed(tt.output); got != tt.want {
+			t.Errorf("failingSeed(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package fuzz
+
+import (
+	"fmt"
+	"go/ast"
+	"strconv"
+	"strings"
+)
+
+// optionsDirective configures how the arguments for a fuzz function with a rich signature are filled.
+// It can be placed in the doc comment of the fuzz function, such as:
+//
+//   //fzgo:randparam maxelements=20 maxdepth=4 nilchance=0.2 maxstringlength=64
+//   func FuzzFoo(m map[string][]int) { ... }
+//
+// maxelements limits the number of elements in maps, chans and slices other than []byte and []string,
+// maxdepth limits how many pointers, maps, slices, chans and interfaces are nested,
+// nilchance is the probability in [0, 1] that a pointer, map, slice, chan or interface is left nil,
+// maxstringlength limits the length of strings and []byte, up to 254,
+// and unexported=true fills the unexported fields of structs declared in the package under test
+// (and the fuzz function's package, for an external test package), which are otherwise left as zero values.
+// Anything not set is selected by the first byte of the input, as usual.
+// See the corresponding randparam.Option for details.
+const optionsDirective = "//fzgo:randparam"
+
+// optionKeys maps the keys in a '//fzgo:randparam' directive to the randparam.Option that they set,
+// whether the value is a probability or a bool rather than a count, and the largest count allowed.
+var optionKeys = map[string]struct {
+	option string
+	prob   bool
+	flag   bool
+	max    int
+}{
+	"maxelements":     {option: "MaxElements", max: 255},
+	"maxdepth":        {option: "MaxDepth", max: 255},
+	"nilchance":       {option: "NilChance", prob: true},
+	"maxstringlength": {option: "MaxStringLength", max: 254},
+	"unexported":      {option: "FillUnexported", flag: true},
+}
+
+// FuzzerOptions returns the Go source for the randparam.Option values set by a '//fzgo:randparam'
+// directive in the doc comment of the fuzz function, such as 'randparam.MaxElements(20)',
+// or nil if there is no directive.
+func FuzzerOptions(function Func) ([]string, error) {
+	if len(function.pkgs) == 0 {
+		return nil, nil
+	}
+	pkg := function.pkgs[0]
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv != nil || fd.Name.Pos() != function.TypesFunc.Pos() {
+				continue
+			}
+			opts, err := parseOptionsDirective(fd.Doc, unexportedPkgs(function))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", pkg.Fset.Position(fd.Pos()), err)
+			}
+			return opts, nil
+		}
+	}
+	return nil, nil
+}
+
+// unexportedPkgs returns the import paths of the packages whose unexported fields are filled
+// with 'unexported=true': the package under test, and the fuzz function's package if it differs,
+// such as for an external test package.
+func unexportedPkgs(function Func) []string {
+	result := []string{function.PkgPath}
+	if path := function.TypesFunc.Pkg().Path(); path != function.PkgPath {
+		result = append(result, path)
+	}
+	return result
+}
+
+// parseOptionsDirective returns the Go source for the options in a '//fzgo:randparam' directive in doc.
+// pkgPaths are the packages whose unexported fields are filled with 'unexported=true'.
+func parseOptionsDirective(doc *ast.CommentGroup, pkgPaths []string) ([]string, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	var result []string
+	seen := make(map[string]bool)
+	for _, c := range doc.List {
+		fields := strings.Fields(c.Text)
+		if len(fields) == 0 || fields[0] != optionsDirective {
+			continue
+		}
+		if len(fields) == 1 {
+			return nil, fmt.Errorf("%s directive has no options", optionsDirective)
+		}
+		for _, field := range fields[1:] {
+			key, val, err := parseOption(field, seen, pkgPaths)
+			if err != nil {
+				return nil, fmt.Errorf("%s directive: %v", optionsDirective, err)
+			}
+			if key == "" {
+				// a flag that is not set.
+				continue
+			}
+			result = append(result, fmt.Sprintf("randparam.%s(%s)", key, val))
+		}
+	}
+	return result, nil
+}
+
+// parseOption parses a single 'key=value' option from a '//fzgo:randparam' directive,
+// returning the name of the randparam.Option and its arguments, or "" for a flag that is false.
+func parseOption(field string, seen map[string]bool, pkgPaths []string) (string, string, error) {
+	eq := strings.Index(field, "=")
+	if eq < 0 {
+		return "", "", fmt.Errorf("option %q is not of the form key=value", field)
+	}
+	key, val := strings.ToLower(field[:eq]), field[eq+1:]
+	k, ok := optionKeys[key]
+	if !ok {
+		return "", "", fmt.Errorf("unknown option %q", field[:eq])
+	}
+	if seen[key] {
+		return "", "", fmt.Errorf("option %s set more than once", key)
+	}
+	seen[key] = true
+
+	if k.flag {
+		set, err := strconv.ParseBool(val)
+		if err != nil {
+			return "", "", fmt.Errorf("%s=%s: must be true or false", key, val)
+		}
+		if !set {
+			return "", "", nil
+		}
+		var args []string
+		for _, path := range pkgPaths {
+			args = append(args, strconv.Quote(path))
+		}
+		return k.option, strings.Join(args, ", "), nil
+	}
+	if k.prob {
+		p, err := strconv.ParseFloat(val, 64)
+		if err != nil || p < 0 || p > 1 {
+			return "", "", fmt.Errorf("%s=%s: must be a number between 0 and 1", key, val)
+		}
+		return k.option, strconv.FormatFloat(p, 'g', -1, 64), nil
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 0 || n > k.max {
+		return "", "", fmt.Errorf("%s=%s: must be an integer between 0 and %d", key, val, k.max)
+	}
+	return k.option, strconv.Itoa(n), nil
+}
+
+// newFuzzerCall returns the Go source for creating the randparam.Fuzzer for data with opts.
+func newFuzzerCall(data string, opts []string) string {
+	if len(opts) == 0 {
+		return fmt.Sprintf("randparam.NewFuzzer(%s)", data)
+	}
+	return fmt.Sprintf("randparam.NewFuzzerWithOptions(%s, %s)", data, strings.Join(opts, ", "))
+}
+
+// encodeCall returns the Go source for encoding args for a randparam.Fuzzer created with opts.
+func encodeCall(args []string, opts []string) string {
+	if len(opts) == 0 {
+		return fmt.Sprintf("randparam.Encode(%s)", strings.Join(args, ", "))
+	}
+	return fmt.Sprintf("randparam.EncodeWithOptions([]interface{}{%s}, %s)", strings.Join(args, ", "), strings.Join(opts, ", "))
+}
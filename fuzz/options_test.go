@@ -0,0 +1,56 @@
+package fuzz
+
+import (
+	"go/ast"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseOptionsDirective(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment []string
+		want    []string
+		wantErr string
+	}{
+		{"no directive", []string{"// FuzzFoo is a fuzz function.", "//fzgo:impl"}, nil, ""},
+		{"all options", []string{"// FuzzFoo is a fuzz function.", "//fzgo:randparam maxelements=20 MaxDepth=4 nilchance=.25 maxstringlength=0"},
+			[]string{"randparam.MaxElements(20)", "randparam.MaxDepth(4)", "randparam.NilChance(0.25)", "randparam.MaxStringLength(0)"}, ""},
+		{"multiple directives", []string{"//fzgo:randparam nilchance=1", "//fzgo:randparam maxdepth=0"},
+			[]string{"randparam.NilChance(1)", "randparam.MaxDepth(0)"}, ""},
+		{"unexported fields", []string{"//fzgo:randparam unexported=true"},
+			[]string{`randparam.FillUnexported("example.com/p", "example.com/p_test")`}, ""},
+		{"unexported fields not set", []string{"//fzgo:randparam unexported=false maxdepth=1"}, []string{"randparam.MaxDepth(1)"}, ""},
+		{"flag not a bool", []string{"//fzgo:randparam unexported=yes"}, nil, "true or false"},
+		{"no options", []string{"//fzgo:randparam"}, nil, "no options"},
+		{"unknown option", []string{"//fzgo:randparam depth=2"}, nil, "unknown option"},
+		{"missing value", []string{"//fzgo:randparam maxdepth"}, nil, "key=value"},
+		{"repeated option", []string{"//fzgo:randparam maxdepth=2", "//fzgo:randparam maxdepth=3"}, nil, "more than once"},
+		{"chance out of range", []string{"//fzgo:randparam nilchance=10"}, nil, "between 0 and 1"},
+		{"count out of range", []string{"//fzgo:randparam maxstringlength=255"}, nil, "between 0 and 254"},
+		{"count not an integer", []string{"//fzgo:randparam maxelements=2.5"}, nil, "between 0 and 255"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &ast.CommentGroup{}
+			for _, c := range tt.comment {
+				doc.List = append(doc.List, &ast.Comment{Text: c})
+			}
+			got, err := parseOptionsDirective(doc, []string{"example.com/p", "example.com/p_test"})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseOptionsDirective() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseOptionsDirective() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parseOptionsDirective() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
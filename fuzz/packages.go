@@ -12,7 +12,7 @@ import (
 	"golang.org/x/tools/go/packages"
 )
 
-const buildTagsArg = "-tags=gofuzz fuzz"
+const buildTagsArg = "-tags=gofuzz fuzz " + testVariantTag
 
 // Func represents a discovered function that will be fuzzed.
 type Func struct {
@@ -20,7 +20,19 @@ type Func struct {
 	PkgName   string      // package name (should be the same as the package's package statement)
 	PkgPath   string      // import path
 	PkgDir    string      // local on-disk directory
+	ModPath   string      // module path, or empty if not in module mode
+	ModDir    string      // local on-disk directory of the module root, or empty if not in module mode
 	TypesFunc *types.Func // auxiliary information about a Func from the go/types package
+
+	InTestFile bool     // defined in a _test.go file
+	XTest      bool     // defined in an external test package (e.g., 'package foo_test'), in which case PkgPath is the package under test
+	TestFiles  []string // the package's in-package _test.go files, only set if InTestFile is true
+	XTestFiles []string // the external test package's _test.go files, only set if XTest is true
+
+	SeedsFunc string // the name of a 'func() [][]interface{}' supplying seed inputs, such as FuzzFooSeeds, if any
+
+	pkgs    []*packages.Package // the loaded package containing the function, plus the package under test for an external test package
+	variant *testVariant        // our copy of the source tree for building a function in a _test.go file, if any
 }
 
 // FuzzName returns the '<pkg>.<OrigFuzzFunc>' string.
@@ -41,6 +53,9 @@ func (f *Func) String() string {
 // suggests not allowing something like 'go test -fuzz=. ./...' to match multiple fuzz functions.
 // As an experiment, allowMultiFuzz flag allows that.
 // FindFunc also allows for multiple packages in pkgPattern separated by whitespace.
+// Fuzz functions may reside in _test.go files, including in external test packages.
+// A fuzz function FuzzFoo may be accompanied by a 'func FuzzFooSeeds() [][]interface{}'
+// in the same package that returns seed inputs, which is not itself treated as a fuzz function.
 func FindFunc(pkgPattern, funcPattern string, env []string, allowMultiFuzz bool) ([]Func, error) {
 	report := func(err error) error {
 		return fmt.Errorf("error while loading packages for pattern %v: %v", pkgPattern, err)
@@ -50,8 +65,9 @@ func FindFunc(pkgPattern, funcPattern string, env []string, allowMultiFuzz bool)
 	// load packages based on our package pattern
 	// build tags example: https://groups.google.com/d/msg/golang-tools/Adwr7jEyDmw/wQZ5qi8ZGAAJ
 	cfg := &packages.Config{
-		Mode:       packages.LoadSyntax,
+		Mode:       packages.LoadSyntax | packages.NeedModule,
 		BuildFlags: []string{buildTagsArg},
+		Tests:      true,
 	}
 	if len(env) > 0 {
 		cfg.Env = env
@@ -60,20 +76,48 @@ func FindFunc(pkgPattern, funcPattern string, env []string, allowMultiFuzz bool)
 	if err != nil {
 		return nil, report(err)
 	}
-	if packages.PrintErrors(pkgs) > 0 {
+	if printErrors(pkgs) > 0 {
 		return nil, fmt.Errorf("package load error for package pattern %v", pkgPattern)
 	}
 
+	// because we load tests, a package with tests shows up multiple times: the package itself,
+	// the package compiled for test (e.g., ID 'foo [foo.test]'), possibly an external test package
+	// (e.g., ID 'foo_test [foo.test]'), and the test main package (e.g., ID 'foo.test').
+	// gather the _test.go files from the in-package test variants.
+	// also remember the package under test for an external test package, preferring the test variant.
+	testFiles := make(map[string][]string)
+	underTest := make(map[string]*packages.Package)
+	for _, pkg := range pkgs {
+		if isTestVariant(pkg) && !isXTest(pkg) {
+			testFiles[pkg.PkgPath] = goTestFiles(pkg)
+			underTest[pkg.PkgPath] = pkg
+		} else if !isTestVariant(pkg) && underTest[pkg.PkgPath] == nil {
+			underTest[pkg.PkgPath] = pkg
+		}
+	}
+
 	// look for a func that starts with 'Fuzz' and matches our regexp.
 	// loop over the packages we found and loop over the Defs for each package.
 	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.ID, ".test") {
+			// skip the synthesized test main package.
+			continue
+		}
 		for id, obj := range pkg.TypesInfo.Defs {
 			// check if we have a func
 			f, ok := obj.(*types.Func)
 			if ok {
 
 				// check if it starts with "Fuzz" and matches our fuzz function regular expression
-				if !strings.HasPrefix(id.Name, "Fuzz") {
+				// skip seeds funcs and native Go fuzz tests like 'func FuzzFoo(f *testing.F)'.
+				if !strings.HasPrefix(id.Name, "Fuzz") || isSeedsFunc(f) || isNativeFuzzTest(f) {
+					continue
+				}
+
+				// a func in a non-test file shows up in both the package and the test variant of the package,
+				// so for the test variant we only look at funcs in _test.go files.
+				inTestFile := strings.HasSuffix(pkg.Fset.Position(id.Pos()).Filename, "_test.go")
+				if isTestVariant(pkg) && !inTestFile {
 					continue
 				}
 
@@ -83,19 +127,43 @@ func FindFunc(pkgPattern, funcPattern string, env []string, allowMultiFuzz bool)
 				}
 				if matchedPattern {
 					// found a match.
+					// for an external test package, we use the import path of the package under test.
+					pkgPath := pkg.PkgPath
+					if isXTest(pkg) {
+						pkgPath = strings.TrimSuffix(pkgPath, "_test")
+					}
 					// check if we already found a match in a prior iteration our of loops.
 					if len(result) > 0 && !allowMultiFuzz {
 						return nil, fmt.Errorf("multiple matches not allowed. multiple matches for pattern %v and func %v: %v.%v and %v.%v",
-							pkgPattern, funcPattern, pkg.PkgPath, id.Name, result[0].PkgPath, result[0].FuncName)
+							pkgPattern, funcPattern, pkgPath, id.Name, result[0].PkgPath, result[0].FuncName)
 					}
-					pkgDir, err := goListDir(pkg.PkgPath, env)
+					pkgDir, err := goListDir(pkgPath, env)
 					if err != nil {
 						return nil, report(err)
 					}
 
 					function := Func{
-						FuncName: id.Name, PkgName: pkg.Name, PkgPath: pkg.PkgPath, PkgDir: pkgDir,
-						TypesFunc: f,
+						FuncName: id.Name, PkgName: pkg.Name, PkgPath: pkgPath, PkgDir: pkgDir,
+						TypesFunc: f, pkgs: []*packages.Package{pkg},
+					}
+					if seeds, ok := pkg.Types.Scope().Lookup(id.Name + "Seeds").(*types.Func); ok && isSeedsFunc(seeds) {
+						function.SeedsFunc = seeds.Name()
+					}
+					if inTestFile {
+						function.InTestFile = true
+						function.TestFiles = testFiles[pkgPath]
+						if isXTest(pkg) {
+							function.XTest = true
+							function.XTestFiles = goTestFiles(pkg)
+							if p := underTest[pkgPath]; p != nil {
+								function.pkgs = append(function.pkgs, p)
+							}
+						}
+					}
+					if pkg.Module != nil {
+						// we are in module mode.
+						function.ModPath = pkg.Module.Path
+						function.ModDir = pkg.Module.Dir
 					}
 					result = append(result, function)
 
@@ -121,6 +189,87 @@ func FindFunc(pkgPattern, funcPattern string, env []string, allowMultiFuzz bool)
 	return result, nil
 }
 
+// isSeedsFunc reports whether f is a function like 'func FuzzFooSeeds() [][]interface{}'
+// that supplies seed inputs for a fuzz function.
+func isSeedsFunc(f *types.Func) bool {
+	sig, ok := f.Type().(*types.Signature)
+	if !ok || sig.Recv() != nil || !strings.HasSuffix(f.Name(), "Seeds") {
+		return false
+	}
+	if sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+		return false
+	}
+	outer, ok := sig.Results().At(0).Type().(*types.Slice)
+	if !ok {
+		return false
+	}
+	inner, ok := outer.Elem().(*types.Slice)
+	if !ok {
+		return false
+	}
+	iface, ok := inner.Elem().Underlying().(*types.Interface)
+	return ok && iface.Empty()
+}
+
+// isNativeFuzzTest reports whether f is a native Go fuzz test like 'func FuzzFoo(f *testing.F)',
+// which is run by 'go test -fuzz' rather than by fzgo.
+func isNativeFuzzTest(f *types.Func) bool {
+	sig, ok := f.Type().(*types.Signature)
+	if !ok || sig.Recv() != nil || sig.Params().Len() != 1 {
+		return false
+	}
+	ptr, ok := sig.Params().At(0).Type().(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "testing" && obj.Name() == "F"
+}
+
+// printErrors is similar to packages.PrintErrors, but skips the synthesized test main packages.
+// Since Go 1.18, the go command reports an error for a test main package if a _test.go file contains
+// a FuzzXxx func that is not a 'func(*testing.F)', which is expected for our fuzz functions.
+// It returns the number of errors printed.
+func printErrors(pkgs []*packages.Package) int {
+	var n int
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if strings.HasSuffix(pkg.ID, ".test") {
+			return
+		}
+		for _, err := range pkg.Errors {
+			fmt.Fprintln(os.Stderr, err)
+			n++
+		}
+	})
+	return n
+}
+
+// isTestVariant reports whether pkg is a package compiled for a test,
+// such as ID 'foo [foo.test]' or 'foo_test [foo.test]'.
+func isTestVariant(pkg *packages.Package) bool {
+	return strings.HasSuffix(pkg.ID, ".test]")
+}
+
+// isXTest reports whether pkg is an external test package, such as 'package foo_test'.
+func isXTest(pkg *packages.Package) bool {
+	return isTestVariant(pkg) && strings.HasSuffix(pkg.PkgPath, "_test")
+}
+
+// goTestFiles returns the _test.go files for a package.
+func goTestFiles(pkg *packages.Package) []string {
+	var result []string
+	for _, file := range pkg.GoFiles {
+		if strings.HasSuffix(file, "_test.go") {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
 // goListDir returns the dir for a package import path
 func goListDir(pkgPath string, env []string) (string, error) {
 	if len(env) == 0 {
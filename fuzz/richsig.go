@@ -56,14 +56,21 @@ func IsPlainSig(f *types.Func) (bool, error) {
 
 // CreateRichSigWrapper creates a temp working directory, then
 // creates a rich signature wrapping fuzz function.
+// In module mode, the temp directory is the root of a temporary module
+// that uses a replace directive to point at the user's module.
+// Otherwise, the temp directory is set up as an additional GOPATH entry.
 // Important: don't set printArgs=true when actually fuzzing. (Likely bad for perf, though not yet attempted).
 func CreateRichSigWrapper(function Func, printArgs bool) (t Target, err error) {
 	report := func(err error) (Target, error) {
 		return Target{}, fmt.Errorf("creating wrapper function for %s: %v", function.FuzzName(), err)
 	}
 
-	// create temp dir to work in
-	tempDir, err := ioutil.TempDir("", "fzgo-fuzz-rich-signature")
+	// create temp dir to work in, which is alongside any copy of the source tree for a test variant.
+	var parentDir string
+	if function.variant != nil {
+		parentDir = function.variant.tempDir
+	}
+	tempDir, err := ioutil.TempDir(parentDir, "fzgo-fuzz-rich-signature")
 	if err != nil {
 		return report(fmt.Errorf("create staging temp dir: %v", err))
 	}
@@ -76,14 +83,24 @@ func CreateRichSigWrapper(function Func, printArgs bool) (t Target, err error) {
 	}()
 
 	// to support modules, the first element of our import path must include a '.'.
-	wrapperDir := filepath.Join(tempDir, "gopath", "src", "fzgo.tmp", "richsigwrapper")
-	if err := os.MkdirAll(wrapperDir, 0700); err != nil {
-		return report(fmt.Errorf("failed to create gopath/src in temp dir: %v", err))
-	}
+	var wrapperDir string
+	var env []string
+	if function.InModule() {
+		// the temp dir is the root of our temporary module.
+		wrapperDir = tempDir
+	} else {
+		wrapperDir = filepath.Join(tempDir, "gopath", "src", "fzgo.tmp", "richsigwrapper")
+		if err := os.MkdirAll(wrapperDir, 0700); err != nil {
+			return report(fmt.Errorf("failed to create gopath/src in temp dir: %v", err))
+		}
 
-	origGp := Gopath()
-	gp := strings.Join([]string{origGp, filepath.Join(tempDir, "gopath")},
-		string(os.PathListSeparator))
+		// Create an env map to include our temporary gopath.
+		// (If env contains duplicate environment keys for GOPATH, only the last value is used).
+		origGp := function.buildGopath()
+		gp := strings.Join([]string{origGp, filepath.Join(tempDir, "gopath")},
+			string(os.PathListSeparator))
+		env = append(os.Environ(), "GOPATH="+gp)
+	}
 
 	// cd to our temp dir to simplify things when we indirectly invoke the
 	// 'go' command (e.g., when searching for funcs below).
@@ -115,16 +132,19 @@ func CreateRichSigWrapper(function Func, printArgs bool) (t Target, err error) {
 		return report(fmt.Errorf("failed to create temporary richsigwrapper.go: %v", err))
 	}
 
-	// Create an env map to include our temporary gopath.
-	// (If env contains duplicate environment keys for GOPATH, only the last value is used).
-	env := append(os.Environ(), "GOPATH="+gp)
+	if function.InModule() {
+		// now that our source is in place, create the go.mod for our temporary module.
+		if err := createTempModule(wrapperDir, "fzgo.tmp/richsigwrapper", function); err != nil {
+			return report(err)
+		}
+	}
 
 	// Re-use our fuzz.FindFunc to find the newly created wrapper.
 	// Note: pkg patterns like 'fzgo/...' and 'fzgo/richsigwrapper' don't seem to work, but '.' does.
 	// (We cd'ed above to the working directory. Maybe a go/packages bug, not liking >1 GOPATH entry?)
 	functions, err := FindFunc("fzgo.tmp/richsigwrapper", "FuzzRichSigWrapper", env, false)
 	if err != nil || len(functions) == 0 {
-		return report(fmt.Errorf("failed to find wrapper func in temp gopath: %v", err))
+		return report(fmt.Errorf("failed to find wrapper func in temp dir: %v", err))
 	}
 
 	// Pull together everything we need about our wrapper into a Target.
@@ -149,20 +169,33 @@ func createWrapper(w io.Writer, function Func, printArgs bool) error {
 	f := function.TypesFunc
 	sig, ok := f.Type().(*types.Signature)
 	if !ok {
-		return fmt.Errorf("function %s is not *types.Signature (%+v)", function, f)
+		return fmt.Errorf("function %s is not *types.Signature (%+v)", function.String(), f)
+	}
+
+	// any options set by a '//fzgo:randparam' directive on the fuzz function.
+	opts, err := FuzzerOptions(function)
+	if err != nil {
+		return err
 	}
 
 	// start emitting the wrapper program!
-	// TODO: add in something like: fuzzer := gofuzz.New().NilChance(0.1).NumElements(0, 10).MaxDepth(10)
 	fmt.Fprintf(w, "\npackage richsigwrapper\n")
-	fmt.Fprintf(w, "\nimport \"%s\"\n", function.PkgPath)
+	if !function.XTest {
+		fmt.Fprintf(w, "\nimport \"%s\"\n", function.PkgPath)
+	} else {
+		// the package name of an external test package (e.g., foo_test) does not match its
+		// import path, so we name the import. The signature might also refer to types from
+		// the package under test. (Any unused import is removed when we later fix up imports).
+		fmt.Fprintf(w, "\nimport %s \"%s\"\n", f.Pkg().Name(), function.buildPkgPath())
+		fmt.Fprintf(w, "\nimport \"%s\"\n", function.PkgPath)
+	}
 	fmt.Fprintf(w, `
 import "github.com/thepudds/fzgo/randparam"
 
 // FuzzRichSigWrapper is an automatically generated wrapper that is
 // compatible with dvyukov/go-fuzz.
 func FuzzRichSigWrapper(data []byte) int {
-	fuzzer := randparam.NewFuzzer(data)
+	fuzzer := %s
 	fuzzOne(fuzzer)
 	return 0
 }
@@ -174,12 +207,21 @@ func fuzzOne (fuzzer *randparam.Fuzzer) {
 
 	// Create random args for each parameter from the signature.
 	// fuzzer.Fuzz recursively fills all of obj's fields with something random.
-	// Only exported (public) fields can be set currently. (That is how google/go-fuzz operates).
-`)
+	// Only exported (public) fields are set, unless a '//fzgo:randparam unexported=true' directive
+	// allows unexported fields for types in the package under test.
+`, newFuzzerCall("data", opts))
 
 	// emit declaring and filling the arguments we will
-	// pass into the wrapped function.
-	fillVars(w, sig, printArgs)
+	// pass into the wrapped function, using any implementations registered
+	// with '//fzgo:impl' directives for interface parameters.
+	impls, err := FindImpls(function.pkgs)
+	if err != nil {
+		return err
+	}
+	if err := checkShapeTags(sig, impls); err != nil {
+		return err
+	}
+	fillVars(w, sig, printArgs, impls)
 
 	// emit the call to the wrapped function
 	fmt.Fprintf(w, "\t%s.%s(", f.Pkg().Name(), f.Name()) // was target.%s with f.Name()
@@ -251,7 +293,9 @@ var InterfaceImpl = map[string]string{
 
 // fillVars declares and populates each variable for the function under test.
 // It iterates over the parameters, emitting the wrapper function as it goes.
-func fillVars(w io.Writer, sig *types.Signature, printArgs bool) {
+// An interface parameter with implementations in impls is set to one of the implementations,
+// and a func parameter is set to a synthesized func value.
+func fillVars(w io.Writer, sig *types.Signature, printArgs bool, impls []Impl) {
 	// first version was loosely modeled after PrintHugeParams in https://github.com/golang/example/blob/master/gotypes/hugeparam/main.go#L24
 	for i := 0; i < sig.Params().Len(); i++ {
 		v := sig.Params().At(i)
@@ -260,58 +304,88 @@ func fillVars(w io.Writer, sig *types.Signature, printArgs bool) {
 		typeStringWithSelector := types.TypeString(v.Type(), externalQualifier)
 		fmt.Fprintf(w, "\tvar %s %s\n", v.Name(), typeStringWithSelector)
 
-		// Set the value based on whether this is an interface
-		// for which we do something special. If we don't find
-		// anything in our InterfaceImpl, default to attempting to
-		// fill the variable directly.
-		switch InterfaceImpl[typeStringWithSelector] {
-		case "ioutil.Discard":
-			// example:
-			//    w = ioutil.Discard
-			fmt.Fprintf(w, "\t%s = ioutil.Discard\n", v.Name())
-		case "bytes.Reader":
-			// example:
-			//   var __fzgoTmp1 []byte
-			//   fuzzer.Fuzz(&__fzgoTmp1)
-			//   r = bytes.NewReader(__fzgoTemp1)
-			fmt.Fprintf(w, "\tvar __fzgoTmp%d []byte\n", i+1)
-			fmt.Fprintf(w, "\tfuzzer.Fuzz(&__fzgoTmp%d)\n", i+1)
-			fmt.Fprintf(w, "\t%s = bytes.NewReader(__fzgoTmp%d)\n", v.Name(), i+1)
-		case "bytes.Buffer":
-			// example:
-			//   var __fzgoTmp1 []byte
-			//   fuzzer.Fuzz(&__fzgoTmp1)
-			//   foo = bytes.NewBuffer(__fzgoTemp1)
-			fmt.Fprintf(w, "\tvar __fzgoTmp%d []byte\n", i+1)
-			fmt.Fprintf(w, "\tfuzzer.Fuzz(&__fzgoTmp%d)\n", i+1)
-			fmt.Fprintf(w, "\t%s = bytes.NewBuffer(__fzgoTmp%d)\n", v.Name(), i+1)
-		case "ioutil.NopCloser":
-			// example:
-			//   var __fzgoTmp1 []byte
-			//   fuzzer.Fuzz(&__fzgoTmp1)
-			//   r = ioutil.NopCloser(bytes.NewReader(__fzgoTemp1))
-			fmt.Fprintf(w, "\tvar __fzgoTmp%d []byte\n", i+1)
-			fmt.Fprintf(w, "\tfuzzer.Fuzz(&__fzgoTmp%d)\n", i+1)
-			fmt.Fprintf(w, "\t%s = ioutil.NopCloser(bytes.NewReader(__fzgoTmp%d))\n", v.Name(), i+1)
-		case "context.Context":
-			// example:
-			//    ctx = context.Background()
-			fmt.Fprintf(w, "\t%s = context.Background()\n", v.Name())
-		default:
-			// Use the type directly.
-			// example:
-			//		fuzzer.Fuzz(&foo)
-			fmt.Fprintf(w, "\tfuzzer.Fuzz(&%s)\n", v.Name())
+		// if printArgs is set, we print Go source for the argument, such as for a regression test.
+		// literal is an expression in the wrapper for that Go source.
+		var literal string
+		if choices := implsFor(v.Type(), impls); len(choices) > 0 {
+			literal = fillImpl(w, v.Name(), i+1, choices, printArgs)
+		} else if funcSig, ok := v.Type().Underlying().(*types.Signature); ok {
+			literal = fillFunc(w, v.Name(), i+1, funcSig, printArgs)
+		} else {
+			literal = fillVar(w, "\t", v.Name(), typeStringWithSelector, fmt.Sprint(i+1), paramShape(v.Name()))
 		}
 
 		if printArgs {
-			fmt.Fprintf(w, "\tfmt.Printf(\"        %20s:  %%#v\\n\", %s)\n",
-				v.Name(), v.Name())
+			fmt.Fprintf(w, "\tfmt.Printf(\"        %20s:  %%s\\n\", %s)\n",
+				v.Name(), literal)
 		}
 		fmt.Fprintf(w, "\n")
 	}
 }
 
+// fillVar emits the code to populate the already declared variable name of type typeStringWithSelector,
+// with each line starting with indent. tmp is a unique suffix for any temporary variables.
+// shape is the Go source for a randparam.Shape for the strings in the value, or "" for any string.
+// It returns an expression in the wrapper for the Go source of the value.
+func fillVar(w io.Writer, indent, name, typeStringWithSelector, tmp, shape string) string {
+	// Set the value based on whether this is an interface
+	// for which we do something special. If we don't find
+	// anything in our InterfaceImpl, default to attempting to
+	// fill the variable directly.
+	literal := fmt.Sprintf("randparam.Literal(%s)", name)
+	switch InterfaceImpl[typeStringWithSelector] {
+	case "ioutil.Discard":
+		// example:
+		//    w = ioutil.Discard
+		fmt.Fprintf(w, "%s%s = ioutil.Discard\n", indent, name)
+		literal = `"ioutil.Discard"`
+	case "bytes.Reader":
+		// example:
+		//   var __fzgoTmp1 []byte
+		//   fuzzer.Fuzz(&__fzgoTmp1)
+		//   r = bytes.NewReader(__fzgoTemp1)
+		fmt.Fprintf(w, "%svar __fzgoTmp%s []byte\n", indent, tmp)
+		fmt.Fprintf(w, "%sfuzzer.Fuzz(&__fzgoTmp%s)\n", indent, tmp)
+		fmt.Fprintf(w, "%s%s = bytes.NewReader(__fzgoTmp%s)\n", indent, name, tmp)
+		literal = fmt.Sprintf(`"bytes.NewReader(" + randparam.Literal(__fzgoTmp%s) + ")"`, tmp)
+	case "bytes.Buffer":
+		// example:
+		//   var __fzgoTmp1 []byte
+		//   fuzzer.Fuzz(&__fzgoTmp1)
+		//   foo = bytes.NewBuffer(__fzgoTemp1)
+		fmt.Fprintf(w, "%svar __fzgoTmp%s []byte\n", indent, tmp)
+		fmt.Fprintf(w, "%sfuzzer.Fuzz(&__fzgoTmp%s)\n", indent, tmp)
+		fmt.Fprintf(w, "%s%s = bytes.NewBuffer(__fzgoTmp%s)\n", indent, name, tmp)
+		literal = fmt.Sprintf(`"bytes.NewBuffer(" + randparam.Literal(__fzgoTmp%s) + ")"`, tmp)
+	case "ioutil.NopCloser":
+		// example:
+		//   var __fzgoTmp1 []byte
+		//   fuzzer.Fuzz(&__fzgoTmp1)
+		//   r = ioutil.NopCloser(bytes.NewReader(__fzgoTemp1))
+		fmt.Fprintf(w, "%svar __fzgoTmp%s []byte\n", indent, tmp)
+		fmt.Fprintf(w, "%sfuzzer.Fuzz(&__fzgoTmp%s)\n", indent, tmp)
+		fmt.Fprintf(w, "%s%s = ioutil.NopCloser(bytes.NewReader(__fzgoTmp%s))\n", indent, name, tmp)
+		literal = fmt.Sprintf(`"ioutil.NopCloser(bytes.NewReader(" + randparam.Literal(__fzgoTmp%s) + "))"`, tmp)
+	case "context.Context":
+		// example:
+		//    ctx = context.Background()
+		fmt.Fprintf(w, "%s%s = context.Background()\n", indent, name)
+		literal = `"context.Background()"`
+	default:
+		// Use the type directly.
+		// example:
+		//		fuzzer.Fuzz(&foo)
+		// or with a shape selected by the name of the parameter:
+		//		fuzzer.FuzzShape(&utf8Foo, randparam.UTF8)
+		if shape != "" {
+			fmt.Fprintf(w, "%sfuzzer.FuzzShape(&%s, %s)\n", indent, name, shape)
+		} else {
+			fmt.Fprintf(w, "%sfuzzer.Fuzz(&%s)\n", indent, name)
+		}
+	}
+	return literal
+}
+
 // externalQualifier can be used as types.Qualifier in calls to types.TypeString and similar.
 func externalQualifier(p *types.Package) string {
 	// always return the package name, which
@@ -50,7 +50,8 @@ func fuzzOne (fuzzer *randparam.Fuzzer) {
 
 	// Create random args for each parameter from the signature.
 	// fuzzer.Fuzz recursively fills all of obj's fields with something random.
-	// Only exported (public) fields can be set currently. (That is how google/go-fuzz operates).
+	// Only exported (public) fields are set, unless a '//fzgo:randparam unexported=true' directive
+	// allows unexported fields for types in the package under test.
 	var re string
 	fuzzer.Fuzz(&re)
 
@@ -62,6 +63,132 @@ func fuzzOne (fuzzer *randparam.Fuzzer) {
 
 	pkgname.FuzzWithBasicTypes(re, input, posix)
 
+}
+`,
+		},
+		{
+			name: "fuzzer options set via fzgo:randparam",
+			args: args{
+				funcPattern: "FuzzWithOptions",
+				pkgPattern:  "github.com/thepudds/fzgo/examples/richsignatures",
+				printArgs:   false,
+			},
+			wantErr: false,
+			wantOutput: `
+package richsigwrapper
+
+import "github.com/thepudds/fzgo/examples/richsignatures"
+
+import "github.com/thepudds/fzgo/randparam"
+
+// FuzzRichSigWrapper is an automatically generated wrapper that is
+// compatible with dvyukov/go-fuzz.
+func FuzzRichSigWrapper(data []byte) int {
+	fuzzer := randparam.NewFuzzerWithOptions(data, randparam.MaxElements(20), randparam.NilChance(0), randparam.MaxStringLength(8))
+	fuzzOne(fuzzer)
+	return 0
+}
+
+// fuzzOne is an automatically generated function that
+// uses fzgo/randparam.Fuzzer to automatically fuzz the arguments for a
+// user-supplied function.
+func fuzzOne (fuzzer *randparam.Fuzzer) {
+
+	// Create random args for each parameter from the signature.
+	// fuzzer.Fuzz recursively fills all of obj's fields with something random.
+	// Only exported (public) fields are set, unless a '//fzgo:randparam unexported=true' directive
+	// allows unexported fields for types in the package under test.
+	var counts map[string][]int
+	fuzzer.Fuzz(&counts)
+
+	pkgname.FuzzWithOptions(counts)
+
+}
+`,
+		},
+		{
+			name: "string shapes selected by parameter names",
+			args: args{
+				funcPattern: "FuzzWithStringShapes",
+				pkgPattern:  "github.com/thepudds/fzgo/examples/richsignatures",
+				printArgs:   false,
+			},
+			wantErr: false,
+			wantOutput: `
+package richsigwrapper
+
+import "github.com/thepudds/fzgo/examples/richsignatures"
+
+import "github.com/thepudds/fzgo/randparam"
+
+// FuzzRichSigWrapper is an automatically generated wrapper that is
+// compatible with dvyukov/go-fuzz.
+func FuzzRichSigWrapper(data []byte) int {
+	fuzzer := randparam.NewFuzzer(data)
+	fuzzOne(fuzzer)
+	return 0
+}
+
+// fuzzOne is an automatically generated function that
+// uses fzgo/randparam.Fuzzer to automatically fuzz the arguments for a
+// user-supplied function.
+func fuzzOne (fuzzer *randparam.Fuzzer) {
+
+	// Create random args for each parameter from the signature.
+	// fuzzer.Fuzz recursively fills all of obj's fields with something random.
+	// Only exported (public) fields are set, unless a '//fzgo:randparam unexported=true' directive
+	// allows unexported fields for types in the package under test.
+	var utf8Text string
+	fuzzer.FuzzShape(&utf8Text, randparam.UTF8)
+
+	var numberCount string
+	fuzzer.FuzzShape(&numberCount, randparam.Number)
+
+	var c pkgname.Contact
+	fuzzer.Fuzz(&c)
+
+	pkgname.FuzzWithStringShapes(utf8Text, numberCount, c)
+
+}
+`,
+		},
+		{
+			name: "unexported fields set via fzgo:randparam",
+			args: args{
+				funcPattern: "FuzzWithUnexported",
+				pkgPattern:  "github.com/thepudds/fzgo/examples/richsignatures",
+				printArgs:   false,
+			},
+			wantErr: false,
+			wantOutput: `
+package richsigwrapper
+
+import "github.com/thepudds/fzgo/examples/richsignatures"
+
+import "github.com/thepudds/fzgo/randparam"
+
+// FuzzRichSigWrapper is an automatically generated wrapper that is
+// compatible with dvyukov/go-fuzz.
+func FuzzRichSigWrapper(data []byte) int {
+	fuzzer := randparam.NewFuzzerWithOptions(data, randparam.FillUnexported("github.com/thepudds/fzgo/examples/richsignatures"))
+	fuzzOne(fuzzer)
+	return 0
+}
+
+// fuzzOne is an automatically generated function that
+// uses fzgo/randparam.Fuzzer to automatically fuzz the arguments for a
+// user-supplied function.
+func fuzzOne (fuzzer *randparam.Fuzzer) {
+
+	// Create random args for each parameter from the signature.
+	// fuzzer.Fuzz recursively fills all of obj's fields with something random.
+	// Only exported (public) fields are set, unless a '//fzgo:randparam unexported=true' directive
+	// allows unexported fields for types in the package under test.
+	var c pkgname.Counter
+	fuzzer.Fuzz(&c)
+
+	pkgname.FuzzWithUnexported(c)
+
 }
 `,
 		},
@@ -95,7 +222,8 @@ func fuzzOne (fuzzer *randparam.Fuzzer) {
 
 	// Create random args for each parameter from the signature.
 	// fuzzer.Fuzz recursively fills all of obj's fields with something random.
-	// Only exported (public) fields can be set currently. (That is how google/go-fuzz operates).
+	// Only exported (public) fields are set, unless a '//fzgo:randparam unexported=true' directive
+	// allows unexported fields for types in the package under test.
 	var something string
 	fuzzer.Fuzz(&something)
 
@@ -142,7 +270,8 @@ func fuzzOne (fuzzer *randparam.Fuzzer) {
 
 	// Create random args for each parameter from the signature.
 	// fuzzer.Fuzz recursively fills all of obj's fields with something random.
-	// Only exported (public) fields can be set currently. (That is how google/go-fuzz operates).
+	// Only exported (public) fields are set, unless a '//fzgo:randparam unexported=true' directive
+	// allows unexported fields for types in the package under test.
 	var f fuzz.Func
 	fuzzer.Fuzz(&f)
 
@@ -181,21 +310,165 @@ func fuzzOne (fuzzer *randparam.Fuzzer) {
 
 	// Create random args for each parameter from the signature.
 	// fuzzer.Fuzz recursively fills all of obj's fields with something random.
-	// Only exported (public) fields can be set currently. (That is how google/go-fuzz operates).
+	// Only exported (public) fields are set, unless a '//fzgo:randparam unexported=true' directive
+	// allows unexported fields for types in the package under test.
 	var re string
 	fuzzer.Fuzz(&re)
-	fmt.Printf("                          re:  %#v\n", re)
+	fmt.Printf("                          re:  %s\n", randparam.Literal(re))
 
 	var input []byte
 	fuzzer.Fuzz(&input)
-	fmt.Printf("                       input:  %#v\n", input)
+	fmt.Printf("                       input:  %s\n", randparam.Literal(input))
 
 	var posix bool
 	fuzzer.Fuzz(&posix)
-	fmt.Printf("                       posix:  %#v\n", posix)
+	fmt.Printf("                       posix:  %s\n", randparam.Literal(posix))
 
 	pkgname.FuzzWithBasicTypes(re, input, posix)
 
+}
+`,
+		},
+		{
+			name: "interface with implementations registered via fzgo:impl",
+			args: args{
+				funcPattern: "FuzzWithShape",
+				pkgPattern:  "github.com/thepudds/fzgo/examples/richsignatures",
+				printArgs:   true,
+			},
+			wantErr: false,
+			wantOutput: `
+package richsigwrapper
+
+import "github.com/thepudds/fzgo/examples/richsignatures"
+
+import "github.com/thepudds/fzgo/randparam"
+
+// FuzzRichSigWrapper is an automatically generated wrapper that is
+// compatible with dvyukov/go-fuzz.
+func FuzzRichSigWrapper(data []byte) int {
+	fuzzer := randparam.NewFuzzer(data)
+	fuzzOne(fuzzer)
+	return 0
+}
+
+// fuzzOne is an automatically generated function that
+// uses fzgo/randparam.Fuzzer to automatically fuzz the arguments for a
+// user-supplied function.
+func fuzzOne (fuzzer *randparam.Fuzzer) {
+
+	// Create random args for each parameter from the signature.
+	// fuzzer.Fuzz recursively fills all of obj's fields with something random.
+	// Only exported (public) fields are set, unless a '//fzgo:randparam unexported=true' directive
+	// allows unexported fields for types in the package under test.
+	var s pkgname.Shape
+	var __fzgoLit1 string
+	switch fuzzer.Choose(3) {
+	case 0:
+		var __fzgoImpl1 pkgname.Square
+		fuzzer.Fuzz(&__fzgoImpl1)
+		s = __fzgoImpl1
+		__fzgoLit1 = randparam.Literal(__fzgoImpl1)
+	case 1:
+		var __fzgoImpl1 pkgname.Triangle
+		fuzzer.Fuzz(&__fzgoImpl1)
+		s = &__fzgoImpl1
+		__fzgoLit1 = randparam.Literal(&__fzgoImpl1)
+	case 2:
+		var __fzgoArg1_1 float64
+		fuzzer.Fuzz(&__fzgoArg1_1)
+		__fzgoImpl1, __fzgoErr1 := pkgname.NewCircle(__fzgoArg1_1)
+		if __fzgoErr1 != nil {
+			return
+		}
+		s = __fzgoImpl1
+		__fzgoLit1 = "pkgname.NewCircle(" + randparam.Literal(__fzgoArg1_1) + ")"
+	}
+	fmt.Printf("                           s:  %s\n", __fzgoLit1)
+
+	var scale int
+	fuzzer.Fuzz(&scale)
+	fmt.Printf("                       scale:  %s\n", randparam.Literal(scale))
+
+	pkgname.FuzzWithShape(s, scale)
+
+}
+`,
+		},
+		{
+			name: "func parameters with synthesized funcs",
+			args: args{
+				funcPattern: "FuzzWithFuncs",
+				pkgPattern:  "github.com/thepudds/fzgo/examples/richsignatures",
+				printArgs:   true,
+			},
+			wantErr: false,
+			wantOutput: `
+package richsigwrapper
+
+import "github.com/thepudds/fzgo/examples/richsignatures"
+
+import "github.com/thepudds/fzgo/randparam"
+
+// FuzzRichSigWrapper is an automatically generated wrapper that is
+// compatible with dvyukov/go-fuzz.
+func FuzzRichSigWrapper(data []byte) int {
+	fuzzer := randparam.NewFuzzer(data)
+	fuzzOne(fuzzer)
+	return 0
+}
+
+// fuzzOne is an automatically generated function that
+// uses fzgo/randparam.Fuzzer to automatically fuzz the arguments for a
+// user-supplied function.
+func fuzzOne (fuzzer *randparam.Fuzzer) {
+
+	// Create random args for each parameter from the signature.
+	// fuzzer.Fuzz recursively fills all of obj's fields with something random.
+	// Only exported (public) fields are set, unless a '//fzgo:randparam unexported=true' directive
+	// allows unexported fields for types in the package under test.
+	var list []int
+	fuzzer.Fuzz(&list)
+	fmt.Printf("                        list:  %s\n", randparam.Literal(list))
+
+	var less func(i int, j int) bool
+	var __fzgoResults2 []struct{ R1 bool }
+	fuzzer.Fuzz(&__fzgoResults2)
+	var __fzgoCalls2 int
+	less = func(__fzgoP1 int, __fzgoP2 int) (__fzgoR1 bool) {
+		if len(__fzgoResults2) > 0 {
+			__fzgoR := __fzgoResults2[__fzgoCalls2%len(__fzgoResults2)]
+			__fzgoR1 = __fzgoR.R1
+		}
+		__fzgoCalls2++
+		fmt.Printf("                        less   call %d: (%s) -> (%s)\n", __fzgoCalls2,
+			randparam.Literal(__fzgoP1) + ", " + randparam.Literal(__fzgoP2), randparam.Literal(__fzgoR1))
+		return
+	}
+	fmt.Printf("                        less:  %s\n", "func() func(int, int) (r1 bool) { var results []struct{ R1 bool } = " + randparam.Literal(__fzgoResults2) + "; calls := 0; return func(int, int) (r1 bool) { if len(results) > 0 { r := results[calls%len(results)]; r1 = r.R1 }; calls++; return } }()")
+
+	var visit func(n int) error
+	var __fzgoCalls3 int
+	visit = func(__fzgoP1 int) (__fzgoR1 error) {
+		__fzgoCalls3++
+		fmt.Printf("                       visit   call %d: (%s) -> (%s)\n", __fzgoCalls3,
+			randparam.Literal(__fzgoP1), randparam.Literal(__fzgoR1))
+		return
+	}
+	fmt.Printf("                       visit:  %s\n", "func(int) (r1 error) { return }")
+
+	var done func()
+	var __fzgoCalls4 int
+	done = func() {
+		__fzgoCalls4++
+		fmt.Printf("                        done   call %d: (%s) -> (%s)\n", __fzgoCalls4,
+			"", "")
+		return
+	}
+	fmt.Printf("                        done:  %s\n", "func() { return }")
+
+	pkgname.FuzzWithFuncs(list, less, visit, done)
+
 }
 `,
 		},
@@ -24,20 +24,22 @@ var ErrGoTestFailed = errors.New("go test failed")
 // The inputs used are all deterministic (without generating new fuzzing-based inputs).
 // The names used with t.Run mean a 'fzgo test -run=TestCorpus/<corpus-file-name>' works.
 // One way to see the file names or otherwise verify execution is to run 'fzgo test -v <pkg>'.
-func VerifyCorpus(function Func, workDir string, run string, verbose bool) error {
+// If coverProfile is not empty, a coverage profile for the user's package
+// (rather than for our synthetic test package) is written to coverProfile.
+func VerifyCorpus(function Func, workDir string, run string, coverProfile string, verbose bool) error {
 	corpusDir := filepath.Join(workDir, "corpus")
-	return verifyFiles(function, corpusDir, run, "TestCorpus", verbose)
+	return verifyFiles(function, corpusDir, run, "TestCorpus", coverProfile, verbose)
 }
 
 // VerifyCrashers is similar to VerifyCorpus, but runs the crashers. It
 // can be useful to pass -v to what is causing a crash, such as 'fzgo test -v -fuzz=. -run=TestCrashers'
-func VerifyCrashers(function Func, workDir string, run string, verbose bool) error {
+func VerifyCrashers(function Func, workDir string, run string, coverProfile string, verbose bool) error {
 	crashersDir := filepath.Join(workDir, "crashers")
-	return verifyFiles(function, crashersDir, run, "TestCrashers", verbose)
+	return verifyFiles(function, crashersDir, run, "TestCrashers", coverProfile, verbose)
 }
 
 // verifyFiles implements the heart of VerifyCorpus and VerifyCrashers
-func verifyFiles(function Func, filesDir string, run string, testFunc string, verbose bool) error {
+func verifyFiles(function Func, filesDir string, run string, testFunc string, coverProfile string, verbose bool) error {
 	report := func(err error) error {
 		if err == ErrGoTestFailed {
 			return err
@@ -98,42 +100,35 @@ func verifyFiles(function Func, filesDir string, run string, testFunc string, ve
 		return nil
 	}
 
-	// check if we have a plain data []byte signature, vs. a rich signature
-	plain, err := IsPlainSig(function.TypesFunc)
+	// if both -v and -run is set (presumaly to some corpus file),
+	// as a convinience also print the deserialized arguments for a rich signature.
+	printArgs := verbose && run != ""
+	target, err := newTarget(function, printArgs)
 	if err != nil {
 		return report(err)
 	}
+	// By the time we leave our current function, we are done with any temp dir
+	// for a rich signature wrapper, so delete via a defer.
+	defer target.removeTemp()
 
-	var target Target
-	if plain {
-		// create our initial target struct using the actual func supplied by the user.
-		target = Target{UserFunc: function}
+	var pkgPath, funcName string
+	if target.hasWrapper {
+		pkgPath = target.wrapperFunc.PkgPath
+		funcName = target.wrapperFunc.FuncName
 	} else {
-		info("detected rich signature for %v.%v", function.PkgName, function.FuncName)
-		// create a wrapper function to handle the rich signature.
-		// if both -v and -run is set (presumaly to some corpus file),
-		// as a convinience also print the deserialized arguments
-		printArgs := verbose && run != ""
-
-		target, err = CreateRichSigWrapper(function, printArgs)
-		if err != nil {
-			return report(err)
-		}
-		// CreateRichSigWrapper was successful, which means it populated the temp dir with the wrapper func.
-		// By the time we leave our current function, we are done with the temp dir
-		// that CreateRichSigWrapper created, so delete via a defer.
-		// (We can't delete it immediately because we haven't yet run go-fuzz-build on it).
-		defer os.RemoveAll(target.wrapperTempDir)
-
-		// TODO: consider moving gopath setup into richsig, store on Target
-		// also set up a second entry in GOPATH to include our temporary directory containing the rich sig wrapper.
+		pkgPath = target.UserFunc.buildPkgPath()
+		funcName = target.UserFunc.FuncName
 	}
 
-	// create temp dir to work in.
-	// this is where we will create a corpus test wrapper suitable for running a normal 'go test'.
-	tempDir, err := ioutil.TempDir("", "fzgo-verify-corpus")
+	// create our corpus test wrapper suitable for running a normal 'go test'.
+	vals := map[string]string{"pkgPath": pkgPath, "filesDir": filesDir, "testFunc": testFunc, "funcName": funcName, "minSuffix": minSuffix}
+	buf := new(bytes.Buffer)
+	if err := corpusTestSrc.Execute(buf, vals); err != nil {
+		return report(fmt.Errorf("could not execute template: %v", err))
+	}
+	tempDir, err := createHarness(target, "corpustest", map[string][]byte{"corpus_test.go": buf.Bytes()})
 	if err != nil {
-		return report(fmt.Errorf("failed to create temp dir: %v", err))
+		return report(err)
 	}
 	defer os.RemoveAll(tempDir)
 
@@ -148,37 +143,6 @@ func verifyFiles(function Func, filesDir string, run string, testFunc string, ve
 	}
 	defer func() { os.Chdir(oldWd) }()
 
-	var pkgPath, funcName string
-	var env []string
-	if target.hasWrapper {
-		pkgPath = target.wrapperFunc.PkgPath
-		funcName = target.wrapperFunc.FuncName
-		env = target.wrapperEnv
-	} else {
-		pkgPath = target.UserFunc.PkgPath
-		funcName = target.UserFunc.FuncName
-		env = nil
-	}
-
-	// write out temporary corpus_test.go file
-	vals := map[string]string{"pkgPath": pkgPath, "filesDir": filesDir, "testFunc": testFunc, "funcName": funcName}
-	buf := new(bytes.Buffer)
-	if err := corpusTestSrc.Execute(buf, vals); err != nil {
-		report(fmt.Errorf("could not execute template: %v", err))
-	}
-	// return buf.Bytes()
-	// src := fmt.Sprintf(corpusTestSrc,
-	// 	pkgPath,
-	// 	filesDir,
-	// 	testFunc,
-	// 	testFunc,
-	// 	funcName)
-	// err = ioutil.WriteFile(filepath.Join(tempDir, "corpus_test.go"), []byte(src), 0700)
-	err = ioutil.WriteFile(filepath.Join(tempDir, "corpus_test.go"), buf.Bytes(), 0700)
-	if err != nil {
-		return report(fmt.Errorf("failed to create temporary corpus_test.go: %v", err))
-	}
-
 	// actually run 'go test .' now!
 	runArgs := []string{
 		"test",
@@ -195,8 +159,17 @@ func verifyFiles(function Func, filesDir string, run string, testFunc string, ve
 	if verbose {
 		runArgs = append(runArgs, "-v")
 	}
+	if coverProfile != "" {
+		// measure coverage of the user's package, rather than of our corpustest package
+		// or of any rich signature wrapper.
+		runArgs = append(runArgs,
+			"-covermode=count",
+			"-coverpkg="+function.PkgPath,
+			"-coverprofile="+coverProfile,
+		)
+	}
 
-	err = ExecGo(runArgs, env)
+	err = ExecGo(runArgs, target.wrapperEnv)
 	if err != nil {
 		// we will guess for now at least that this was due to a test failure.
 		// the 'go' command should have already printed the details on the failure.
@@ -209,13 +182,16 @@ func verifyFiles(function Func, filesDir string, run string, testFunc string, ve
 
 // corpusTestTemplate provides a test function that runs
 // all of the files in a corpus directory as subtests.
-// This template needs three string variables to be supplied:
+// This template needs the following string variables to be supplied:
 //   1. an import path to the fuzzer, such as:
 //        github.com/dvyukov/go-fuzz-corpus/png
 //   2. the directory path to the corpus, such as:
 //        /tmp/gopath/src/github.com/dvyukov/go-fuzz-corpus/png/testdata/fuzz/png.Fuzz/corpus/
 //   3. the fuzz function name, such as:
 //        Fuzz
+//   4. the test function name, which is TestCrashers when running crashers.
+//   5. the suffix of minimized crashers, which TestCrashers skips:
+//        .min
 var corpusTestSrc = template.Must(template.New("CorpusTest").Parse(`
 package corpustest
 
@@ -247,8 +223,10 @@ func {{.testFunc}}(t *testing.T) {
 		}
 
 		{{if eq .testFunc "TestCrashers"}}
-		// exclude auxillary files that reside in the crashers directory.
-		if strings.HasSuffix(file.Name(), ".output") || strings.HasSuffix(file.Name(), ".quoted") {
+		// exclude auxillary files that reside in the crashers directory,
+		// including minimized crashers written by 'fzgo minimize'.
+		if strings.HasSuffix(file.Name(), ".output") || strings.HasSuffix(file.Name(), ".quoted") ||
+			strings.HasSuffix(file.Name(), "{{.minSuffix}}") {
 			continue
 		}
 		{{end}}
@@ -0,0 +1,62 @@
+package fuzz
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestCorpusTestSkipsAuxiliaryFiles(t *testing.T) {
+	modDir, err := ioutil.TempDir("", "fzgo-corpustest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(modDir)
+
+	// the fuzz function panics if it is run with anything other than the crasher itself.
+	crashersDir := filepath.Join(modDir, "crashers")
+	files := map[string]string{
+		"go.mod":                   "module example.com/fz\n",
+		"fz.go":                    "package fz\n\nfunc Fuzz(data []byte) int {\n\tif string(data) != \"crash\" {\n\t\tpanic(\"ran \" + string(data))\n\t}\n\treturn 0\n}\n",
+		"crashers/aaa":             "crash",
+		"crashers/aaa.output":      "output",
+		"crashers/aaa.quoted":      "quoted",
+		"crashers/aaa" + minSuffix: "minimized",
+	}
+	for name, src := range files {
+		path := filepath.Join(modDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vals := map[string]string{"pkgPath": "example.com/fz", "filesDir": crashersDir, "testFunc": "TestCrashers", "funcName": "Fuzz", "minSuffix": minSuffix}
+	buf := new(bytes.Buffer)
+	if err := corpusTestSrc.Execute(buf, vals); err != nil {
+		t.Fatalf("could not execute template: %v", err)
+	}
+	testDir := filepath.Join(modDir, "corpustest")
+	if err := os.MkdirAll(testDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(testDir, "corpus_test.go"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "test", "-run=TestCrashers", "-v", ".")
+	cmd.Dir = testDir
+	cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOFLAGS=-mod=mod")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go test failed: %v\n%s", err, out)
+	}
+	if !bytes.Contains(out, []byte("--- PASS: TestCrashers/aaa ")) {
+		t.Errorf("go test did not run crasher aaa:\n%s", out)
+	}
+}
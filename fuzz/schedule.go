@@ -0,0 +1,405 @@
+package fuzz
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// rebalanceInterval is how often Schedule reconsiders how many workers each target has.
+const rebalanceInterval = 30 * time.Second
+
+// goFuzzCmd is the go-fuzz command that Schedule runs, which tests replace with a fake.
+var goFuzzCmd = "go-fuzz"
+
+// Schedule fuzzes multiple go-fuzz targets concurrently, as an alternative to calling Start
+// for one target at a time. workDirs contains the workDir for each target.
+//
+// For each target, Schedule starts a go-fuzz coordinator process that stays alive for the duration
+// of the run and owns the target's corpus in its workDir. The -parallel budget is then split
+// across the targets by starting go-fuzz worker processes (each with -procs=1) that connect to
+// a target's coordinator. Each target always has at least one worker, which means opts.Parallel
+// must be at least the number of targets. (A caller with a smaller budget can instead fuzz the
+// targets one at a time using Start). Periodically the remaining workers are shifted toward
+// the targets that are still finding new coverage according to the go-fuzz stats. Only the
+// worker processes are started and stopped when rebalancing, so a target does not lose its
+// state when it has fewer workers.
+//
+// An opts.MaxDuration of 0 means fuzzing continues until fzgo is interrupted.
+// The output of the go-fuzz processes is prefixed with the target's name, or is emitted
+// as output Events for the target if Events are enabled.
+func Schedule(targets []Target, workDirs []string, opts StartOptions) error {
+	report := func(err error) error {
+		return fmt.Errorf("scheduling fuzzing: %v", err)
+	}
+	if len(targets) != len(workDirs) {
+		return report(fmt.Errorf("mismatched number of targets (%d) and workDirs (%d)", len(targets), len(workDirs)))
+	}
+	if opts.Parallel < len(targets) {
+		return report(fmt.Errorf("-parallel=%d is less than the number of targets (%d), which each need a worker", opts.Parallel, len(targets)))
+	}
+	if opts.FuncTimeout < 1*time.Second {
+		return fmt.Errorf("minimum allowed func timeout value is 1 second")
+	}
+	if err := checkGoFuzz(); err != nil {
+		return report(err)
+	}
+
+	warnUnusedDicts(GoFuzz, opts)
+	opts, checkCrashers := eventOptions(opts, targets, workDirs)
+	started := time.Now()
+
+	var sts []*scheduledTarget
+	for i := range targets {
+		if targets[i].engine != GoFuzz && targets[i].engine != "" {
+			return report(fmt.Errorf("%s: only the go-fuzz engine supports concurrent scheduling", targets[i].FuzzName()))
+		}
+		zipPath, err := targets[i].artifactPath(opts.Verbose)
+		if err != nil {
+			return report(fmt.Errorf("zip path failed: %v", err))
+		}
+		sts = append(sts, &scheduledTarget{
+			name:     targets[i].FuzzName(),
+			function: targets[i].UserFunc,
+			zipPath:  zipPath,
+			workDir:  workDirs[i],
+			opts:     opts,
+		})
+	}
+
+	rebalanced := func(counts []int) {
+		if opts.Verbose {
+			info("worker allocation: %v", counts)
+		}
+	}
+	err := runSchedule(sts, opts, rebalanceInterval, rebalanced)
+
+	// the go-fuzz processes have stopped, so we can report on our final state.
+	checkCrashers()
+	for _, st := range sts {
+		if st.coordinator != nil {
+			e := FuncEvent(ActionStop, st.function)
+			e.Elapsed = time.Since(started).Seconds()
+			EmitEvent(e)
+		}
+	}
+	if err != nil {
+		return report(err)
+	}
+	return nil
+}
+
+// runSchedule starts a coordinator for each target, and then splits the opts.Parallel workers
+// across the targets, rebalancing the workers every interval. rebalanced is called with
+// the number of workers for each target after each rebalancing. runSchedule returns once
+// opts.MaxDuration has elapsed or a coordinator exits, after stopping all of the processes.
+func runSchedule(sts []*scheduledTarget, opts StartOptions, interval time.Duration, rebalanced func(counts []int)) error {
+	defer func() {
+		for _, st := range sts {
+			st.stop()
+		}
+	}()
+
+	// exited receives the error (or nil) from any coordinator that exits on its own.
+	exited := make(chan error, len(sts))
+	for _, st := range sts {
+		info("starting fuzzing %s", st.name)
+		info("output in %s", st.workDir)
+		if err := st.startCoordinator(exited); err != nil {
+			return err
+		}
+		EmitEvent(FuncEvent(ActionStart, st.function))
+	}
+
+	// start with an even split of our workers.
+	progressing := make([]bool, len(sts))
+	rebalance := func() error {
+		counts := allocateWorkers(opts.Parallel, progressing)
+		for i, st := range sts {
+			if err := st.setWorkers(counts[i]); err != nil {
+				return err
+			}
+		}
+		rebalanced(counts)
+		return nil
+	}
+	if err := rebalance(); err != nil {
+		return err
+	}
+
+	var timeout <-chan time.Time
+	if opts.MaxDuration > 0 {
+		timeout = time.After(opts.MaxDuration)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-timeout:
+			return nil
+		case err := <-exited:
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("go-fuzz coordinator exited unexpectedly")
+		case <-ticker.C:
+			for i, st := range sts {
+				progressing[i] = st.progressed()
+			}
+			if err := rebalance(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// allocateWorkers splits parallel workers across targets, where parallel must be at least
+// the number of targets. Each target gets at least one worker, and any remaining workers are
+// split evenly across the targets that are progressing, or across all targets if none are progressing.
+func allocateWorkers(parallel int, progressing []bool) []int {
+	counts := make([]int, len(progressing))
+	var favored []int
+	for i := range progressing {
+		counts[i] = 1
+		if progressing[i] {
+			favored = append(favored, i)
+		}
+	}
+	if len(favored) == 0 {
+		for i := range progressing {
+			favored = append(favored, i)
+		}
+	}
+	for extra := parallel - len(progressing); extra > 0; extra-- {
+		i := favored[extra%len(favored)]
+		counts[i]++
+	}
+	return counts
+}
+
+// scheduledTarget tracks the go-fuzz processes for one target.
+type scheduledTarget struct {
+	name     string
+	function Func
+	zipPath  string
+	workDir  string
+	opts     StartOptions
+
+	addr        string
+	coordinator *proc
+	workers     []*proc
+
+	mu        sync.Mutex
+	cover     int // latest cover from the go-fuzz stats
+	corpus    int // latest corpus size from the go-fuzz stats
+	lastCover int // cover as of the last call to progressed
+	lastCorp  int // corpus size as of the last call to progressed
+}
+
+// startCoordinator starts a go-fuzz coordinator and waits for it to start listening.
+func (st *scheduledTarget) startCoordinator(exited chan<- error) error {
+	addr, err := freeAddr()
+	if err != nil {
+		return err
+	}
+	st.addr = addr
+
+	// to support experimentation, initial args for go-fuzz are
+	// populated by the optional FZGOFLAGSFUZZ env var
+	// (or an empty slice if FZGOFLAGSFUZZ is not set).
+	args := fzgoEnvFlags("FZGOFLAGSFUZZ")
+	args = append(args,
+		fmt.Sprintf("-workdir=%s", st.workDir),
+		fmt.Sprintf("-coordinator=%s", st.addr),
+		fmt.Sprintf("-v=%d", st.verboseLevel()),
+	)
+	p, err := st.start(args, true)
+	if err != nil {
+		return err
+	}
+	st.coordinator = p
+	go func() {
+		<-p.done
+		exited <- p.err
+	}()
+
+	// wait for the coordinator to be ready before any workers try to connect.
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", st.addr)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("go-fuzz coordinator for %s did not start listening on %s: %v", st.name, st.addr, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// setWorkers starts or stops go-fuzz worker processes so that the target has n workers.
+func (st *scheduledTarget) setWorkers(n int) error {
+	// drop any workers that have exited on their own so that they are replaced.
+	live := st.workers[:0]
+	for _, p := range st.workers {
+		select {
+		case <-p.done:
+		default:
+			live = append(live, p)
+		}
+	}
+	st.workers = live
+
+	for len(st.workers) < n {
+		args := fzgoEnvFlags("FZGOFLAGSFUZZ")
+		args = append(args,
+			fmt.Sprintf("-bin=%s", st.zipPath),
+			fmt.Sprintf("-worker=%s", st.addr),
+			"-procs=1",
+			fmt.Sprintf("-timeout=%d", int(st.opts.FuncTimeout.Seconds())), // this is not total run time
+			fmt.Sprintf("-v=%d", st.verboseLevel()),
+		)
+		p, err := st.start(args, false)
+		if err != nil {
+			return err
+		}
+		st.workers = append(st.workers, p)
+	}
+	if len(st.workers) > n {
+		stopProcs(st.workers[n:])
+		st.workers = st.workers[:n]
+	}
+	return nil
+}
+
+// stop stops the workers, and then the coordinator.
+func (st *scheduledTarget) stop() {
+	stopProcs(st.workers)
+	st.workers = nil
+	if st.coordinator != nil {
+		stopProcs([]*proc{st.coordinator})
+	}
+}
+
+// progressed reports whether the target found new coverage or new corpus entries
+// since the prior call to progressed.
+func (st *scheduledTarget) progressed() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	result := st.cover > st.lastCover || st.corpus > st.lastCorp
+	st.lastCover, st.lastCorp = st.cover, st.corpus
+	return result
+}
+
+func (st *scheduledTarget) verboseLevel() int {
+	if st.opts.Verbose {
+		return 1
+	}
+	return 0
+}
+
+// proc is a running go-fuzz process.
+type proc struct {
+	cmd  *exec.Cmd
+	done chan struct{} // closed when the process has exited
+	err  error         // the result of Wait, valid once done is closed
+}
+
+// start starts go-fuzz with args. The output is passed through to our stderr (or opts.Output) with
+// each line prefixed by the target's name, and if parse is true, the go-fuzz stats are recorded.
+func (st *scheduledTarget) start(args []string, parse bool) (*proc, error) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(goFuzzCmd, args...)
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	err = cmd.Start()
+	// the child has its own copy of our pipe's write end. closing ours means
+	// our reader sees EOF once the child exits.
+	pw.Close()
+	if err != nil {
+		pr.Close()
+		return nil, fmt.Errorf("exec go-fuzz error: %v", err)
+	}
+
+	p := &proc{cmd: cmd, done: make(chan struct{})}
+	go func() {
+		defer pr.Close()
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if parse {
+				if stats, ok := ParseStats(line); ok {
+					st.record(stats)
+					if st.opts.Output != nil || EventsEnabled() {
+						continue
+					}
+				}
+			}
+			if EventsEnabled() {
+				e := FuncEvent(ActionOutput, st.function)
+				e.Output = line + "\n"
+				EmitEvent(e)
+				continue
+			}
+			w := st.opts.Output
+			if w == nil {
+				w = os.Stderr
+			}
+			fmt.Fprintf(w, "%s: %s\n", st.name, line)
+		}
+	}()
+	go func() {
+		p.err = cmd.Wait()
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// record records the corpus size and cover from the go-fuzz stats.
+func (st *scheduledTarget) record(stats Stats) {
+	stats.FuzzName = st.name
+	st.mu.Lock()
+	st.corpus, st.cover = stats.Corpus, stats.Cover
+	st.mu.Unlock()
+	if st.opts.OnStats != nil {
+		st.opts.OnStats(stats)
+	}
+}
+
+// stopProcs interrupts processes, killing any that do not exit promptly,
+// and waits for them to exit.
+func stopProcs(procs []*proc) {
+	for _, p := range procs {
+		err := p.cmd.Process.Signal(os.Interrupt)
+		if err != nil {
+			// os.Interrupt expected to fail in some cases (e.g., not implemented on Windows)
+			_ = p.cmd.Process.Kill()
+		}
+	}
+	for _, p := range procs {
+		select {
+		case <-p.done:
+		case <-time.After(5 * time.Second):
+			_ = p.cmd.Process.Kill()
+			<-p.done
+		}
+	}
+}
+
+// freeAddr returns a local address with a port that is currently available.
+func freeAddr() (string, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer ln.Close()
+	return ln.Addr().String(), nil
+}
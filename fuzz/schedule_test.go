@@ -0,0 +1,216 @@
+package fuzz
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary act as a fake go-fuzz for the scheduler tests.
+func TestMain(m *testing.M) {
+	if os.Getenv("FZGO_TEST_FAKE_GOFUZZ") == "1" {
+		os.Exit(fakeGoFuzz(os.Args[1:]))
+	}
+	os.Exit(m.Run())
+}
+
+// fakeGoFuzz is a go-fuzz that does not fuzz anything. As a coordinator, it listens on its
+// address and prints stats lines, with the cover increasing if the base name of its workdir
+// is 'progress', and exiting with an error shortly after starting if the base name is 'exit'.
+// As a worker, it waits to be interrupted.
+func fakeGoFuzz(args []string) int {
+	fs := flag.NewFlagSet("go-fuzz", flag.ContinueOnError)
+	workDir := fs.String("workdir", "", "")
+	coordinator := fs.String("coordinator", "", "")
+	fs.String("bin", "", "")
+	fs.String("worker", "", "")
+	fs.Int("procs", 0, "")
+	fs.Int("timeout", 0, "")
+	fs.Int("v", 0, "")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *coordinator == "" {
+		select {}
+	}
+	ln, err := net.Listen("tcp", *coordinator)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer ln.Close()
+	mode := filepath.Base(*workDir)
+	cover := 10
+	for i := 0; ; i++ {
+		if mode == "exit" && i == 5 {
+			return 1
+		}
+		if mode == "progress" {
+			cover++
+		}
+		fmt.Printf("workers: 1, corpus: 5 (0s ago), crashers: 0, restarts: 1/0, execs: 0 (0/sec), cover: %d, uptime: 1s\n", cover)
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// useFakeGoFuzz sets up Schedule to run fakeGoFuzz via the test binary,
+// returning a func to restore the real go-fuzz.
+func useFakeGoFuzz() func() {
+	oldCmd := goFuzzCmd
+	goFuzzCmd = os.Args[0]
+	os.Setenv("FZGO_TEST_FAKE_GOFUZZ", "1")
+	return func() {
+		goFuzzCmd = oldCmd
+		os.Unsetenv("FZGO_TEST_FAKE_GOFUZZ")
+	}
+}
+
+func newFakeScheduledTargets(dir string, opts StartOptions, names ...string) []*scheduledTarget {
+	var sts []*scheduledTarget
+	for _, name := range names {
+		sts = append(sts, &scheduledTarget{name: name, workDir: filepath.Join(dir, name), opts: opts})
+	}
+	return sts
+}
+
+// procExited reports whether a process has exited.
+func procExited(p *proc) bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestRunScheduleRebalances(t *testing.T) {
+	defer useFakeGoFuzz()()
+	dir, err := ioutil.TempDir("", "fzgo-schedule-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	opts := StartOptions{Parallel: 4, FuncTimeout: 10 * time.Second, MaxDuration: 1500 * time.Millisecond, Output: ioutil.Discard}
+	sts := newFakeScheduledTargets(dir, opts, "progress", "stuck")
+
+	var allocs [][]int
+	var procs []*proc
+	rebalanced := func(counts []int) {
+		allocs = append(allocs, counts)
+		running := 0
+		for i, st := range sts {
+			if len(st.workers) != counts[i] {
+				t.Errorf("%s has %d workers after rebalancing, want %d", st.name, len(st.workers), counts[i])
+			}
+			for _, p := range st.workers {
+				if !procExited(p) {
+					running++
+				}
+			}
+			procs = append(procs, st.workers...)
+		}
+		if running > opts.Parallel {
+			t.Errorf("%d workers running, more than -parallel=%d", running, opts.Parallel)
+		}
+	}
+	if err := runSchedule(sts, opts, 200*time.Millisecond, rebalanced); err != nil {
+		t.Fatalf("runSchedule() failed: %v", err)
+	}
+
+	// both targets start with an even split, and then the workers shift toward
+	// the target that keeps finding new coverage.
+	if len(allocs) < 3 {
+		t.Fatalf("rebalanced %d times, want at least 3", len(allocs))
+	}
+	if want := []int{2, 2}; !reflect.DeepEqual(allocs[0], want) {
+		t.Errorf("initial allocation = %v, want %v", allocs[0], want)
+	}
+	if want := []int{3, 1}; !reflect.DeepEqual(allocs[len(allocs)-1], want) {
+		t.Errorf("final allocation = %v, want %v (all allocations %v)", allocs[len(allocs)-1], want, allocs)
+	}
+
+	// all of the processes are stopped once runSchedule returns.
+	for _, st := range sts {
+		procs = append(procs, st.coordinator)
+		if len(st.workers) != 0 {
+			t.Errorf("%s has %d workers after stopping", st.name, len(st.workers))
+		}
+	}
+	for _, p := range procs {
+		if !procExited(p) {
+			t.Errorf("process %v still running after runSchedule returned", p.cmd.Args)
+		}
+	}
+}
+
+func TestRunScheduleCoordinatorExit(t *testing.T) {
+	defer useFakeGoFuzz()()
+	dir, err := ioutil.TempDir("", "fzgo-schedule-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	opts := StartOptions{Parallel: 3, FuncTimeout: 10 * time.Second, MaxDuration: time.Minute, Output: ioutil.Discard}
+	sts := newFakeScheduledTargets(dir, opts, "exit", "stuck")
+
+	var procs []*proc
+	rebalanced := func(counts []int) {
+		for _, st := range sts {
+			procs = append(procs, st.workers...)
+		}
+	}
+	start := time.Now()
+	err = runSchedule(sts, opts, 100*time.Millisecond, rebalanced)
+	if err == nil || !strings.Contains(err.Error(), "exit status 1") {
+		t.Errorf("runSchedule() error = %v, want the coordinator's exit status", err)
+	}
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Errorf("runSchedule() took %v to notice the coordinator exited", elapsed)
+	}
+	for _, st := range sts {
+		procs = append(procs, st.coordinator)
+	}
+	for _, p := range procs {
+		if !procExited(p) {
+			t.Errorf("process %v still running after runSchedule returned", p.cmd.Args)
+		}
+	}
+}
+
+func TestAllocateWorkers(t *testing.T) {
+	tests := []struct {
+		name        string
+		parallel    int
+		progressing []bool
+		want        []int
+	}{
+		{"even split when none progressing", 6, []bool{false, false, false}, []int{2, 2, 2}},
+		{"even split when all progressing", 4, []bool{true, true}, []int{2, 2}},
+		{"extra workers to progressing target", 8, []bool{false, true, false}, []int{1, 6, 1}},
+		{"extra workers split across progressing targets", 7, []bool{true, false, true}, []int{3, 1, 3}},
+		{"one worker each", 3, []bool{true, false, false}, []int{1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := allocateWorkers(tt.parallel, tt.progressing)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allocateWorkers(%d, %v) = %v, want %v", tt.parallel, tt.progressing, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleRejectsSmallBudget(t *testing.T) {
+	opts := StartOptions{Parallel: 2, FuncTimeout: 10 * time.Second}
+	err := Schedule(make([]Target, 3), []string{"a", "b", "c"}, opts)
+	if err == nil || !strings.Contains(err.Error(), "-parallel=2") {
+		t.Errorf("Schedule() error = %v, want an error for -parallel less than the number of targets", err)
+	}
+}
@@ -0,0 +1,128 @@
+package fuzz
+
+import (
+	"fmt"
+	"go/types"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/thepudds/fzgo/randparam"
+)
+
+// shapePrefixes are the prefixes of parameter names that select a randparam.Shape for the strings
+// filled in for a parameter of a rich signature, such as 'utf8Input string' or 'identNames []string'.
+// The strings within a struct instead take their shape from a struct tag, such as `fzgo:"ascii"`.
+var shapePrefixes = []struct {
+	prefix string
+	shape  string
+}{
+	{"utf8", "randparam.UTF8"},
+	{"ascii", "randparam.ASCII"},
+	{"ident", "randparam.Ident"},
+	{"number", "randparam.Number"},
+}
+
+// paramShape returns the Go source for the randparam.Shape selected by the name of a parameter,
+// or "" if there is none. A prefix selects a shape if it is the whole name, or if it is followed
+// by an upper case letter, digit or underscore, so 'utf8', 'utf8Body' and 'number_2' have shapes,
+// but 'numbers' and 'identity' do not.
+func paramShape(name string) string {
+	for _, p := range shapePrefixes {
+		if !strings.HasPrefix(name, p.prefix) {
+			continue
+		}
+		rest := name[len(p.prefix):]
+		r, _ := utf8.DecodeRuneInString(rest)
+		if rest == "" || unicode.IsUpper(r) || unicode.IsDigit(r) || r == '_' {
+			return p.shape
+		}
+	}
+	return ""
+}
+
+// encodedShape returns the Go source for the randparam.Shape of the strings a rich signature wrapper
+// fills for parameter v, or "" if there is none.
+func encodedShape(v *types.Var) string {
+	if _, ok := InterfaceImpl[types.TypeString(v.Type(), externalQualifier)]; ok {
+		return ""
+	}
+	if _, ok := v.Type().Underlying().(*types.Signature); ok {
+		return ""
+	}
+	return paramShape(v.Name())
+}
+
+// checkShapeTags returns an error if a struct filled in for a parameter of sig has a field with
+// an invalid 'fzgo' struct tag, such as a regexp that does not compile, which otherwise would
+// panic while fuzzing. This includes structs nested within the parameter types, the results
+// of func parameters, and the parameters of any implementations in impls used for interface parameters.
+func checkShapeTags(sig *types.Signature, impls []Impl) error {
+	seen := make(map[types.Type]bool)
+	for i := 0; i < sig.Params().Len(); i++ {
+		v := sig.Params().At(i)
+		filled := []types.Type{v.Type()}
+		for _, impl := range implsFor(v.Type(), impls) {
+			if impl.Factory == nil {
+				filled = append(filled, impl.Type)
+				continue
+			}
+			params := impl.Factory.Type().(*types.Signature).Params()
+			for k := 0; k < params.Len(); k++ {
+				filled = append(filled, params.At(k).Type())
+			}
+		}
+		for _, t := range filled {
+			if err := checkTypeShapeTags(t, seen); err != nil {
+				return fmt.Errorf("parameter %s: %v", v.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkTypeShapeTags returns an error for the first struct field with an invalid 'fzgo' struct tag within t.
+// seen records the types already checked.
+func checkTypeShapeTags(t types.Type, seen map[types.Type]bool) error {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	switch t := t.(type) {
+	case *types.Named:
+		return checkTypeShapeTags(t.Underlying(), seen)
+	case *types.Pointer:
+		return checkTypeShapeTags(t.Elem(), seen)
+	case *types.Slice:
+		return checkTypeShapeTags(t.Elem(), seen)
+	case *types.Array:
+		return checkTypeShapeTags(t.Elem(), seen)
+	case *types.Chan:
+		return checkTypeShapeTags(t.Elem(), seen)
+	case *types.Map:
+		if err := checkTypeShapeTags(t.Key(), seen); err != nil {
+			return err
+		}
+		return checkTypeShapeTags(t.Elem(), seen)
+	case *types.Signature:
+		// a synthesized func value returns filled in results.
+		for i := 0; i < t.Results().Len(); i++ {
+			if err := checkTypeShapeTags(t.Results().At(i).Type(), seen); err != nil {
+				return err
+			}
+		}
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			field := t.Field(i)
+			shape := randparam.Shape(reflect.StructTag(t.Tag(i)).Get("fzgo"))
+			if err := shape.Validate(); err != nil {
+				return fmt.Errorf("field %s: %v", field.Name(), err)
+			}
+			if err := checkTypeShapeTags(field.Type(), seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package fuzz
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+func TestParamShape(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"utf8", "randparam.UTF8"},
+		{"utf8Body", "randparam.UTF8"},
+		{"asciiKeys", "randparam.ASCII"},
+		{"ident_2", "randparam.Ident"},
+		{"number1", "randparam.Number"},
+		{"numbers", ""},
+		{"identity", ""},
+		{"input", ""},
+		{"Utf8Body", ""},
+	}
+	for _, tt := range tests {
+		if got := paramShape(tt.name); got != tt.want {
+			t.Errorf("paramShape(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckShapeTags(t *testing.T) {
+	src := `package p
+
+type Good struct {
+	Name  string ` + "`fzgo:\"ident\"`" + `
+	Email string ` + "`fzgo:\"regexp=^[a-z]+@[a-z]+\\\\.com$\"`" + `
+	Next  *Good
+}
+
+type Bad struct {
+	Name string ` + "`fzgo:\"regexp=[a-z\"`" + `
+}
+
+type Unknown struct {
+	Name string ` + "`fzgo:\"hex\"`" + `
+}
+
+func FuzzGood(g Good, list []*Good, m map[string]Good) {}
+
+func FuzzBad(n int, b Bad) {}
+
+func FuzzNested(m map[string][]struct{ B *Bad }) {}
+
+func FuzzFunc(f func(int) Bad) {}
+
+func FuzzUnknown(u Unknown) {}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	conf := types.Config{Importer: nil}
+	pkg, err := conf.Check("p", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		wantErr string
+	}{
+		{"FuzzGood", ""},
+		{"FuzzBad", "parameter b: field Name: invalid shape"},
+		{"FuzzNested", "parameter m: field Name: invalid shape"},
+		{"FuzzFunc", "parameter f: field Name: invalid shape"},
+		{"FuzzUnknown", `parameter u: field Name: unknown shape "hex"`},
+	}
+	for _, tt := range tests {
+		sig := pkg.Scope().Lookup(tt.name).Type().(*types.Signature)
+		err := checkShapeTags(sig, nil)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("checkShapeTags(%s) error = %v, want nil", tt.name, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("checkShapeTags(%s) error = %v, want error containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
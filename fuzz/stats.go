@@ -0,0 +1,157 @@
+package fuzz
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Stats contains the periodic status reported by a fuzzing engine while fuzzing.
+// Fields that a particular engine does not report are left as zero.
+type Stats struct {
+	FuzzName    string  // the target's '<pkg>.<OrigFuzzFunc>' name, such as 'fmt.FuzzFmt'
+	Workers     int     // number of fuzzing processes
+	Corpus      int     // number of inputs in the corpus
+	Crashers    int     // number of unique crashers found
+	Execs       int64   // total executions of the fuzz function
+	ExecsPerSec int64   // recent executions per second
+	Cover       int     // coverage as reported by the engine (e.g., number of covered edges or counters)
+	Uptime      float64 // seconds since fuzzing started
+}
+
+var (
+	// goFuzzStatsRe matches a go-fuzz status line such as:
+	//   2019/01/01 12:00:00 workers: 8, corpus: 234 (3s ago), crashers: 0, restarts: 1/9923, execs: 12345 (4114/sec), cover: 1234, uptime: 3s
+	goFuzzStatsRe = regexp.MustCompile(`workers: (\d+), corpus: (\d+) \([^)]*\), crashers: (\d+), restarts: [^,]*, execs: (\d+) \((\d+)/sec\), cover: (\d+), uptime: (\S+)`)
+
+	// nativeStatsRe matches a native Go fuzzing status line such as:
+	//   fuzz: elapsed: 3s, execs: 83370 (27788/sec), new interesting: 0 (total: 1)
+	nativeStatsRe = regexp.MustCompile(`^fuzz: elapsed: (\S+), execs: (\d+) \((\d+)/sec\), new interesting: \d+ \(total: (\d+)\)`)
+
+	// libFuzzerStatsRe matches a libFuzzer status line such as:
+	//   #4096	pulse  cov: 12 ft: 13 corp: 4/10b lim: 43 exec/s: 2048 rss: 29Mb
+	libFuzzerStatsRe = regexp.MustCompile(`^#(\d+)\s+\S+\s+cov: (\d+) .*corp: (\d+)/.*exec/s: (\d+)`)
+)
+
+// ParseStats parses a line of status output from a fuzzing engine. It returns false if
+// the line is not a status line. The returned Stats does not have its FuzzName set.
+func ParseStats(line string) (Stats, bool) {
+	var s Stats
+	if m := goFuzzStatsRe.FindStringSubmatch(line); m != nil {
+		s.Workers = atoi(m[1])
+		s.Corpus = atoi(m[2])
+		s.Crashers = atoi(m[3])
+		s.Execs = int64(atoi(m[4]))
+		s.ExecsPerSec = int64(atoi(m[5]))
+		s.Cover = atoi(m[6])
+		s.Uptime = seconds(m[7])
+		return s, true
+	}
+	if m := nativeStatsRe.FindStringSubmatch(line); m != nil {
+		s.Uptime = seconds(m[1])
+		s.Execs = int64(atoi(m[2]))
+		s.ExecsPerSec = int64(atoi(m[3]))
+		s.Corpus = atoi(m[4])
+		return s, true
+	}
+	if m := libFuzzerStatsRe.FindStringSubmatch(line); m != nil {
+		s.Execs = int64(atoi(m[1]))
+		s.Cover = atoi(m[2])
+		s.Corpus = atoi(m[3])
+		s.ExecsPerSec = int64(atoi(m[4]))
+		return s, true
+	}
+	return s, false
+}
+
+// seconds converts a duration such as '1m3s' to seconds, or 0 if it is not a valid duration.
+func seconds(s string) float64 {
+	d, _ := time.ParseDuration(s)
+	return d.Seconds()
+}
+
+// atoi is strconv.Atoi for strings already validated by a regexp.
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// statsWriter is an io.Writer that parses complete lines of output from a fuzzing engine
+// into Stats for a callback, and passes the output through to w.
+type statsWriter struct {
+	mu       *sync.Mutex // shared by the statsWriters for a process's stdout and stderr
+	fuzzName string
+	onStats  func(Stats)
+	w        io.Writer
+	hide     bool // if true, lines parsed as Stats are not passed through
+	buf      []byte
+}
+
+// outputWriters returns the writers for the stdout and stderr of a fuzzing engine,
+// along with a func to flush any buffered partial lines once the engine exits.
+func (opts *StartOptions) outputWriters(fuzzName string) (stdout, stderr io.Writer, flush func()) {
+	if opts.OnStats == nil && opts.Output == nil {
+		// nothing to parse, so pass our output through as is.
+		return os.Stdout, os.Stderr, func() {}
+	}
+	mu := new(sync.Mutex)
+	newWriter := func(w io.Writer) *statsWriter {
+		if opts.Output != nil {
+			w = opts.Output
+		}
+		return &statsWriter{mu: mu, fuzzName: fuzzName, onStats: opts.OnStats, w: w, hide: opts.Output != nil}
+	}
+	outW, errW := newWriter(os.Stdout), newWriter(os.Stderr)
+	return outW, errW, func() {
+		outW.flush()
+		errW.flush()
+	}
+}
+
+func (sw *statsWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.buf = append(sw.buf, p...)
+	for {
+		i := bytes.IndexByte(sw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := sw.buf[:i+1]
+		if err := sw.line(line); err != nil {
+			return len(p), err
+		}
+		sw.buf = sw.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// line handles one complete line, including its trailing newline.
+func (sw *statsWriter) line(line []byte) error {
+	s, ok := ParseStats(string(bytes.TrimRight(line, "\r\n")))
+	if ok {
+		s.FuzzName = sw.fuzzName
+		if sw.onStats != nil {
+			sw.onStats(s)
+		}
+		if sw.hide {
+			return nil
+		}
+	}
+	_, err := sw.w.Write(line)
+	return err
+}
+
+// flush passes through any remaining partial line.
+func (sw *statsWriter) flush() {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if len(sw.buf) > 0 {
+		sw.line(append(sw.buf, '\n'))
+		sw.buf = nil
+	}
+}
@@ -0,0 +1,78 @@
+package fuzz
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseStats(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want Stats
+		ok   bool
+	}{
+		{
+			name: "go-fuzz",
+			line: "2019/01/01 12:00:00 workers: 8, corpus: 234 (3s ago), crashers: 2, restarts: 1/9923, execs: 12345 (4114/sec), cover: 1234, uptime: 1m3s",
+			want: Stats{Workers: 8, Corpus: 234, Crashers: 2, Execs: 12345, ExecsPerSec: 4114, Cover: 1234, Uptime: 63},
+			ok:   true,
+		},
+		{
+			name: "go-fuzz prefixed by target name",
+			line: "pkg.FuzzFoo: 2019/01/01 12:00:00 workers: 1, corpus: 5 (now), crashers: 0, restarts: 1/0, execs: 0 (0/sec), cover: 0, uptime: 3s",
+			want: Stats{Workers: 1, Corpus: 5, Uptime: 3},
+			ok:   true,
+		},
+		{
+			name: "native",
+			line: "fuzz: elapsed: 6s, execs: 176184 (30937/sec), new interesting: 2 (total: 3)",
+			want: Stats{Corpus: 3, Execs: 176184, ExecsPerSec: 30937, Uptime: 6},
+			ok:   true,
+		},
+		{
+			name: "libfuzzer",
+			line: "#4096\tpulse  cov: 12 ft: 13 corp: 4/10b lim: 43 exec/s: 2048 rss: 29Mb",
+			want: Stats{Corpus: 4, Execs: 4096, ExecsPerSec: 2048, Cover: 12},
+			ok:   true,
+		},
+		{
+			name: "not a status line",
+			line: "fzgo: starting fuzzing pkg.FuzzFoo",
+			ok:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseStats(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("ParseStats() ok = %v, want %v", ok, tt.ok)
+			}
+			if got != tt.want {
+				t.Errorf("ParseStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsWriter(t *testing.T) {
+	var out bytes.Buffer
+	var got []Stats
+	opts := StartOptions{
+		OnStats: func(s Stats) { got = append(got, s) },
+		Output:  &out,
+	}
+	stdout, _, flush := opts.outputWriters("pkg.FuzzFoo")
+
+	// write a status line split across two writes, followed by other output.
+	stdout.Write([]byte("fuzz: elapsed: 3s, execs: 10 (3/sec), new"))
+	stdout.Write([]byte(" interesting: 0 (total: 1)\nsome other output\npartial"))
+	flush()
+
+	if len(got) != 1 || got[0].FuzzName != "pkg.FuzzFoo" || got[0].Execs != 10 {
+		t.Errorf("OnStats got %+v, want one Stats for pkg.FuzzFoo with 10 execs", got)
+	}
+	if want := "some other output\npartial\n"; out.String() != want {
+		t.Errorf("Output got %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,292 @@
+package fuzz
+
+import (
+	"bytes"
+	"fmt"
+	"go/build/constraint"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// testVariantTag is the build tag for the non-test copies of _test.go files
+// that we create in order to build a fuzz function that resides in a _test.go file.
+// It is part of buildTagsArg.
+const testVariantTag = "fzgo_testvariant"
+
+// testVariantPrefix is the filename prefix for our non-test copies of _test.go files.
+const testVariantPrefix = "fzgo_testvariant_"
+
+// xtestDir is the directory under the package directory where we place the non-test copies
+// of the _test.go files for an external test package.
+const xtestDir = "fzgoxtest"
+
+// buildPkgPath returns the import path to use when building against the function.
+// This differs from PkgPath for a function in an external test package.
+func (f *Func) buildPkgPath() string {
+	if f.XTest {
+		return path.Join(f.PkgPath, xtestDir)
+	}
+	return f.PkgPath
+}
+
+// testVariant is a temporary copy of the package's source tree that includes our non-test
+// copies of the package's _test.go files. The copy is a tree of directories leading to the
+// package directory, with symlinks to everything else, so it is cheap to create, and it keeps
+// the import paths and any relative paths of the original tree.
+type testVariant struct {
+	tempDir string // removed when we are done with the copy
+	pkgDir  string // the package directory within the copy
+	modDir  string // the module root within the copy, or empty in GOPATH mode
+	gopath  string // the GOPATH entry for the copy in GOPATH mode, or empty in module mode
+}
+
+// buildDir returns the package directory to use when building against the function,
+// which is within our copy of the source tree if the function is in a _test.go file.
+func (f *Func) buildDir() string {
+	if f.variant != nil {
+		return f.variant.pkgDir
+	}
+	return f.PkgDir
+}
+
+// buildModDir returns the module root to use when building against the function.
+func (f *Func) buildModDir() string {
+	if f.variant != nil && f.variant.modDir != "" {
+		return f.variant.modDir
+	}
+	return f.ModDir
+}
+
+// buildGopath returns the GOPATH to use when building against the function, which
+// places our copy of the source tree first in GOPATH mode.
+func (f *Func) buildGopath() string {
+	if f.variant != nil && f.variant.gopath != "" {
+		return strings.Join([]string{f.variant.gopath, Gopath()}, string(os.PathListSeparator))
+	}
+	return Gopath()
+}
+
+// buildEnv returns the env to use when building against the function, or nil for our own env.
+func (f *Func) buildEnv() []string {
+	if f.variant != nil && f.variant.gopath != "" {
+		return append(os.Environ(), "GOPATH="+f.buildGopath())
+	}
+	return nil
+}
+
+// materializeTestVariant supports fuzz functions that reside in _test.go files.
+// The go tool does not allow importing a test variant of a package, and go-fuzz-build
+// does not build test files, so we create non-test copies of the package's _test.go files
+// that are only included when building with our testVariantTag.
+// For an external test package, the copies are placed in a subdirectory, which makes them importable.
+// The copies are placed in a temporary copy of the source tree rather than the user's package directory,
+// and the returned testVariant should be removed when done.
+func materializeTestVariant(function Func) (*testVariant, error) {
+	report := func(err error) (*testVariant, error) {
+		return nil, fmt.Errorf("creating test variant for %s: %v", function.FuzzName(), err)
+	}
+
+	tempDir, err := ioutil.TempDir("", "fzgo-testvariant")
+	if err != nil {
+		return report(err)
+	}
+	v := &testVariant{tempDir: tempDir}
+
+	// the root of the tree we copy is the module root, or the GOPATH src directory.
+	var srcRoot, dstRoot string
+	if function.InModule() {
+		srcRoot = function.ModDir
+		dstRoot = filepath.Join(tempDir, "mod")
+		v.modDir = dstRoot
+	} else {
+		srcRoot = strings.TrimSuffix(function.PkgDir, filepath.FromSlash(function.PkgPath))
+		if srcRoot == function.PkgDir {
+			v.remove()
+			return report(fmt.Errorf("package directory %s does not match import path %s", function.PkgDir, function.PkgPath))
+		}
+		v.gopath = filepath.Join(tempDir, "gopath")
+		dstRoot = filepath.Join(v.gopath, "src")
+	}
+	rel, err := filepath.Rel(srcRoot, function.PkgDir)
+	if err != nil {
+		v.remove()
+		return report(err)
+	}
+	v.pkgDir = filepath.Join(dstRoot, rel)
+
+	// mirror each directory from the root down to the package directory.
+	src, dst := filepath.Clean(srcRoot), dstRoot
+	elems := strings.Split(rel, string(filepath.Separator))
+	if rel == "." {
+		elems = nil
+	}
+	for i := 0; ; i++ {
+		next := ""
+		if i < len(elems) {
+			next = elems[i]
+		}
+		if err := mirrorDir(src, dst, next); err != nil {
+			v.remove()
+			return report(err)
+		}
+		if next == "" {
+			break
+		}
+		src, dst = filepath.Join(src, next), filepath.Join(dst, next)
+	}
+	if function.InModule() {
+		// relative paths in the user's go.mod are relative to the original module root.
+		if err := writeVariantGoMod(function.ModDir, v.modDir); err != nil {
+			v.remove()
+			return report(err)
+		}
+	}
+
+	copyFiles := func(files []string, dstDir string) error {
+		for _, file := range files {
+			src, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			out, err := addBuildTag(src, testVariantTag)
+			if err != nil {
+				return fmt.Errorf("%s: %v", file, err)
+			}
+			// keep any _GOOS or _GOARCH suffix, which still applies after we drop '_test'.
+			name := testVariantPrefix + strings.TrimSuffix(filepath.Base(file), "_test.go") + ".go"
+			if err := ioutil.WriteFile(filepath.Join(dstDir, name), out, 0644); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := copyFiles(function.TestFiles, v.pkgDir); err != nil {
+		v.remove()
+		return report(err)
+	}
+	if function.XTest {
+		dir := filepath.Join(v.pkgDir, xtestDir)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			v.remove()
+			return report(err)
+		}
+		if err := copyFiles(function.XTestFiles, dir); err != nil {
+			v.remove()
+			return report(err)
+		}
+	}
+	return v, nil
+}
+
+// remove removes our copy of the source tree.
+func (v *testVariant) remove() {
+	os.RemoveAll(v.tempDir)
+}
+
+// mirrorDir creates the directory dst with symlinks to each entry in the directory src,
+// other than next, which the caller mirrors in turn. Any copies of _test.go files
+// left in src by an older version of fzgo are skipped.
+func mirrorDir(src, dst, next string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == next || name == xtestDir || strings.HasPrefix(name, testVariantPrefix) {
+			continue
+		}
+		if err := os.Symlink(filepath.Join(src, name), filepath.Join(dst, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeVariantGoMod replaces the go.mod and go.sum symlinks in our copy of the module root at dst
+// with copies of those in the module root at src. Relative replace directives are made absolute,
+// given they are relative to the original module root, and the go.sum is a copy so that
+// the go command does not update the user's go.sum via the symlink.
+func writeVariantGoMod(src, dst string) error {
+	gomod := filepath.Join(src, "go.mod")
+	data, err := ioutil.ReadFile(gomod)
+	if err != nil {
+		return err
+	}
+	f, err := modfile.Parse(gomod, data, nil)
+	if err != nil {
+		return err
+	}
+	for _, r := range f.Replace {
+		if r.New.Version == "" && !filepath.IsAbs(r.New.Path) {
+			if err := f.AddReplace(r.Old.Path, r.Old.Version, filepath.Join(src, r.New.Path), ""); err != nil {
+				return err
+			}
+		}
+	}
+	out, err := f.Format()
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{"go.mod", "go.sum"} {
+		os.Remove(filepath.Join(dst, name))
+	}
+	if err := ioutil.WriteFile(filepath.Join(dst, "go.mod"), out, 0644); err != nil {
+		return err
+	}
+	gosum := filepath.Join(src, "go.sum")
+	if !PathExists(gosum) {
+		return nil
+	}
+	data, err = ioutil.ReadFile(gosum)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dst, "go.sum"), data, 0644)
+}
+
+// addBuildTag adds a build constraint requiring tag to a Go source file,
+// combining it with any existing //go:build or // +build constraints,
+// which are replaced by a single //go:build line.
+func addBuildTag(src []byte, tag string) ([]byte, error) {
+	var expr constraint.Expr = &constraint.TagExpr{Tag: tag}
+
+	// build constraints must appear before the package clause, so only examine
+	// lines prior to the first line that is not blank or a comment.
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	var out bytes.Buffer
+	inHeader := true
+	inBlockComment := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(string(line))
+		if inHeader && !inBlockComment {
+			switch {
+			case constraint.IsGoBuild(trimmed) || constraint.IsPlusBuild(trimmed):
+				existing, err := constraint.Parse(trimmed)
+				if err != nil {
+					return nil, err
+				}
+				expr = &constraint.AndExpr{X: expr, Y: existing}
+				continue
+			case trimmed == "" || strings.HasPrefix(trimmed, "//"):
+			case strings.HasPrefix(trimmed, "/*"):
+				inBlockComment = !strings.Contains(trimmed, "*/")
+			default:
+				inHeader = false
+			}
+		} else if inBlockComment && strings.Contains(trimmed, "*/") {
+			inBlockComment = false
+		}
+		out.Write(line)
+	}
+	return append([]byte("//go:build "+expr.String()+"\n\n"), out.Bytes()...), nil
+}
@@ -0,0 +1,170 @@
+package fuzz
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddBuildTag(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "no constraint",
+			src:  "package foo\n",
+			want: "//go:build fzgo_testvariant\n\npackage foo\n",
+		},
+		{
+			name: "go:build constraint",
+			src:  "//go:build fuzz || gofuzz\n\npackage foo\n",
+			want: "//go:build fzgo_testvariant && (fuzz || gofuzz)\n\n\npackage foo\n",
+		},
+		{
+			name: "plus build constraint",
+			src:  "// Copyright\n\n// +build fuzz\n\npackage foo\n",
+			want: "//go:build fzgo_testvariant && fuzz\n\n// Copyright\n\n\npackage foo\n",
+		},
+		{
+			name: "constraint-like comment after package clause",
+			src:  "package foo\n\n//go:build fuzz\n",
+			want: "//go:build fzgo_testvariant\n\npackage foo\n\n//go:build fuzz\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := addBuildTag([]byte(tt.src), testVariantTag)
+			if err != nil {
+				t.Fatalf("addBuildTag() failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("addBuildTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaterializeTestVariant(t *testing.T) {
+	modDir, err := ioutil.TempDir("", "fzgo-test-module")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(modDir)
+	files := map[string]string{
+		"go.mod":                      "module example.com/m\n\nreplace example.com/other => ../other\n",
+		"go.sum":                      "",
+		"util/util.go":                "package util\n",
+		"pkg/pkg.go":                  "package pkg\n",
+		"pkg/fuzz_test.go":            "package pkg\n\nfunc FuzzFoo(data []byte) int { return 0 }\n",
+		"pkg/x_test.go":               "package pkg_test\n",
+		"pkg/fzgo_testvariant_old.go": "package pkg\n", // left behind by an older fzgo
+	}
+	for name, src := range files {
+		path := filepath.Join(modDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pkgDir := filepath.Join(modDir, "pkg")
+	function := Func{
+		FuncName: "FuzzFoo", PkgName: "pkg", PkgPath: "example.com/m/pkg", PkgDir: pkgDir,
+		ModPath: "example.com/m", ModDir: modDir,
+		InTestFile: true, XTest: true,
+		TestFiles:  []string{filepath.Join(pkgDir, "fuzz_test.go")},
+		XTestFiles: []string{filepath.Join(pkgDir, "x_test.go")},
+	}
+	v, err := materializeTestVariant(function)
+	if err != nil {
+		t.Fatalf("materializeTestVariant() failed: %v", err)
+	}
+	function.variant = v
+
+	// our copies are in the copy of the source tree, with the other files reachable via symlinks.
+	for _, name := range []string{"fzgo_testvariant_fuzz.go", "fzgoxtest/fzgo_testvariant_x.go", "pkg.go", "../util/util.go"} {
+		if !PathExists(filepath.Join(function.buildDir(), filepath.FromSlash(name))) {
+			t.Errorf("%s missing from the copy of the source tree", name)
+		}
+	}
+	if PathExists(filepath.Join(function.buildDir(), "fzgo_testvariant_old.go")) {
+		t.Errorf("stale copy of a _test.go file included in the copy of the source tree")
+	}
+	gomod, err := ioutil.ReadFile(filepath.Join(function.buildModDir(), "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(modDir, "..", "other"); !strings.Contains(string(gomod), want) {
+		t.Errorf("go.mod in the copy of the source tree = %q, want a replace with %q", gomod, want)
+	}
+
+	// the user's package directory is untouched, including by removing our copy.
+	v.remove()
+	entries, err := ioutil.ReadDir(pkgDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	want := []string{"fuzz_test.go", "fzgo_testvariant_old.go", "pkg.go", "x_test.go"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("package directory contains %q after removing the test variant, want %q", got, want)
+	}
+	if PathExists(v.tempDir) {
+		t.Errorf("copy of the source tree at %s not removed", v.tempDir)
+	}
+}
+
+func TestFindFuncSkipsNativeFuzzTests(t *testing.T) {
+	modDir, err := ioutil.TempDir("", "fzgo-test-module")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(modDir)
+	// a package that mixes fzgo fuzz functions with a native Go fuzz test.
+	files := map[string]string{
+		"go.mod":           "module example.com/mixed\n",
+		"mixed.go":         "package mixed\n\nfunc FuzzPlain(data []byte) int { return 0 }\n",
+		"fuzz_test.go":     "package mixed\n\nfunc FuzzRich(s string, n int) {}\n",
+		"native_test.go":   "package mixed\n\nimport \"testing\"\n\nfunc FuzzNative(f *testing.F) {\n\tf.Fuzz(func(t *testing.T, s string) {})\n}\n",
+		"x_native_test.go": "package mixed_test\n\nimport \"testing\"\n\nfunc FuzzXNative(f *testing.F) {}\n",
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(modDir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(modDir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWd)
+
+	env := append(os.Environ(), "GO111MODULE=on", "GOFLAGS=-mod=mod")
+	functions, err := FindFunc("example.com/mixed", ".", env, true)
+	if err != nil {
+		t.Fatalf("FindFunc() failed: %v", err)
+	}
+	var got []string
+	for _, function := range functions {
+		got = append(got, function.FuncName)
+	}
+	if want := []string{"FuzzPlain", "FuzzRich"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("FindFunc() = %q, want %q", got, want)
+	}
+
+	// a single native Go fuzz test is not a match.
+	if _, err := FindFunc("example.com/mixed", "FuzzNative", env, false); err == nil {
+		t.Errorf("FindFunc(FuzzNative) succeeded, want an error")
+	}
+}
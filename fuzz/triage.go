@@ -0,0 +1,245 @@
+package fuzz
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// triageFrames is the number of stack frames included in a crash signature for triage.
+// A few frames distinguish a shared helper that fails when called from different places.
+const triageFrames = 3
+
+// TriageDB records the crashers found for the fuzz functions in a package, grouped by crash signature.
+// go-fuzz only dedups crashers within a single workDir, whereas a TriageDB groups crashers
+// across all of a fuzz function's workDirs and across the fuzz functions in a package.
+// A TriageDB is stored as JSON under GOPATH/pkg/fuzz/triage/<import-path>/triage.json
+// so that first-seen times and counts are preserved across runs.
+type TriageDB struct {
+	Package string
+	Groups  []*CrashGroup
+
+	path string
+}
+
+// CrashGroup is a set of crashers that share a crash signature.
+type CrashGroup struct {
+	ID        string    // a short hash of the signature
+	Message   string    // the panic message or fatal error with numbers normalized, or a summary of the failure
+	Frames    []string  `json:",omitempty"` // the top functions in the crashing stack
+	FirstSeen time.Time // when the first crasher in this group was recorded
+	LastSeen  time.Time // when the most recent crasher in this group was recorded
+	Crashers  []*TriagedCrasher
+}
+
+// TriagedCrasher is a crasher for one fuzz function, which might be present in multiple workDirs.
+type TriagedCrasher struct {
+	Test      string // the fuzz function name
+	Hash      string
+	Paths     []string
+	FirstSeen time.Time
+}
+
+// Signature returns a one line description of a CrashGroup's signature.
+func (g *CrashGroup) Signature() string {
+	if len(g.Frames) == 0 {
+		return g.Message
+	}
+	return g.Message + " in " + g.Frames[0]
+}
+
+// Counts returns the number of crashers in a CrashGroup for each fuzz function.
+func (g *CrashGroup) Counts() map[string]int {
+	counts := make(map[string]int)
+	for _, c := range g.Crashers {
+		counts[c.Test]++
+	}
+	return counts
+}
+
+// TriageDBPath returns the location of the TriageDB for a package.
+func TriageDBPath(pkgPath string) string {
+	return filepath.Join(Gopath(), "pkg", "fuzz", "triage", filepath.FromSlash(pkgPath), "triage.json")
+}
+
+// LoadTriageDB loads the TriageDB for a package, or returns an empty TriageDB if none exists yet.
+func LoadTriageDB(pkgPath string) (*TriageDB, error) {
+	report := func(err error) (*TriageDB, error) { return nil, fmt.Errorf("load triage db: %v", err) }
+
+	db := &TriageDB{Package: pkgPath, path: TriageDBPath(pkgPath)}
+	data, err := ioutil.ReadFile(db.path)
+	if os.IsNotExist(err) {
+		return db, nil
+	} else if err != nil {
+		return report(err)
+	}
+	if err := json.Unmarshal(data, db); err != nil {
+		return report(fmt.Errorf("%s: %v", db.path, err))
+	}
+	return db, nil
+}
+
+// Save writes the TriageDB, replacing any prior version.
+func (db *TriageDB) Save() error {
+	report := func(err error) error { return fmt.Errorf("save triage db: %v", err) }
+
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return report(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(db.path), os.ModePerm); err != nil {
+		return report(err)
+	}
+	// write to a temp file and rename so that a concurrent reader never sees a partial file.
+	tmp := db.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return report(err)
+	}
+	if err := os.Rename(tmp, db.path); err != nil {
+		return report(err)
+	}
+	return nil
+}
+
+// Update records any crashers for a fuzz function in workDirs that are not yet in the TriageDB.
+// A crasher's first-seen time is the modification time of its file, which is when the
+// fuzzing engine wrote it, rather than when Update happens to run. A crasher with the same hash
+// in multiple workDirs is recorded once, using its earliest time. Update returns the number
+// of newly recorded crashers.
+func (db *TriageDB) Update(function Func, workDirs []string) int {
+	added := 0
+	for _, workDir := range workDirs {
+		for _, crasher := range Crashers(workDir) {
+			fi, err := os.Stat(crasher.Path)
+			if err != nil {
+				// the crasher was removed after it was listed.
+				continue
+			}
+			seen := fi.ModTime()
+			c := db.crasher(function.FuncName, crasher.Hash)
+			if c == nil {
+				c = &TriagedCrasher{Test: function.FuncName, Hash: crasher.Hash, FirstSeen: seen}
+				g := db.group(triageSignature(function, crasher))
+				g.Crashers = append(g.Crashers, c)
+				added++
+			}
+			if !contains(c.Paths, crasher.Path) {
+				c.Paths = append(c.Paths, crasher.Path)
+			}
+			if seen.Before(c.FirstSeen) {
+				c.FirstSeen = seen
+			}
+			g := db.groupOf(c)
+			if g.FirstSeen.IsZero() || seen.Before(g.FirstSeen) {
+				g.FirstSeen = seen
+			}
+			if seen.After(g.LastSeen) {
+				g.LastSeen = seen
+			}
+		}
+	}
+	sort.SliceStable(db.Groups, func(i, j int) bool { return db.Groups[i].FirstSeen.Before(db.Groups[j].FirstSeen) })
+	return added
+}
+
+// Find returns the CrashGroups whose ID starts with prefix, or that contain
+// a crasher whose hash starts with prefix.
+func (db *TriageDB) Find(prefix string) []*CrashGroup {
+	var found []*CrashGroup
+	for _, g := range db.Groups {
+		match := strings.HasPrefix(g.ID, prefix)
+		for _, c := range g.Crashers {
+			if strings.HasPrefix(c.Hash, prefix) {
+				match = true
+			}
+		}
+		if match {
+			found = append(found, g)
+		}
+	}
+	return found
+}
+
+// crasher returns the recorded crasher for a fuzz function with the supplied hash, or nil if none.
+func (db *TriageDB) crasher(test, hash string) *TriagedCrasher {
+	for _, g := range db.Groups {
+		for _, c := range g.Crashers {
+			if c.Test == test && c.Hash == hash {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// groupOf returns the CrashGroup holding a recorded crasher.
+func (db *TriageDB) groupOf(c *TriagedCrasher) *CrashGroup {
+	for _, g := range db.Groups {
+		for _, gc := range g.Crashers {
+			if gc == c {
+				return g
+			}
+		}
+	}
+	return nil
+}
+
+// group returns the CrashGroup matching sig, creating it if needed.
+func (db *TriageDB) group(sig CrashGroup) *CrashGroup {
+	for _, g := range db.Groups {
+		if g.ID == sig.ID {
+			return g
+		}
+	}
+	g := &sig
+	db.Groups = append(db.Groups, g)
+	return g
+}
+
+// triageSignature returns an empty CrashGroup holding the signature for a crasher of a fuzz function.
+// The signature is based on the crasher's .output file if it contains a panic or fatal error,
+// and otherwise on the crasher's summary (such as for a hang). The fuzz function itself
+// is not part of the signature unless it is where the crash occurred, which lets the
+// same bug found via different fuzz functions be grouped together. A crasher without any
+// output (such as for the libFuzzer engine) cannot be grouped, and is given a signature of its own.
+func triageSignature(function Func, crasher Crasher) CrashGroup {
+	var msg string
+	var frames []string
+	if output, err := ioutil.ReadFile(crasher.Path + ".output"); err == nil {
+		msg, frames = parseCrash(string(output))
+	}
+	for i := 1; i < len(frames); i++ {
+		if strings.HasSuffix(frames[i], "."+function.FuncName) {
+			frames = frames[:i]
+			break
+		}
+	}
+	if msg == "" && crasher.Summary != "" {
+		msg = signatureNumbersRe.ReplaceAllString(crasher.Summary, "N")
+	}
+	key := msg
+	if msg == "" {
+		msg = "(no output available)"
+		key = "crasher " + crasher.Hash
+	}
+	if len(frames) > triageFrames {
+		frames = frames[:triageFrames]
+	}
+	sum := sha1.Sum([]byte(key + "\n" + strings.Join(frames, "\n")))
+	return CrashGroup{ID: fmt.Sprintf("%x", sum[:4]), Message: msg, Frames: frames}
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package fuzz
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const goFuzzOutput = `panic: runtime error: index out of range [7] with length 2
+
+goroutine 1 [running]:
+example.com/pkg.parse(...)
+	/tmp/pkg/parse.go:10
+example.com/pkg.FuzzParse(0xc000012345, 0x9, 0x9)
+	/tmp/pkg/fuzz.go:5 +0x22
+go-fuzz-dep.Main(0xc000041f48, 0x1, 0x1)
+	go-fuzz-dep/main.go:36 +0x1ad
+main.main()
+	go-fuzz-main/main.go:10 +0x52
+`
+
+const nativeOutput = `--- FAIL: FuzzNative (0.52s)
+    --- FAIL: FuzzNative (0.00s)
+        testing.go:2076: panic: runtime error: index out of range [3] with length 1
+            goroutine 5690 [running]:
+            runtime/debug.Stack()
+            	/usr/local/go/src/runtime/debug/stack.go:26 +0x9b
+            panic({0x86a3c8?, 0x687a60?})
+            	/usr/local/go/src/runtime/panic.go:859 +0x125
+            example.com/pkg.parse(...)
+            	/tmp/pkg/parse.go:10
+            example.com/pkg/fzgoxtest.FuzzOther(...)
+            	/tmp/pkg/fzgoxtest/fzgo_testvariant_x.go:18
+            fzgo.tmp/richsigwrapper.fuzzOne(0x277f04612308)
+            	/tmp/fzgo-fuzz-rich-signature1198634120/richsigwrapper.go:30 +0x17f
+    Failing input written to testdata/fuzz/FuzzNative/dba5aca245ee057e
+FAIL
+`
+
+func TestParseCrash(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		wantMsg    string
+		wantFrames []string
+	}{
+		{
+			name:       "go-fuzz",
+			output:     goFuzzOutput,
+			wantMsg:    "panic: runtime error: index out of range [N] with length N",
+			wantFrames: []string{"example.com/pkg.parse", "example.com/pkg.FuzzParse"},
+		},
+		{
+			name:       "native with xtest",
+			output:     nativeOutput,
+			wantMsg:    "panic: runtime error: index out of range [N] with length N",
+			wantFrames: []string{"example.com/pkg.parse", "example.com/pkg_test.FuzzOther"},
+		},
+		{
+			name:    "no crash",
+			output:  "PASS\n",
+			wantMsg: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, frames := parseCrash(tt.output)
+			if msg != tt.wantMsg || !reflect.DeepEqual(frames, tt.wantFrames) {
+				t.Errorf("parseCrash() = %q, %q, want %q, %q", msg, frames, tt.wantMsg, tt.wantFrames)
+			}
+		})
+	}
+}
+
+func TestTriageDBUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fzgo-triage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeCrasher := func(workDir, hash, output string, modTime time.Time) {
+		crashersDir := filepath.Join(dir, workDir, "crashers")
+		if err := os.MkdirAll(crashersDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(crashersDir, hash)
+		if err := ioutil.WriteFile(path, []byte(hash), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if output != "" {
+			if err := ioutil.WriteFile(path+".output", []byte(output), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// FuzzParse has the same crasher in two workDirs, plus a second crasher with the same signature
+	// and a crasher with a different signature. FuzzOther crashes in the same place as FuzzParse,
+	// which is grouped with FuzzParse's crashers, and also has a crasher without output.
+	// The first and last seen times come from the crasher files, with the copy of aaaa in
+	// testdata being newer than the copy found by fuzzing.
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	hour := func(n int) time.Time { return t0.Add(time.Duration(n) * time.Hour) }
+	writeCrasher("testdata/FuzzParse", "aaaa", goFuzzOutput, hour(5))
+	writeCrasher("gopath/FuzzParse", "aaaa", goFuzzOutput, hour(0))
+	writeCrasher("gopath/FuzzParse", "bbbb", goFuzzOutput, hour(2))
+	writeCrasher("gopath/FuzzParse", "cccc", "panic: boom\n\ngoroutine 1 [running]:\nexample.com/pkg.FuzzParse(0x1)\n", hour(1))
+	writeCrasher("gopath/FuzzOther", "dddd", nativeOutput, hour(3))
+	writeCrasher("gopath/FuzzOther", "eeee", "", hour(4))
+
+	parse := Func{FuncName: "FuzzParse", PkgPath: "example.com/pkg"}
+	other := Func{FuncName: "FuzzOther", PkgPath: "example.com/pkg"}
+	db := &TriageDB{Package: "example.com/pkg"}
+	if n := db.Update(parse, []string{filepath.Join(dir, "testdata/FuzzParse"), filepath.Join(dir, "gopath/FuzzParse")}); n != 3 {
+		t.Errorf("Update(FuzzParse) added %d crashers, want 3", n)
+	}
+	if n := db.Update(other, []string{filepath.Join(dir, "gopath/FuzzOther")}); n != 2 {
+		t.Errorf("Update(FuzzOther) added %d crashers, want 2", n)
+	}
+	// a second update does not add anything or change any times.
+	if n := db.Update(parse, []string{filepath.Join(dir, "gopath/FuzzParse")}); n != 0 {
+		t.Errorf("second Update(FuzzParse) added %d crashers, want 0", n)
+	}
+
+	type group struct {
+		sig                 string
+		counts              map[string]int
+		firstSeen, lastSeen time.Time
+	}
+	var got []group
+	for _, g := range db.Groups {
+		got = append(got, group{g.Signature(), g.Counts(), g.FirstSeen.UTC(), g.LastSeen.UTC()})
+	}
+	want := []group{
+		{"panic: runtime error: index out of range [N] with length N in example.com/pkg.parse", map[string]int{"FuzzParse": 2, "FuzzOther": 1}, hour(0), hour(5)},
+		{"panic: boom in example.com/pkg.FuzzParse", map[string]int{"FuzzParse": 1}, hour(1), hour(1)},
+		{"(no output available)", map[string]int{"FuzzOther": 1}, hour(4), hour(4)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Update() groups = %+v, want %+v", got, want)
+	}
+	if c := db.Groups[0].Crashers[0]; !c.FirstSeen.Equal(hour(0)) {
+		t.Errorf("crasher aaaa first seen %v, want %v", c.FirstSeen, hour(0))
+	}
+	if paths := db.Groups[0].Crashers[0].Paths; len(paths) != 2 {
+		t.Errorf("crasher aaaa has paths %q, want 2 paths", paths)
+	}
+	if found := db.Find("bbbb"); len(found) != 1 || found[0] != db.Groups[0] {
+		t.Errorf("Find(bbbb) = %v, want first group", found)
+	}
+}
@@ -48,6 +48,18 @@ func createWrappers(pkgPattern string, functions []fuzz.Func, options wrapperOpt
 		})
 	}
 
+	// find any interface implementations registered with '//fzgo:impl' directives in the same package,
+	// which fzgo uses to fill interface parameters. The wrappers need to reside in the same package
+	// for fzgo to find the implementations, so we don't use them if we are qualifying everything.
+	var impls []fuzz.Impl
+	if !options.qualifyAll {
+		var err error
+		impls, err = findImpls(pkgPattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// emit the intro material
 	buf := new(bytes.Buffer)
 	var w io.Writer = buf
@@ -69,7 +81,7 @@ func createWrappers(pkgPattern string, functions []fuzz.Func, options wrapperOpt
 	})
 	// loop over our the functions we are wrapping, emitting a wrapper where possible.
 	for _, function := range functions {
-		err := createWrapper(w, function, possibleConstructors, options.qualifyAll)
+		err := createWrapper(w, function, possibleConstructors, impls, options.qualifyAll)
 		if err != nil {
 			return nil, fmt.Errorf("error processing %s: %v", function.FuncName, err)
 		}
@@ -87,13 +99,14 @@ func createWrappers(pkgPattern string, functions []fuzz.Func, options wrapperOpt
 // createWrapper emits one fuzzing wrapper if possible.
 // It takes a list of possible constructors to insert into the wrapper body if the
 // constructor is suitable for creating the receiver of a wrapped method.
+// impls are the interface implementations that fzgo can use to fill interface parameters.
 // qualifyAll indicates if all variables should be qualified with their package.
-func createWrapper(w io.Writer, function fuzz.Func, possibleConstructors []fuzz.Func, qualifyAll bool) error {
+func createWrapper(w io.Writer, function fuzz.Func, possibleConstructors []fuzz.Func, impls []fuzz.Impl, qualifyAll bool) error {
 	var err error
 	f := function.TypesFunc
 	wrappedSig, ok := f.Type().(*types.Signature)
 	if !ok {
-		return fmt.Errorf("function %s is not *types.Signature (%+v)", function, f)
+		return fmt.Errorf("function %s is not *types.Signature (%+v)", function.String(), f)
 	}
 	localPkg := f.Pkg()
 
@@ -160,7 +173,7 @@ func createWrapper(w io.Writer, function fuzz.Func, possibleConstructors []fuzz.
 
 	// check if we have an interface or function pointer in our desired parameters,
 	// we can't fill in with values during fuzzing.
-	if disallowedParams(w, allParams, wrapperName) {
+	if disallowedParams(w, allParams, wrapperName, impls) {
 		// skip this wrapper, disallowedParams emitted a comment with more details.
 		return nil
 	}
@@ -333,11 +346,11 @@ func emitArgs(w io.Writer, sig *types.Signature, collisionOffset int, localPkg *
 }
 
 // disallowedParams reports if the parameters include interfaces or funcs, and emits
-// a comment saying we are skipping if found.
-// We could try to handle certain interfaces like io.Reader, but right now google/gofuzz
-// I think will panic if asked to fuzz an interface with "panic: Can't handle <nil>".
-// Could translate at least io.Reader/io.Writer to []byte or *bytes.Buffer or similar.
-func disallowedParams(w io.Writer, allWrapperParams []*types.Var, wrapperName string) bool {
+// a comment saying we are skipping if found. Interfaces in fuzz.InterfaceImpl
+// or with an implementation in impls are allowed.
+// randparam only fills an interface with a handful of basic types (for example, an interface{}
+// can hold a string, but an io.Reader is left nil), so other interfaces are skipped.
+func disallowedParams(w io.Writer, allWrapperParams []*types.Var, wrapperName string, impls []fuzz.Impl) bool {
 	for _, v := range allWrapperParams {
 		// basic checking for interfaces, funcs, or pointers or slices of interfaces or funcs.
 		var t types.Type
@@ -353,7 +366,7 @@ func disallowedParams(w io.Writer, allWrapperParams []*types.Var, wrapperName st
 		switch t.Underlying().(type) {
 		case *types.Interface:
 			_, ok := fuzz.InterfaceImpl[v.Type().String()]
-			if !ok {
+			if !ok && len(fuzz.ImplsFor(v.Type(), impls)) == 0 {
 				// TODO: leaving old output for now.
 				fmt.Fprintf(w, "// skipping %s because parameters include interfaces or funcs: %v\n\n",
 					wrapperName, v.Type())
@@ -362,9 +375,12 @@ func disallowedParams(w io.Writer, allWrapperParams []*types.Var, wrapperName st
 				return true
 			}
 		case *types.Signature:
-			fmt.Fprintf(w, "// skipping %s because parameters include interfaces or funcs: %v\n\n",
-				wrapperName, v.Type())
-			return true
+			if t != v.Type() {
+				// a func parameter is filled with a synthesized func, but not a pointer or slice of funcs.
+				fmt.Fprintf(w, "// skipping %s because parameters include interfaces or funcs: %v\n\n",
+					wrapperName, v.Type())
+				return true
+			}
 		}
 	}
 	return false
@@ -391,7 +407,7 @@ func constructorReplace(recv *types.Var, possibleConstructors []fuzz.Func) (ctor
 		ctorSig, ok := possibleConstructor.TypesFunc.Type().(*types.Signature)
 		if !ok {
 			return ctorReplace, paramsToAdd, fmt.Errorf("function %s is not *types.Signature (%+v)",
-				possibleConstructor, possibleConstructor.TypesFunc)
+				possibleConstructor.String(), possibleConstructor.TypesFunc)
 		}
 
 		if ctorSig.Params().Len() == 0 {
@@ -616,6 +632,19 @@ func FindFunc(pkgPattern, funcPattern string, env []string, flags FindFuncFlag)
 	return result, nil
 }
 
+// findImpls returns the interface implementations registered with '//fzgo:impl' directives
+// in the packages matching pkgPattern.
+// The packages are loaded separately from FindFunc, so the implementations are matched
+// against interface parameters based on their methods.
+func findImpls(pkgPattern string) ([]fuzz.Impl, error) {
+	cfg := &packages.Config{Mode: packages.LoadSyntax}
+	pkgs, err := packages.Load(cfg, pkgPattern)
+	if err != nil {
+		return nil, fmt.Errorf("error while loading packages for pattern %v: %v", pkgPattern, err)
+	}
+	return fuzz.FindImpls(pkgs)
+}
+
 // goListDir returns the dir for a package import path
 // TODO: this is a temporary fork from fzgo/fuzz
 func goListDir(pkgPath string, env []string) (string, error) {
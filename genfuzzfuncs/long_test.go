@@ -191,7 +191,9 @@ func Fuzz_Fields(s string) {
 	strings.Fields(s)
 }
 
-// skipping Fuzz_FieldsFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_FieldsFunc(s string, f func(rune) bool) {
+	strings.FieldsFunc(s, f)
+}
 
 func Fuzz_HasPrefix(s string, prefix string) {
 	strings.HasPrefix(s, prefix)
@@ -213,7 +215,9 @@ func Fuzz_IndexByte(s string, c byte) {
 	strings.IndexByte(s, c)
 }
 
-// skipping Fuzz_IndexFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_IndexFunc(s string, f func(rune) bool) {
+	strings.IndexFunc(s, f)
+}
 
 func Fuzz_IndexRune(s string, r rune) {
 	strings.IndexRune(s, r)
@@ -235,9 +239,13 @@ func Fuzz_LastIndexByte(s string, c byte) {
 	strings.LastIndexByte(s, c)
 }
 
-// skipping Fuzz_LastIndexFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_LastIndexFunc(s string, f func(rune) bool) {
+	strings.LastIndexFunc(s, f)
+}
 
-// skipping Fuzz_Map because parameters include interfaces or funcs: func(rune) rune
+func Fuzz_Map(mapping func(rune) rune, s string) {
+	strings.Map(mapping, s)
+}
 
 func Fuzz_NewReader(s string) {
 	strings.NewReader(s)
@@ -311,13 +319,17 @@ func Fuzz_Trim(s string, cutset string) {
 	strings.Trim(s, cutset)
 }
 
-// skipping Fuzz_TrimFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_TrimFunc(s string, f func(rune) bool) {
+	strings.TrimFunc(s, f)
+}
 
 func Fuzz_TrimLeft(s string, cutset string) {
 	strings.TrimLeft(s, cutset)
 }
 
-// skipping Fuzz_TrimLeftFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_TrimLeftFunc(s string, f func(rune) bool) {
+	strings.TrimLeftFunc(s, f)
+}
 
 func Fuzz_TrimPrefix(s string, prefix string) {
 	strings.TrimPrefix(s, prefix)
@@ -327,7 +339,9 @@ func Fuzz_TrimRight(s string, cutset string) {
 	strings.TrimRight(s, cutset)
 }
 
-// skipping Fuzz_TrimRightFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_TrimRightFunc(s string, f func(rune) bool) {
+	strings.TrimRightFunc(s, f)
+}
 
 func Fuzz_TrimSpace(s string) {
 	strings.TrimSpace(s)
@@ -506,7 +520,9 @@ func Fuzz_Fields(s string) {
 	Fields(s)
 }
 
-// skipping Fuzz_FieldsFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_FieldsFunc(s string, f func(rune) bool) {
+	FieldsFunc(s, f)
+}
 
 func Fuzz_HasPrefix(s string, prefix string) {
 	HasPrefix(s, prefix)
@@ -528,7 +544,9 @@ func Fuzz_IndexByte(s string, c byte) {
 	IndexByte(s, c)
 }
 
-// skipping Fuzz_IndexFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_IndexFunc(s string, f func(rune) bool) {
+	IndexFunc(s, f)
+}
 
 func Fuzz_IndexRune(s string, r rune) {
 	IndexRune(s, r)
@@ -550,9 +568,13 @@ func Fuzz_LastIndexByte(s string, c byte) {
 	LastIndexByte(s, c)
 }
 
-// skipping Fuzz_LastIndexFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_LastIndexFunc(s string, f func(rune) bool) {
+	LastIndexFunc(s, f)
+}
 
-// skipping Fuzz_Map because parameters include interfaces or funcs: func(rune) rune
+func Fuzz_Map(mapping func(rune) rune, s string) {
+	Map(mapping, s)
+}
 
 func Fuzz_NewReader(s string) {
 	NewReader(s)
@@ -626,13 +648,17 @@ func Fuzz_Trim(s string, cutset string) {
 	Trim(s, cutset)
 }
 
-// skipping Fuzz_TrimFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_TrimFunc(s string, f func(rune) bool) {
+	TrimFunc(s, f)
+}
 
 func Fuzz_TrimLeft(s string, cutset string) {
 	TrimLeft(s, cutset)
 }
 
-// skipping Fuzz_TrimLeftFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_TrimLeftFunc(s string, f func(rune) bool) {
+	TrimLeftFunc(s, f)
+}
 
 func Fuzz_TrimPrefix(s string, prefix string) {
 	TrimPrefix(s, prefix)
@@ -642,7 +668,9 @@ func Fuzz_TrimRight(s string, cutset string) {
 	TrimRight(s, cutset)
 }
 
-// skipping Fuzz_TrimRightFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_TrimRightFunc(s string, f func(rune) bool) {
+	TrimRightFunc(s, f)
+}
 
 func Fuzz_TrimSpace(s string) {
 	TrimSpace(s)
@@ -848,7 +876,9 @@ func Fuzz_Fields(s string) {
 	strings.Fields(s)
 }
 
-// skipping Fuzz_FieldsFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_FieldsFunc(s string, f func(rune) bool) {
+	strings.FieldsFunc(s, f)
+}
 
 func Fuzz_HasPrefix(s string, prefix string) {
 	strings.HasPrefix(s, prefix)
@@ -870,7 +900,9 @@ func Fuzz_IndexByte(s string, c byte) {
 	strings.IndexByte(s, c)
 }
 
-// skipping Fuzz_IndexFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_IndexFunc(s string, f func(rune) bool) {
+	strings.IndexFunc(s, f)
+}
 
 func Fuzz_IndexRune(s string, r rune) {
 	strings.IndexRune(s, r)
@@ -892,9 +924,13 @@ func Fuzz_LastIndexByte(s string, c byte) {
 	strings.LastIndexByte(s, c)
 }
 
-// skipping Fuzz_LastIndexFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_LastIndexFunc(s string, f func(rune) bool) {
+	strings.LastIndexFunc(s, f)
+}
 
-// skipping Fuzz_Map because parameters include interfaces or funcs: func(rune) rune
+func Fuzz_Map(mapping func(rune) rune, s string) {
+	strings.Map(mapping, s)
+}
 
 func Fuzz_NewReader(s string) {
 	strings.NewReader(s)
@@ -968,13 +1004,17 @@ func Fuzz_Trim(s string, cutset string) {
 	strings.Trim(s, cutset)
 }
 
-// skipping Fuzz_TrimFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_TrimFunc(s string, f func(rune) bool) {
+	strings.TrimFunc(s, f)
+}
 
 func Fuzz_TrimLeft(s string, cutset string) {
 	strings.TrimLeft(s, cutset)
 }
 
-// skipping Fuzz_TrimLeftFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_TrimLeftFunc(s string, f func(rune) bool) {
+	strings.TrimLeftFunc(s, f)
+}
 
 func Fuzz_TrimPrefix(s string, prefix string) {
 	strings.TrimPrefix(s, prefix)
@@ -984,7 +1024,9 @@ func Fuzz_TrimRight(s string, cutset string) {
 	strings.TrimRight(s, cutset)
 }
 
-// skipping Fuzz_TrimRightFunc because parameters include interfaces or funcs: func(rune) bool
+func Fuzz_TrimRightFunc(s string, f func(rune) bool) {
+	strings.TrimRightFunc(s, f)
+}
 
 func Fuzz_TrimSpace(s string) {
 	strings.TrimSpace(s)
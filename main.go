@@ -18,6 +18,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -28,27 +29,37 @@ import (
 )
 
 var (
-	flagCompile  bool
-	flagFuzzFunc string
-	flagFuzzDir  string
-	flagFuzzTime time.Duration
-	flagParallel int
-	flagRun      string
-	flagTimeout  time.Duration
-	flagVerbose  bool
-	flagDebug    string
+	flagCompile      bool
+	flagCoverProfile string
+	flagCoverHTML    string
+	flagFuzzFunc     string
+	flagFuzzDir      string
+	flagFuzzDict     string
+	flagFuzzTime     time.Duration
+	flagJSON         bool
+	flagEngine       string
+	flagParallel     int
+	flagRun          string
+	flagTimeout      time.Duration
+	flagVerbose      bool
+	flagDebug        string
 )
 
 var flagDefs = []fuzz.FlagDef{
 	{Name: "fuzz", Ptr: &flagFuzzFunc, Description: "fuzz at most one function matching `regexp`"},
 	{Name: "fuzzdir", Ptr: &flagFuzzDir, Description: "store fuzz artifacts in `dir` (default pkgpath/testdata/fuzz)"},
+	{Name: "fuzzdict", Ptr: &flagFuzzDict, Description: "add the tokens from the dictionaries in comma separated `files` to the dictionary derived from the fuzzed package (libfuzzer and native engines)"},
 	{Name: "fuzztime", Ptr: &flagFuzzTime, Description: "fuzz for duration `d` (default unlimited)"},
+	{Name: "fuzzengine", Ptr: &flagEngine, Description: "fuzzing `engine`: go-fuzz (default), native (Go 1.18+ 'go test -fuzz') or libfuzzer (requires clang)"},
 	{Name: "parallel", Ptr: &flagParallel, Description: "start `n` fuzzing operations (default GOMAXPROCS)"},
 	{Name: "run", Ptr: &flagRun, Description: "if supplied with -fuzz, -run=Corpus/123ABCD executes corpus file matching regexp 123ABCD as a unit test." +
 		"Otherwise, run normal 'go test' with only those tests and examples matching the regexp."},
 	{Name: "timeout", Ptr: &flagTimeout, Description: "fail an individual call to a fuzz function after duration `d` (default 10s, minimum 1s)"},
+	{Name: "coverprofile", Ptr: &flagCoverProfile, Description: "if supplied with -fuzz, replay the corpus and write a coverage profile for the fuzzed package to `file` instead of fuzzing"},
+	{Name: "coverhtml", Ptr: &flagCoverHTML, Description: "if supplied with -fuzz, replay the corpus and write an HTML coverage report for the fuzzed package to `file` instead of fuzzing"},
 	{Name: "c", Ptr: &flagCompile, Description: "compile the instrumented code but do not run it"},
 	{Name: "v", Ptr: &flagVerbose, Description: "verbose: print additional output"},
+	{Name: "json", Ptr: &flagJSON, Description: "print a stream of JSON events describing our progress, similar in spirit to 'go test -json'"},
 	{Name: "debug", Ptr: &flagDebug, Description: "comma separated list of debug options; currently only supports 'nomultifuzz'"},
 }
 
@@ -84,6 +95,16 @@ func fzgoMain() int {
 		return ArgErr
 	}
 
+	if os.Args[1] == "minimize" {
+		return minimizeMain(os.Args[2:], fs)
+	}
+	if os.Args[1] == "corpus" {
+		return corpusMain(os.Args[2:])
+	}
+	if os.Args[1] == "crashers" {
+		return crashersMain(os.Args[2:])
+	}
+
 	if os.Args[1] != "test" {
 		// pass through to 'go' command
 		err = fuzz.ExecGo(os.Args[1:], nil)
@@ -105,6 +126,9 @@ func fzgoMain() int {
 		fmt.Println("fzgo:", err)
 		return ArgErr
 	}
+	if flagJSON {
+		fuzz.SetEventOutput(os.Stdout)
+	}
 
 	if flagFuzzFunc == "" {
 		// 'fzgo test' without '-fuzz'
@@ -121,20 +145,28 @@ func fzgoMain() int {
 		// Because -fuzz is not set, we also:
 		//   2. pass our arguments through to the normal 'go' command, which will run normal 'go test'.
 		if flagFuzzFunc == "" {
+			// 'go test -json' emits its own events.
+			fuzz.SetEventOutput(nil)
 			err = fuzz.ExecGo(os.Args[1:], nil)
 			if err != nil {
 				return OtherErr
 			}
 		}
 		return Success
-	} else if flagFuzzFunc != "" && flagRun != "" {
+	} else if flagFuzzFunc != "" && (flagRun != "" || flagCoverProfile != "" || flagCoverHTML != "") {
 		//'fzgo test -fuzz=foo -run=bar'
 		// The -run means we have not been asked to generate new fuzz-based inputs,
 		// but instead will run our corpus, and possibly any crashers if
 		// -run matches (e.g., -run=TestCrashers or -run=TestCrashers/02ABCDEF).
 		// Crashers will only be executed if the -run argument matches.
+		// Similarly, -coverprofile or -coverhtml means we report the coverage
+		// from running our corpus (by default excluding the crashers).
+		run := flagRun
+		if run == "" {
+			run = "TestCorpus"
+		}
 		return verifyCorpus(os.Args,
-			verifyCorpusOptions{run: flagRun, tryCrashers: true, verbose: flagVerbose})
+			verifyCorpusOptions{run: run, tryCrashers: true, verbose: flagVerbose, coverProfile: flagCoverProfile, coverHTML: flagCoverHTML})
 	}
 
 	// we now know we have been asked to do fuzzing.
@@ -148,17 +180,23 @@ func fzgoMain() int {
 	if funcTimeout == 0 {
 		funcTimeout = 10 * time.Second
 	} else if funcTimeout < 1*time.Second {
-		fmt.Printf("fzgo: fuzz function timeout value %s in -timeout flag is less than minimum of 1 second\n", funcTimeout)
+		printMsg(fmt.Sprintf("fuzz function timeout value %s in -timeout flag is less than minimum of 1 second", funcTimeout))
+		return ArgErr
+	}
+
+	engine, err := fuzz.ParseEngine(flagEngine)
+	if err != nil {
+		printMsg(err)
 		return ArgErr
 	}
 
 	// look for the functions we have been asked to fuzz.
 	functions, err := fuzz.FindFunc(pkgPattern, flagFuzzFunc, nil, allowMultiFuzz)
 	if err != nil {
-		fmt.Println("fzgo:", err)
+		printMsg(err)
 		return OtherErr
 	} else if len(functions) == 0 {
-		fmt.Printf("fzgo: failed to find fuzz function for pattern %v and func %v\n", pkgPattern, flagFuzzFunc)
+		printMsg(fmt.Sprintf("failed to find fuzz function for pattern %v and func %v", pkgPattern, flagFuzzFunc))
 		return OtherErr
 	}
 	if flagVerbose {
@@ -166,25 +204,84 @@ func fzgoMain() int {
 		for _, function := range functions {
 			names = append(names, function.String())
 		}
-		fmt.Printf("fzgo: found functions %s\n", strings.Join(names, ", "))
+		printMsg(fmt.Sprintf("found functions %s", strings.Join(names, ", ")))
+	}
+	for _, function := range functions {
+		fuzz.EmitEvent(fuzz.FuncEvent(fuzz.ActionDiscover, function))
 	}
 
 	// build our instrumented code, or find if is is already built in the fzgo cache
 	var targets []fuzz.Target
 	for _, function := range functions {
-		target, err := fuzz.Instrument(function, flagVerbose)
+		target, err := fuzz.Instrument(function, engine, flagVerbose)
 		if err != nil {
-			fmt.Println("fzgo:", err)
+			printMsg(err)
 			return OtherErr
 		}
 		targets = append(targets, target)
 	}
 
 	if flagCompile {
-		fmt.Println("fzgo: finished instrumenting binaries")
+		printMsg("finished instrumenting binaries")
 		return Success
 	}
 
+	opts := fuzz.StartOptions{Parallel: parallel, FuncTimeout: funcTimeout, Verbose: flagVerbose}
+	if flagFuzzDict != "" {
+		opts.Dicts = strings.Split(flagFuzzDict, ",")
+	}
+
+	// remember any crashers that already exist so that we can report on new crashers once we are done.
+	existing := make(map[string]bool)
+	for _, target := range targets {
+		for _, crasher := range fuzz.Crashers(determineWorkDir(target.UserFunc, flagFuzzDir)) {
+			existing[crasher.Path] = true
+		}
+	}
+
+	// if we are on a terminal, show a compact live view of our progress
+	// instead of the raw status output from the fuzzing engine.
+	var dash *dashboard
+	if !flagVerbose && !flagJSON && isTerminal(os.Stdout) {
+		dash = newDashboard(os.Stdout, targets)
+		opts.OnStats = dash.update
+		opts.Output = dash
+	}
+
+	if len(targets) > 1 && engine == fuzz.GoFuzz && parallel >= len(targets) {
+		// fuzz our targets concurrently, splitting our -parallel budget across them.
+		// each target needs at least one worker, so with a smaller budget we instead
+		// fall through to fuzzing one target at a time below.
+		var workDirs []string
+		for _, target := range targets {
+			workDir := determineWorkDir(target.UserFunc, flagFuzzDir)
+			if err = seedCorpus(target.UserFunc, workDir); err != nil {
+				printMsg(err)
+				return OtherErr
+			}
+			if err = copyCachedCorpus(target.UserFunc, workDir); err != nil {
+				printMsg(err)
+				return OtherErr
+			}
+			workDirs = append(workDirs, workDir)
+		}
+		opts.MaxDuration = flagFuzzTime
+		err = fuzz.Schedule(targets, workDirs, opts)
+		if err != nil {
+			printMsg(err)
+			return OtherErr
+		}
+		return reportNewCrashers(targets, existing)
+	}
+
+	// otherwise, we fuzz one target at a time, using round-robin if there are multiple targets.
+	// we only need to add any seeds from FuzzXxxSeeds funcs once.
+	for _, target := range targets {
+		if err = seedCorpus(target.UserFunc, determineWorkDir(target.UserFunc, flagFuzzDir)); err != nil {
+			printMsg(err)
+			return OtherErr
+		}
+	}
 	// run forever if flagFuzzTime was not set (that is, has default value of 0).
 	loopForever := flagFuzzTime == 0
 	timeQuantum := 5 * time.Second
@@ -196,7 +293,7 @@ func fzgoMain() int {
 			// seed our workDir with any other corpus that might exist from other known locations.
 			// see comment for copyCachedCorpus for discussion of current behavior vs. desired behavior.
 			if err = copyCachedCorpus(target.UserFunc, workDir); err != nil {
-				fmt.Println("fzgo:", err)
+				printMsg(err)
 				return OtherErr
 			}
 
@@ -213,12 +310,19 @@ func fzgoMain() int {
 			}
 
 			// fuzz!
-			err = fuzz.Start(target, workDir, fuzzDuration, parallel, funcTimeout, flagVerbose)
+			if dash != nil {
+				// fuzz.Start prints some output of its own, so start a fresh dashboard below that output.
+				dash.reset()
+			}
+			opts.MaxDuration = fuzzDuration
+			err = fuzz.Start(target, workDir, opts)
 			if err != nil {
-				fmt.Println("fzgo:", err)
+				printMsg(err)
 				return OtherErr
 			}
-			fmt.Println() // blank separator line at end of one target's fuzz run.
+			if !flagJSON {
+				fmt.Println() // blank separator line at end of one target's fuzz run.
+			}
 		}
 		// run forever if flagFuzzTime was not set,
 		// but otherwise break after fuzzing each target once for flagFuzzTime above.
@@ -231,13 +335,51 @@ func fzgoMain() int {
 		}
 
 	}
-	return Success
+	return reportNewCrashers(targets, existing)
+}
+
+// reportNewCrashers prints a summary of any crashers for our targets that are not in existing,
+// which is keyed by crasher path. It returns OtherErr if there are new crashers
+// so that a run such as 'fzgo test -fuzz=. -fuzztime=10m' in CI fails when fuzzing finds a crash.
+func reportNewCrashers(targets []fuzz.Target, existing map[string]bool) int {
+	// record our crashers in the triage database used by 'fzgo crashers list'.
+	var functions []fuzz.Func
+	for _, target := range targets {
+		functions = append(functions, target.UserFunc)
+	}
+	if _, err := updateTriage(functions); err != nil {
+		printMsg(err)
+	}
+
+	status := Success
+	for _, target := range targets {
+		for _, crasher := range fuzz.Crashers(determineWorkDir(target.UserFunc, flagFuzzDir)) {
+			if existing[crasher.Path] {
+				continue
+			}
+			if status == Success {
+				printMsg("new crashers found:")
+				status = OtherErr
+			}
+			summary := crasher.Summary
+			if summary == "" {
+				summary = "(no output available)"
+			}
+			printMsg(fmt.Sprintf("  %s %s: %s", target.FuzzName(), crasher.Hash, summary))
+			printMsg(fmt.Sprintf("    reproducer: %s", crasher.Path))
+			printMsg(fmt.Sprintf("    to run: fzgo test -fuzz=%s -run=TestCrashers/%s %s",
+				target.UserFunc.FuncName, crasher.Hash, target.UserFunc.PkgPath))
+		}
+	}
+	return status
 }
 
 type verifyCorpusOptions struct {
-	run         string
-	tryCrashers bool
-	verbose     bool
+	run          string
+	tryCrashers  bool
+	coverProfile string // if set, write a coverage profile to this file
+	coverHTML    string // if set, write an HTML coverage report to this file
+	verbose      bool
 }
 
 // verifyCorpus validates our corpus by executing any fuzz functions in our package pattern
@@ -250,12 +392,12 @@ func verifyCorpus(args []string, opt verifyCorpusOptions) int {
 	// whitelist what we want to pass through to 'go test' (now including -run and -v).
 	testPkgPatterns, _, err := fuzz.FindPkgs(args[2:])
 	if err != nil {
-		fmt.Println("fzgo:", err)
+		printMsg(err)
 		return OtherErr
 	}
 	var testPkgPattern string
 	if len(testPkgPatterns) > 1 {
-		fmt.Printf("fzgo: more than one package pattern not allowed: %q", testPkgPatterns)
+		printMsg(fmt.Sprintf("more than one package pattern not allowed: %q", testPkgPatterns))
 		return ArgErr
 	} else if len(testPkgPatterns) == 0 {
 		testPkgPattern = "."
@@ -265,63 +407,58 @@ func verifyCorpus(args []string, opt verifyCorpusOptions) int {
 
 	functions, err := fuzz.FindFunc(testPkgPattern, flagFuzzFunc, nil, true)
 	if err != nil {
-		fmt.Println("fzgo:", err)
+		printMsg(err)
 		return OtherErr
 	}
 
+	// if we have been asked for coverage, each 'go test' invocation writes a profile
+	// to our temp dir, and we merge them once we are done.
+	var coverDir string
+	var profiles []string
+	nextProfile := func() string { return "" }
+	if opt.coverProfile != "" || opt.coverHTML != "" {
+		coverDir, err = ioutil.TempDir("", "fzgo-cover")
+		if err != nil {
+			printMsg(err)
+			return OtherErr
+		}
+		defer os.RemoveAll(coverDir)
+		nextProfile = func() string {
+			profiles = append(profiles, filepath.Join(coverDir, fmt.Sprintf("%d.out", len(profiles))))
+			return profiles[len(profiles)-1]
+		}
+	}
+
 	status := Success
 	for _, function := range functions {
 
-		// work through how many places we need to check based on
-		// what the user specified in flagFuzzDir.
-		var dirsToCheck []string
-
-		// we always check the "testdata" dir if it exists.
-		testdataWorkDir := determineWorkDir(function, "testdata")
-		dirsToCheck = append(dirsToCheck, testdataWorkDir)
-
-		// we also always check under  GOPATH/pkg/fuzz/corpus/... if it exists.
-		gopathPkgWorkDir := determineWorkDir(function, "")
-		dirsToCheck = append(dirsToCheck, gopathPkgWorkDir)
-
-		// see if we need to check elsewhere as well.
-		if flagFuzzDir == "" {
-			// nothing else to do; the user did not specify a dir.
-		} else if flagFuzzDir == "testdata" {
-			// nothing else to do; we already added testdata dir.
-		} else {
-			// the user supplied a destination
-			userWorkDir := determineWorkDir(function, flagFuzzDir)
-			dirsToCheck = append(dirsToCheck, userWorkDir)
-		}
-
 		// we have 2 or 3 places to check
 		foundWorkDir := false
-		for _, workDir := range dirsToCheck {
+		for _, workDir := range workDirsToCheck(function) {
 			if !fuzz.PathExists(filepath.Join(workDir, "corpus")) {
 				// corpus dir in this workDir does not exist, so skip.
 				continue
 			}
 			foundWorkDir = true
 
-			err := fuzz.VerifyCorpus(function, workDir, opt.run, opt.verbose)
+			err := fuzz.VerifyCorpus(function, workDir, opt.run, nextProfile(), opt.verbose)
 			if err == fuzz.ErrGoTestFailed {
 				// 'go test' itself should have printed an informative error,
 				// so here we just set a non-zero status code and continue.
 				status = OtherErr
 			} else if err != nil {
-				fmt.Println("fzgo:", err)
+				printMsg(err)
 				return OtherErr
 			}
 			if opt.tryCrashers {
 				// This might not end up matching anything based on the -run=foo regexp,
 				// but we try it anyway and let cmd/go skip executing the test if it doesn't match.
-				err = fuzz.VerifyCrashers(function, workDir, opt.run, opt.verbose)
+				err = fuzz.VerifyCrashers(function, workDir, opt.run, nextProfile(), opt.verbose)
 				if err == fuzz.ErrGoTestFailed {
 					// Similar to above, 'go test' itself should have printed an informative error.
 					status = OtherErr
 				} else if err != nil {
-					fmt.Println("fzgo:", err)
+					printMsg(err)
 					return OtherErr
 				}
 			}
@@ -333,9 +470,55 @@ func verifyCorpus(args []string, opt verifyCorpusOptions) int {
 		}
 	}
 
+	if coverDir != "" {
+		profile := opt.coverProfile
+		if profile == "" {
+			profile = filepath.Join(coverDir, "merged.out")
+		}
+		if err := fuzz.MergeCoverProfiles(profile, profiles); err != nil {
+			printMsg(err)
+			return OtherErr
+		}
+		if opt.coverProfile != "" {
+			printMsg(fmt.Sprintf("wrote coverage profile to %s", opt.coverProfile))
+		}
+		if opt.coverHTML != "" {
+			if err := fuzz.CoverHTML(profile, opt.coverHTML); err != nil {
+				printMsg(err)
+				return OtherErr
+			}
+			printMsg(fmt.Sprintf("wrote coverage report to %s", opt.coverHTML))
+		}
+	}
 	return status
 }
 
+// workDirsToCheck returns the workDirs that might exist for a function,
+// based on what the user specified in flagFuzzDir.
+func workDirsToCheck(function fuzz.Func) []string {
+	var dirsToCheck []string
+
+	// we always check the "testdata" dir if it exists.
+	testdataWorkDir := determineWorkDir(function, "testdata")
+	dirsToCheck = append(dirsToCheck, testdataWorkDir)
+
+	// we also always check under  GOPATH/pkg/fuzz/corpus/... if it exists.
+	gopathPkgWorkDir := determineWorkDir(function, "")
+	dirsToCheck = append(dirsToCheck, gopathPkgWorkDir)
+
+	// see if we need to check elsewhere as well.
+	if flagFuzzDir == "" {
+		// nothing else to do; the user did not specify a dir.
+	} else if flagFuzzDir == "testdata" {
+		// nothing else to do; we already added testdata dir.
+	} else {
+		// the user supplied a destination
+		userWorkDir := determineWorkDir(function, flagFuzzDir)
+		dirsToCheck = append(dirsToCheck, userWorkDir)
+	}
+	return dirsToCheck
+}
+
 // determineWorkDir translates from the user's specified -fuzzdir to an actual
 // location on disk, including the default location if the user does not specify a -fuzzdir.
 func determineWorkDir(function fuzz.Func, requestedFuzzDir string) string {
@@ -385,9 +568,10 @@ func copyCachedCorpus(function fuzz.Func, dstWorkDir string) error {
 			continue
 		}
 		if fuzz.PathExists(srcCorpusDir) {
-			// copyDir will create dstDir if needed, and won't overwrite files
-			// in dstDir that already exist.
-			if err := fuzz.CopyDir(dstCorpusDir, srcCorpusDir); err != nil {
+			// CopyCorpus will create dstCorpusDir if needed, won't overwrite files
+			// in dstCorpusDir that already exist, and won't restore files
+			// that 'fzgo corpus distill -inplace' removed from dstCorpusDir.
+			if err := fuzz.CopyCorpus(dstWorkDir, srcWorkDir); err != nil {
 				return fmt.Errorf("failed seeding destination corpus: %v", err)
 			}
 		}
@@ -395,6 +579,40 @@ func copyCachedCorpus(function fuzz.Func, dstWorkDir string) error {
 	return nil
 }
 
+// seedCorpus adds the seed inputs from the fuzz function's FuzzXxxSeeds func, if any, to the corpus
+// in dstWorkDir. Like copyCachedCorpus, it does not update inputs that already exist.
+func seedCorpus(function fuzz.Func, dstWorkDir string) error {
+	if function.SeedsFunc == "" {
+		return nil
+	}
+	inputs, err := fuzz.SeedInputs(function)
+	if err != nil {
+		return err
+	}
+	added := 0
+	for _, data := range inputs {
+		_, existed, err := fuzz.AddInput(filepath.Join(dstWorkDir, "corpus"), data)
+		if err != nil {
+			return fmt.Errorf("failed seeding destination corpus: %v", err)
+		}
+		if !existed {
+			added++
+		}
+	}
+	printMsg(fmt.Sprintf("added %d of %d seeds from %s to corpus for %s", added, len(inputs), function.SeedsFunc, function.FuzzName()))
+	return nil
+}
+
+// printMsg prints a message from fzgo, or emits it as an output event if -json is set.
+func printMsg(msg interface{}) {
+	s := fmt.Sprintln("fzgo:", msg)
+	if fuzz.EventsEnabled() {
+		fuzz.EmitEvent(fuzz.Event{Action: fuzz.ActionOutput, Output: s})
+		return
+	}
+	fmt.Print(s)
+}
+
 func usage(fs *flag.FlagSet) func() {
 	return func() {
 		fmt.Printf("\nfzgo is a simple prototype of integrating dvyukov/go-fuzz into 'go test'.\n\n")
@@ -406,7 +624,10 @@ func usage(fs *flag.FlagSet) func() {
 		fmt.Printf("   fzgo test -fuzz .                   # fuzz the current package with a function starting with 'Fuzz'\n")
 		fmt.Printf("   fzgo test -fuzz FuzzFoo             # fuzz the current package with a function matching 'FuzzFoo'\n")
 		fmt.Printf("   fzgo test ./... -fuzz FuzzFoo       # fuzz a package in ./... with a function matching 'FuzzFoo'\n")
-		fmt.Printf("   fzgo test sample/pkg -fuzz FuzzFoo  # fuzz 'sample/pkg' with a function matching 'FuzzFoo'\n\n")
+		fmt.Printf("   fzgo test sample/pkg -fuzz FuzzFoo  # fuzz 'sample/pkg' with a function matching 'FuzzFoo'\n")
+		fmt.Printf("   fzgo minimize -fuzz FuzzFoo -run TestCrashers/1a2b3c  # minimize crashers matching '1a2b3c'\n")
+		fmt.Printf("   fzgo corpus distill -fuzz FuzzFoo -inplace         # prune the corpus while preserving coverage\n")
+		fmt.Printf("   fzgo crashers list                                 # group crashers by crash signature\n\n")
 		fmt.Printf("The following flags work with 'fzgo test -fuzz':\n\n")
 
 		for _, d := range flagDefs {
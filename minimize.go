@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/thepudds/fzgo/fuzz"
+)
+
+// minimizeMain implements 'fzgo minimize -fuzz=FuzzFoo -run=TestCrashers/<hash> [pkg]', which minimizes
+// the matching crashers for a fuzz function and writes each minimized crasher next to the original.
+// -fuzztime limits how long is spent minimizing each crasher, and -timeout limits a single execution.
+// args is os.Args[2:].
+func minimizeMain(args []string, fs *flag.FlagSet) int {
+	pkgPattern, err := fuzz.ParseArgs(args, fs)
+	if err == flag.ErrHelp {
+		// if we get here, we already printed usage.
+		return ArgErr
+	} else if err != nil {
+		fmt.Println("fzgo:", err)
+		return ArgErr
+	}
+	if flagFuzzFunc == "" || flagRun == "" {
+		fmt.Println("fzgo: minimize requires -fuzz and -run flags, such as 'fzgo minimize -fuzz=FuzzFoo -run=TestCrashers/1a2b3c'")
+		return ArgErr
+	}
+	funcTimeout := flagTimeout
+	if funcTimeout == 0 {
+		funcTimeout = 10 * time.Second
+	}
+
+	functions, err := fuzz.FindFunc(pkgPattern, flagFuzzFunc, nil, true)
+	if err != nil {
+		fmt.Println("fzgo:", err)
+		return OtherErr
+	} else if len(functions) == 0 {
+		fmt.Printf("fzgo: failed to find fuzz function for pattern %v and func %v\n", pkgPattern, flagFuzzFunc)
+		return OtherErr
+	}
+
+	opts := fuzz.MinimizeOptions{Run: flagRun, MaxDuration: flagFuzzTime, FuncTimeout: funcTimeout, Verbose: flagVerbose}
+	found := false
+	for _, function := range functions {
+		for _, workDir := range workDirsToCheck(function) {
+			results, err := fuzz.Minimize(function, workDir, opts)
+			if err != nil {
+				fmt.Println("fzgo:", err)
+				return OtherErr
+			}
+			for _, result := range results {
+				found = true
+				fmt.Printf("fzgo: minimized %s %s from %d to %d bytes: %s\n",
+					function.FuzzName(), result.Crasher.Hash, result.OrigSize, result.Size, result.Path)
+			}
+		}
+	}
+	if !found {
+		fmt.Printf("fzgo: no crashers found matching -run=%s\n", flagRun)
+		return OtherErr
+	}
+	return Success
+}
@@ -52,6 +52,24 @@ func (s *randSource) Uint64() uint64 {
 	return 0
 }
 
+// Uint returns an unsigned integer formed from the next width bytes of our input data
+// in little-endian order, consuming only those bytes. width must be in [1, 8].
+// If fewer than width bytes remain, the remaining bytes are used, which means the
+// value is formed by drawing zeros for the missing high-order bytes.
+// This is not part of rand.Source64 interface, but allows us to read exactly
+// the width of a numeric type, which lets go-fuzz sonar find and replace the value in place.
+func (s *randSource) Uint(width int) uint64 {
+	if width > len(s.data) {
+		width = len(s.data)
+	}
+	var val uint64
+	for i, b := range s.data[:width] {
+		val |= uint64(b) << uint64(i*8)
+	}
+	s.data = s.data[width:]
+	return val
+}
+
 // Byte returns one byte, consuming only one byte of our input data.
 // This is not part of rand.Source64 interface, but useful
 // in our custom fuzzing functions so that we don't waste input
@@ -35,3 +35,22 @@ func TestRandSource_Uint64(t *testing.T) {
 		})
 	}
 }
+
+func TestRandSource_Uint(t *testing.T) {
+	src := randSource{data: []byte{0x01, 0x34, 0x12, 0xEF, 0xBE, 0xAD, 0xDE, 0x42}}
+	tests := []struct {
+		width int
+		want  uint64
+	}{
+		{1, 0x01},
+		{2, 0x1234},
+		{4, 0xdeadbeef},
+		{8, 0x42}, // only 1 byte remains.
+		{2, 0x0},
+	}
+	for _, tt := range tests {
+		if got := src.Uint(tt.width); got != tt.want {
+			t.Errorf("RandSource.Uint(%d) = 0x%x, want 0x%x", tt.width, got, tt.want)
+		}
+	}
+}
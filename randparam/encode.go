@@ -0,0 +1,386 @@
+package randparam
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Encode returns an input that a Fuzzer fills in with args, which allows a corpus for a rich signature
+// to be seeded with hand-picked values. Each arg corresponds to one call to Fuzz or Fill, in order,
+// such as the parameters of a fuzz function. The result is deterministic.
+//
+// Not every value can be produced by a Fuzzer. For example, strings and []byte are limited to 254 bytes,
+// other slices and maps are limited to 10 elements,
+// and an interface can only hold one of the types a Fuzzer chooses from, such as a string or int.
+// Non-nil chans and funcs are not supported. Encode returns an error for a value that a Fuzzer
+// would not fill in the same way, except that a nil []byte or []string is encoded as an empty one.
+func Encode(args ...interface{}) ([]byte, error) {
+	return EncodeWithOptions(args)
+}
+
+// EncodeWithOptions is like Encode, but returns an input that a Fuzzer created by
+// NewFuzzerWithOptions with opts fills in with args.
+func EncodeWithOptions(args []interface{}, opts ...Option) ([]byte, error) {
+	// our first byte selects the nil chance and number of elements in NewFuzzer.
+	// we first try without nil or number of elements decisions, and if we need them,
+	// we start over with the first byte that allows them.
+	data, err := encodeArgs(args, 0, opts)
+	if err == errNeedDecisions {
+		data, err = encodeArgs(args, decisionsFirstByte, opts)
+	}
+	return data, err
+}
+
+func encodeArgs(args []interface{}, firstByte byte, opts []Option) ([]byte, error) {
+	e := &encoder{
+		buf:    []byte{firstByte},
+		minLen: 1,
+		cfg:    NewFuzzerWithOptions([]byte{firstByte}, opts...),
+		retry:  firstByte != decisionsFirstByte,
+	}
+	for i, arg := range args {
+		var sh *shaper
+		if shaped, ok := arg.(Shaped); ok {
+			var err error
+			if sh, err = compileShape(shaped.Shape); err != nil {
+				return nil, fmt.Errorf("cannot encode arg %d: %v", i, err)
+			}
+			arg = shaped.Value
+		}
+		if arg == nil {
+			return nil, fmt.Errorf("cannot encode arg %d: untyped nil", i)
+		}
+		if err := e.encode(reflect.ValueOf(arg), 0, sh); err != nil {
+			if err == errNeedDecisions {
+				return nil, err
+			}
+			return nil, fmt.Errorf("cannot encode arg %d: %v", i, err)
+		}
+	}
+
+	// running out of data is the same as drawing zeros, so trailing zeros are not needed,
+	// except where a length must not exceed the remaining data.
+	data := e.buf
+	for len(data) > e.minLen && data[len(data)-1] == 0 {
+		data = data[:len(data)-1]
+	}
+	for len(data) < e.minLen {
+		data = append(data, 0)
+	}
+	return data, nil
+}
+
+// decisionsFirstByte selects a nil chance and a range for the number of elements in NewFuzzer.
+const decisionsFirstByte = 0x80
+
+// errNeedDecisions reports that a value needs a nil or number of elements decision,
+// which requires decisionsFirstByte.
+var errNeedDecisions = errors.New("nil chance or number of elements needed")
+
+// encoder builds an input by reversing each of the draws made by a Fuzzer as it walks a value.
+type encoder struct {
+	buf    []byte
+	minLen int     // the input must not be trimmed shorter than minLen
+	cfg    *Fuzzer // a Fuzzer configured by our first byte and any options, which we do not draw from
+	retry  bool    // whether we can start over with decisionsFirstByte
+	mapKey bool    // whether we are within a map key, as for Fuzzer.mapKey
+}
+
+// encode walks v in the same order as Fuzzer.walk when filling a value at depth.
+func (e *encoder) encode(v reflect.Value, depth int, sh *shaper) error {
+	t := v.Type()
+	if t == timeType {
+		tm := v.Interface().(time.Time)
+		e.uint(8, uint64(tm.Unix()))
+		e.uint(8, uint64(tm.Nanosecond()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, 1)
+		} else {
+			e.buf = append(e.buf, 0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.uint(int(t.Size()), uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.uint(int(t.Size()), v.Uint())
+	case reflect.Float32, reflect.Float64:
+		e.float(int(t.Size()), v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		e.float(int(t.Size())/2, real(c))
+		e.float(int(t.Size())/2, imag(c))
+	case reflect.String:
+		return e.shapedBytes([]byte(v.String()), sh)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encode(v.Index(i), depth, sh); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		if !v.CanAddr() {
+			// we need an addressable struct to read any unexported fields the Fuzzer sets.
+			addressable := reflect.New(t).Elem()
+			addressable.Set(v)
+			v = addressable
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			field, ok := e.cfg.settableField(v, i)
+			if !ok {
+				// a Fuzzer does not set this unexported field.
+				if !field.IsZero() {
+					return fmt.Errorf("unexported field %s.%s is not supported", t, f.Name)
+				}
+				continue
+			}
+			fieldShape, err := compileShape(Shape(f.Tag.Get("fzgo")))
+			if err != nil {
+				return fmt.Errorf("field %s: %v", f.Name, err)
+			}
+			if err := e.encode(field, depth, fieldShape); err != nil {
+				if err == errNeedDecisions {
+					return err
+				}
+				return fmt.Errorf("field %s: %v", f.Name, err)
+			}
+		}
+	case reflect.Ptr:
+		if fill, err := e.shouldFill(!v.IsNil(), depth); !fill || err != nil {
+			return err
+		}
+		return e.encode(v.Elem(), depth+1, sh)
+	case reflect.Slice:
+		switch t.Elem().Kind() {
+		case reflect.Uint8, reflect.String:
+			if depth >= e.cfg.maxDepth {
+				return e.tooDeep(v)
+			}
+			if t.Elem().Kind() == reflect.Uint8 {
+				return e.shapedBytes(v.Bytes(), sh)
+			}
+			if err := e.length(v.Len()); err != nil {
+				return err
+			}
+			for i := 0; i < v.Len(); i++ {
+				if err := e.shapedBytes([]byte(v.Index(i).String()), sh); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if fill, err := e.shouldFill(!v.IsNil(), depth); !fill || err != nil {
+			return err
+		}
+		if err := e.elementCount(v.Len()); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encode(v.Index(i), depth+1, sh); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if fill, err := e.shouldFill(!v.IsNil(), depth); !fill || err != nil {
+			return err
+		}
+		if err := e.elementCount(v.Len()); err != nil {
+			return err
+		}
+		keys := v.MapKeys()
+		// map iteration order is random, so sort for a stable result.
+		sort.Slice(keys, func(i, j int) bool {
+			return Literal(keys[i].Interface()) < Literal(keys[j].Interface())
+		})
+		for _, key := range keys {
+			mapKey := e.mapKey
+			e.mapKey = true
+			err := e.encode(key, depth+1, sh)
+			e.mapKey = mapKey
+			if err != nil {
+				return err
+			}
+			if err := e.encode(v.MapIndex(key), depth+1, sh); err != nil {
+				return err
+			}
+		}
+	case reflect.Interface:
+		choices := interfaceChoices(t, e.mapKey)
+		if v.IsNil() && len(choices) == 0 {
+			return nil
+		}
+		choice := -1
+		if !v.IsNil() {
+			for i, c := range choices {
+				if v.Elem().Type() == c {
+					choice = i
+				}
+			}
+			if choice < 0 {
+				return fmt.Errorf("%v holding %v is not supported", t, v.Elem().Type())
+			}
+		}
+		if fill, err := e.shouldFill(!v.IsNil(), depth); !fill || err != nil {
+			return err
+		}
+		if len(choices) > 1 {
+			e.buf = append(e.buf, byte(choice))
+		}
+		return e.encode(v.Elem(), depth+1, sh)
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if !v.IsNil() {
+			return fmt.Errorf("non-nil %v is not supported", t)
+		}
+		if v.Kind() == reflect.Chan {
+			_, err := e.shouldFill(false, depth)
+			return err
+		}
+	default:
+		return fmt.Errorf("%v is not supported", t)
+	}
+	return nil
+}
+
+// uint encodes a call to randSource.Uint, which reads width bytes in little-endian order.
+func (e *encoder) uint(width int, u uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], u)
+	e.buf = append(e.buf, b[:width]...)
+}
+
+// float encodes a float of width bytes as drawn by randFloat. A special value is encoded by its selector,
+// a whole number in the range of a signed integer of the same width is encoded as that integer,
+// and any other value is encoded as its raw bits.
+func (e *encoder) float(width int, f float64) {
+	for i, special := range specialFloats(width) {
+		if math.Float64bits(f) == math.Float64bits(special) || math.IsNaN(f) && math.IsNaN(special) {
+			e.buf = append(e.buf, floatSpecial+byte(i))
+			return
+		}
+	}
+	limit := math.Ldexp(1, 8*width-1)
+	if f == math.Trunc(f) && f >= -limit && f < limit && !(f == 0 && math.Signbit(f)) {
+		e.buf = append(e.buf, floatInt)
+		e.uint(width, uint64(int64(f)))
+		return
+	}
+	e.buf = append(e.buf, floatRaw)
+	if width == 4 {
+		e.uint(width, uint64(math.Float32bits(float32(f))))
+		return
+	}
+	e.uint(width, math.Float64bits(f))
+}
+
+// length encodes a length field for a string, []byte or []string, as read by calcSize.
+func (e *encoder) length(n int) error {
+	if n > maxLength {
+		return fmt.Errorf("length %d is greater than maximum of %d", n, maxLength)
+	}
+	if n == 0 {
+		// 0xFF is only treated as a zero length if it does not exceed the remaining data.
+		e.buf = append(e.buf, 0xFF)
+		e.minLen = max(e.minLen, len(e.buf)+0xFF)
+		return nil
+	}
+	e.buf = append(e.buf, byte(n))
+	e.minLen = max(e.minLen, len(e.buf)+n)
+	return nil
+}
+
+// shapedBytes encodes a string or []byte, which must have shape sh.
+func (e *encoder) shapedBytes(b []byte, sh *shaper) error {
+	if !sh.has(string(b)) {
+		return fmt.Errorf("%q does not have shape %q", b, sh.shape)
+	}
+	return e.bytes(b)
+}
+
+func (e *encoder) bytes(b []byte) error {
+	if len(b) > e.cfg.maxStringLength {
+		return fmt.Errorf("length %d is greater than maximum of %d", len(b), e.cfg.maxStringLength)
+	}
+	if err := e.length(len(b)); err != nil {
+		return err
+	}
+	e.buf = append(e.buf, b...)
+	return nil
+}
+
+// shouldFill encodes the Fuzzer's decision of whether to fill or leave nil a pointer, map, slice,
+// chan or interface at depth. It returns fill.
+func (e *encoder) shouldFill(fill bool, depth int) (bool, error) {
+	if depth >= e.cfg.maxDepth {
+		if fill {
+			return false, fmt.Errorf("values nested more than %d deep are not supported", e.cfg.maxDepth)
+		}
+		return false, nil
+	}
+	switch {
+	case e.cfg.nilChance == 0 && fill:
+		// no nil chance, so no decision to encode.
+	case e.cfg.nilChance == 0:
+		return false, e.needDecisions(errors.New("nil values are not supported with a nil chance of 0"))
+	case fill && e.cfg.nilChance > 0xFF:
+		return false, errors.New("non-nil values are not supported with a nil chance of 1")
+	case fill:
+		e.buf = append(e.buf, 0xFF)
+	default:
+		e.buf = append(e.buf, 0)
+	}
+	return fill, nil
+}
+
+// tooDeep reports an error if v is not empty, given it is nested deeper than a Fuzzer fills.
+func (e *encoder) tooDeep(v reflect.Value) error {
+	if v.Len() > 0 {
+		return fmt.Errorf("values nested more than %d deep are not supported", e.cfg.maxDepth)
+	}
+	return nil
+}
+
+// elementCount encodes the number of elements for a map, slice or chan, which must not
+// exceed the remaining data.
+func (e *encoder) elementCount(n int) error {
+	min, max := e.cfg.minElements, e.cfg.maxElements
+	switch {
+	case n > max:
+		return e.needDecisions(fmt.Errorf("%d elements is greater than maximum of %d", n, max))
+	case n < min:
+		return e.needDecisions(fmt.Errorf("%d elements is less than minimum of %d", n, min))
+	}
+	if min != max {
+		e.buf = append(e.buf, byte(n-min))
+	}
+	e.minLen = e.maxLen(n)
+	return nil
+}
+
+// needDecisions returns errNeedDecisions if we can start over with decisionsFirstByte, and otherwise err.
+func (e *encoder) needDecisions(err error) error {
+	if e.retry {
+		return errNeedDecisions
+	}
+	return err
+}
+
+// maxLen returns the larger of minLen and the length needed for n more bytes.
+func (e *encoder) maxLen(n int) int {
+	return max(e.minLen, len(e.buf)+n)
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
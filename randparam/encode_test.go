@@ -0,0 +1,208 @@
+package randparam
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+type encodeMode int
+
+type encodeName string
+
+type encodeStruct struct {
+	A      int
+	B      *string
+	C      []encodeStruct
+	D      map[string]float64
+	Mode   encodeMode
+	Name   encodeName
+	Ratio  float32
+	Arr    [2]bool
+	When   time.Time
+	Names  []string
+	hidden int
+}
+
+func TestEncode(t *testing.T) {
+	s := "x"
+	tests := []struct {
+		name string
+		args []interface{}
+	}{
+		{"basic types", []interface{}{"a+b", []byte("aab"), true}},
+		{"empty string before other args", []interface{}{"", []byte{}, "c", false}},
+		{"numbers", []interface{}{int8(-1), uint16(65535), 42, int64(-1 << 63), float64(1 << 60), float32(3), 'r', byte(0)}},
+		{"floats", []interface{}{1.5, -2.0, math.Copysign(0, -1), math.Inf(-1), math.NaN(), 5e-324, float32(0.1), float32(-3),
+			float32(math.MaxFloat32), float64(1 << 63), complex(0.5, math.Inf(1)), complex64(-1), struct{ F encodeFloat }{2.5}}},
+		{"trailing zeros", []interface{}{"a\x00\x00", 0, false}},
+		{"slices and maps", []interface{}{[]int{1, 2, 3}, []int{}, []int(nil), map[int]bool{3: true, 1: false}, map[string]int(nil)}},
+		{"pointers", []interface{}{&s, (*[]int)(nil), &[]int{7}, (*int)(nil), &[]*int{nil, new(int)}}},
+		{"interfaces", []interface{}{
+			struct{ X interface{} }{1},
+			[]interface{}{"a", []byte("b"), 2.0, true, nil, []interface{}{map[string]interface{}{"c": 3}}},
+			struct{ X fmt.Stringer }{nil},
+			map[interface{}]interface{}{"a": []byte("b"), 2: nil, 1.5: true, false: map[string]interface{}{}},
+		}},
+		{"other kinds", []interface{}{complex(1, 2), complex64(3), uintptr(4), [2]*int{nil, new(int)}, (chan int)(nil), (func())(nil)}},
+		{"time", []interface{}{time.Time{}, time.Unix(-1e10, 7)}},
+		{"nested", []interface{}{newEncodeList(defaultMaxDepth), struct{ F encodeFloat }{2}}},
+		{"named types", []interface{}{encodeMode(-7), encodeName("héllo 世界"), []encodeName{"a", ""}}},
+		{"struct", []interface{}{encodeStruct{
+			A:     1,
+			B:     &s,
+			C:     []encodeStruct{{A: 2, B: &s, When: time.Unix(0, 0), Names: []string{}}},
+			D:     map[string]float64{"b": 2, "a": 1},
+			Mode:  3,
+			Name:  "n",
+			Ratio: 4,
+			Arr:   [2]bool{true, false},
+			When:  time.Unix(1600000000, 5),
+			Names: []string{"p", "", "q"},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Encode(tt.args...)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			// decode with one call to Fuzz per arg, as a rich signature wrapper does.
+			fuzzer := NewFuzzer(data)
+			for i, want := range tt.args {
+				got := reflect.New(reflect.TypeOf(want))
+				fuzzer.Fuzz(got.Interface())
+				opts := cmp.Options{
+					cmp.AllowUnexported(encodeStruct{}),
+					cmpopts.EquateNaNs(),
+					cmp.Comparer(func(x, y time.Time) bool { return x.Equal(y) }),
+				}
+				if diff := cmp.Diff(want, got.Elem().Interface(), opts); diff != "" {
+					t.Errorf("arg %d mismatch after decoding %x (-want +got):\n%s", i, data, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeBytes(t *testing.T) {
+	got, err := Encode("a+b", []byte("aab"), true)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	want := []byte("\x00\x03a+b\x03aab\x01")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Encode() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	s := "x"
+	tests := []struct {
+		name string
+		arg  interface{}
+		want string
+	}{
+		{"long string", strings.Repeat("x", 255), "greater than maximum"},
+		{"too many elements", make([]int, 11), "greater than maximum"},
+		{"unexported field", encodeStruct{B: &s, When: time.Unix(0, 0), hidden: 1}, "unexported field"},
+		{"interface holding unsupported type", struct{ X interface{} }{int8(1)}, "not supported"},
+		{"non-nil chan", make(chan int), "not supported"},
+		{"nested too deep", newEncodeList(defaultMaxDepth + 1), "nested more than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Encode(tt.arg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Encode() error = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeWithOptions(t *testing.T) {
+	s := "x"
+	tests := []struct {
+		name string
+		opts []Option
+		args []interface{}
+	}{
+		{"more elements", []Option{MaxElements(20)}, []interface{}{make([]int, 20), map[int]bool{1: true}, []int(nil)}},
+		{"fewer elements", []Option{MaxElements(2)}, []interface{}{[]int{1, 2}, []int{}, &s}},
+		{"never nil", []Option{NilChance(0)}, []interface{}{&s, []int{1, 2, 3}, map[string]int{"a": 1}}},
+		{"always nil", []Option{NilChance(1)}, []interface{}{(*string)(nil), []int(nil), "y"}},
+		{"shallow", []Option{MaxDepth(1)}, []interface{}{&s, newEncodeList(1), []*int{nil, nil}}},
+		{"short strings", []Option{MaxStringLength(3)}, []interface{}{"abc", []byte("de"), []string{"f", "ghi"}}},
+		{"unexported fields", []Option{FillUnexported(encodePkgPath)}, []interface{}{
+			encodeStruct{A: 1, When: time.Unix(0, 0), Names: []string{}, hidden: -3},
+			map[int]encodeStruct{2: {hidden: 4, When: time.Unix(5, 0), Names: []string{}}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeWithOptions(tt.args, tt.opts...)
+			if err != nil {
+				t.Fatalf("EncodeWithOptions() error = %v", err)
+			}
+			fuzzer := NewFuzzerWithOptions(data, tt.opts...)
+			for i, want := range tt.args {
+				got := reflect.New(reflect.TypeOf(want))
+				fuzzer.Fuzz(got.Interface())
+				opts := cmp.Options{
+					cmp.AllowUnexported(encodeStruct{}),
+					cmp.Comparer(func(x, y time.Time) bool { return x.Equal(y) }),
+				}
+				if diff := cmp.Diff(want, got.Elem().Interface(), opts); diff != "" {
+					t.Errorf("arg %d mismatch after decoding %x (-want +got):\n%s", i, data, diff)
+				}
+			}
+		})
+	}
+
+	errTests := []struct {
+		name string
+		opt  Option
+		arg  interface{}
+		want string
+	}{
+		{"too many elements", MaxElements(2), []int{1, 2, 3}, "greater than maximum"},
+		{"nil with no nil chance", NilChance(0), (*int)(nil), "nil chance of 0"},
+		{"non-nil with nil chance of 1", NilChance(1), &s, "nil chance of 1"},
+		{"nested too deep", MaxDepth(1), newEncodeList(2), "nested more than 1 deep"},
+		{"long string", MaxStringLength(3), "abcd", "greater than maximum of 3"},
+		{"unexported field in another package", FillUnexported("example.com/other"), encodeStruct{hidden: 1}, "unexported field"},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := EncodeWithOptions([]interface{}{tt.arg}, tt.opt)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("EncodeWithOptions() error = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// encodePkgPath is the import path of this package, for FillUnexported.
+var encodePkgPath = reflect.TypeOf(encodeStruct{}).PkgPath()
+
+type encodeFloat float64
+
+type encodeList struct {
+	Val  int
+	Next *encodeList
+}
+
+// newEncodeList returns a list with n elements, which is nested n pointers deep.
+func newEncodeList(n int) *encodeList {
+	var l *encodeList
+	for i := n; i > 0; i-- {
+		l = &encodeList{Val: i, Next: l}
+	}
+	return l
+}
@@ -0,0 +1,87 @@
+package randparam
+
+import (
+	"math"
+)
+
+// Floats are drawn as a selector byte followed by the bytes for the value, if any.
+// The selector picks how to interpret what follows:
+//
+//    [0x00, 0x80): the raw IEEE-754 bits of the float in little-endian order,
+//                  which can be any float, including fractions, subnormals and NaNs.
+//    [0x80, 0xF0): a signed integer of the same width in little-endian order,
+//                  converted to a float. This lets whole numbers appear in the input
+//                  the same way they do for integer types, such as from a dictionary.
+//    [0xF0, 0xFF]: one of 16 special values, with no further bytes.
+//
+// Running out of input means a selector of 0x0 with bits of zero, which is 0.0.
+const (
+	floatRaw     = 0x00
+	floatInt     = 0x80
+	floatSpecial = 0xF0
+)
+
+// randFloat returns a float of the given width in bytes (4 or 8).
+func randFloat(fzgoSrc *randSource, width int) float64 {
+	sel := fzgoSrc.Byte()
+	switch {
+	case sel < floatInt:
+		bits := fzgoSrc.Uint(width)
+		if width == 4 {
+			return float64(math.Float32frombits(uint32(bits)))
+		}
+		return math.Float64frombits(bits)
+	case sel < floatSpecial:
+		i := randInt(fzgoSrc, width)
+		if width == 4 {
+			return float64(float32(i))
+		}
+		return float64(i)
+	default:
+		return specialFloats(width)[sel-floatSpecial]
+	}
+}
+
+// specialFloats returns the special values for floats of the given width in bytes,
+// which are values that tend to find bugs in numeric code but are unlikely to be
+// found by mutating bits.
+func specialFloats(width int) [16]float64 {
+	if width == 4 {
+		return [16]float64{
+			math.NaN(),
+			math.Inf(1),
+			math.Inf(-1),
+			math.Copysign(0, -1),
+			math.SmallestNonzeroFloat32,
+			float64(math.Float32frombits(0x007FFFFF)), // largest subnormal
+			float64(math.Float32frombits(0x00800000)), // smallest normal
+			math.MaxFloat32,
+			-math.MaxFloat32,
+			float64(math.Nextafter32(1, 2)),
+			float64(float32(0.1)),
+			0.5,
+			-1,
+			1 << 24, // beyond this, not every integer can be represented.
+			1 << 31, // overflows an int32.
+			1 << 32, // overflows a uint32.
+		}
+	}
+	return [16]float64{
+		math.NaN(),
+		math.Inf(1),
+		math.Inf(-1),
+		math.Copysign(0, -1),
+		math.SmallestNonzeroFloat64,
+		math.Float64frombits(0x000FFFFFFFFFFFFF), // largest subnormal
+		math.Float64frombits(0x0010000000000000), // smallest normal
+		math.MaxFloat64,
+		-math.MaxFloat64,
+		math.Nextafter(1, 2),
+		0.1,
+		0.5,
+		-1,
+		1 << 53, // beyond this, not every integer can be represented.
+		1 << 63, // overflows an int64.
+		1 << 64, // overflows a uint64.
+	}
+}
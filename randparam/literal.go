@@ -0,0 +1,186 @@
+package randparam
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Literal returns Go source code for a value filled in by a Fuzzer, such as
+// `[]byte("abc")` or `&pkgname.T{A: 1}`, which can be used to reproduce the value
+// in a unit test. Types are qualified by their package name. Struct fields that are
+// unexported or hold a zero value are omitted, which matches how a Fuzzer fills structs
+// unless FillUnexported is used, given a literal cannot set unexported fields outside their package.
+// Numbers are untyped constants, so the result is intended for a context with a known type,
+// such as 'var x int8 = <literal>'. Channels (including any buffered elements) and funcs are reported as nil.
+func Literal(v interface{}) string {
+	if v == nil {
+		return "nil"
+	}
+	var b strings.Builder
+	writeLiteral(&b, reflect.ValueOf(v), true)
+	return b.String()
+}
+
+// writeLiteral writes a literal for v. If typed is false, the context does not supply a type
+// (such as an element of a []interface{}), so a conversion is written for numbers when needed.
+func writeLiteral(b *strings.Builder, v reflect.Value, typed bool) {
+	t := v.Type()
+	switch v.Kind() {
+	case reflect.Bool:
+		writeConst(b, t, strconv.FormatBool(v.Bool()), typed || t == reflect.TypeOf(true))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeConst(b, t, strconv.FormatInt(v.Int(), 10), typed || t == reflect.TypeOf(0))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeConst(b, t, strconv.FormatUint(v.Uint(), 10), typed)
+	case reflect.Float32, reflect.Float64:
+		s, isConst := floatLiteral(v.Float(), t.Bits())
+		// a call such as math.NaN() is a float64, rather than an untyped constant.
+		writeConst(b, t, s, isConst && typed || t == reflect.TypeOf(0.0))
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		re, reConst := floatLiteral(real(c), t.Bits()/2)
+		im, imConst := floatLiteral(imag(c), t.Bits()/2)
+		writeConst(b, t, fmt.Sprintf("complex(%s, %s)", re, im), reConst && imConst && typed || t == reflect.TypeOf(0i))
+	case reflect.String:
+		writeConst(b, t, strconv.Quote(v.String()), typed || t == reflect.TypeOf(""))
+	case reflect.Slice:
+		if v.IsNil() {
+			writeNil(b, t, typed)
+			return
+		}
+		if t.Elem().Kind() == reflect.Uint8 && t.Elem().PkgPath() == "" {
+			// a []byte, which reads best as a string conversion.
+			fmt.Fprintf(b, "%s(%s)", typeString(t), strconv.Quote(string(v.Bytes())))
+			return
+		}
+		writeElems(b, v)
+	case reflect.Array:
+		writeElems(b, v)
+	case reflect.Map:
+		if v.IsNil() {
+			writeNil(b, t, typed)
+			return
+		}
+		var entries []string
+		for _, key := range v.MapKeys() {
+			var e strings.Builder
+			writeLiteral(&e, key, t.Key().Kind() != reflect.Interface)
+			e.WriteString(": ")
+			writeLiteral(&e, v.MapIndex(key), t.Elem().Kind() != reflect.Interface)
+			entries = append(entries, e.String())
+		}
+		// map iteration order is random, so sort for a stable result.
+		sort.Strings(entries)
+		fmt.Fprintf(b, "%s{%s}", typeString(t), strings.Join(entries, ", "))
+	case reflect.Struct:
+		b.WriteString(typeString(t))
+		b.WriteString("{")
+		first := true
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" || v.Field(i).IsZero() {
+				// unexported or zero.
+				continue
+			}
+			if !first {
+				b.WriteString(", ")
+			}
+			first = false
+			b.WriteString(f.Name)
+			b.WriteString(": ")
+			writeLiteral(b, v.Field(i), f.Type.Kind() != reflect.Interface)
+		}
+		b.WriteString("}")
+	case reflect.Ptr:
+		if v.IsNil() {
+			writeNil(b, t, typed)
+			return
+		}
+		var elem strings.Builder
+		writeLiteral(&elem, v.Elem(), true)
+		if strings.HasPrefix(elem.String(), typeString(t.Elem())+"{") {
+			// a composite literal, which we can take the address of.
+			b.WriteString("&")
+			b.WriteString(elem.String())
+			return
+		}
+		// Go does not have a literal for a pointer to other values, so use a func literal.
+		fmt.Fprintf(b, "func() %s { var v %s = %s; return &v }()", typeString(t), typeString(t.Elem()), elem.String())
+	case reflect.Interface:
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		writeLiteral(b, v.Elem(), false)
+	default:
+		// the elements of a chan cannot be seen without receiving them, and funcs and
+		// unsafe pointers are not filled in by a Fuzzer.
+		writeNil(b, t, typed)
+	}
+}
+
+// floatLiteral returns Go source for a float with the given number of bits, and reports whether it is a constant.
+// NaNs, infinities and negative zero do not have a constant, so they are calls to the math package.
+func floatLiteral(f float64, bits int) (string, bool) {
+	switch {
+	case math.IsNaN(f):
+		return "math.NaN()", false
+	case math.IsInf(f, 1):
+		return "math.Inf(1)", false
+	case math.IsInf(f, -1):
+		return "math.Inf(-1)", false
+	case f == 0 && math.Signbit(f):
+		return "math.Copysign(0, -1)", false
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".eIN") {
+		// keep a float constant, such as 1.0 rather than 1, so it reads as a float.
+		s += ".0"
+	}
+	return s, true
+}
+
+// writeConst writes a constant s of type t, converting it to t unless untyped is sufficient.
+func writeConst(b *strings.Builder, t reflect.Type, s string, untyped bool) {
+	if untyped {
+		b.WriteString(s)
+		return
+	}
+	fmt.Fprintf(b, "%s(%s)", typeString(t), s)
+}
+
+// writeNil writes nil, converted to t if needed.
+func writeNil(b *strings.Builder, t reflect.Type, typed bool) {
+	if typed {
+		b.WriteString("nil")
+		return
+	}
+	fmt.Fprintf(b, "%s(nil)", typeString(t))
+}
+
+// writeElems writes a composite literal for a slice or array.
+func writeElems(b *strings.Builder, v reflect.Value) {
+	b.WriteString(typeString(v.Type()))
+	b.WriteString("{")
+	elemTyped := v.Type().Elem().Kind() != reflect.Interface
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		writeLiteral(b, v.Index(i), elemTyped)
+	}
+	b.WriteString("}")
+}
+
+// typeString returns the Go syntax for a type. reflect reports []byte as []uint8,
+// which is an identical type, so we use the name that is more likely to match the user's code.
+func typeString(t reflect.Type) string {
+	s := t.String()
+	s = strings.Replace(s, "[]uint8", "[]byte", -1)
+	s = strings.Replace(s, "interface {}", "interface{}", -1)
+	return s
+}
@@ -0,0 +1,52 @@
+package randparam
+
+import (
+	"math"
+	"testing"
+)
+
+type literalStruct struct {
+	Name    string
+	Count   int8
+	Data    []byte
+	Next    *literalStruct
+	Any     interface{}
+	private int
+}
+
+func TestLiteral(t *testing.T) {
+	eight := int8(8)
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"nil", nil, "nil"},
+		{"string", "a\"b\n", `"a\"b\n"`},
+		{"int8", int8(-5), "-5"},
+		{"float", 2.0, "2.0"},
+		{"float32 NaN", float32(math.NaN()), "float32(math.NaN())"},
+		{"negative zero", math.Copysign(0, -1), "math.Copysign(0, -1)"},
+		{"subnormal float32", float32(math.SmallestNonzeroFloat32), "1e-45"},
+		{"complex with infinity", complex64(complex(1, math.Inf(-1))), "complex64(complex(1.0, math.Inf(-1)))"},
+		{"bytes", []byte("ab\x00"), `[]byte("ab\x00")`},
+		{"nil bytes", []byte(nil), "nil"},
+		{"slice", []uint16{1, 2}, "[]uint16{1, 2}"},
+		{"nested bytes", [][]byte{[]byte("a")}, `[][]byte{[]byte("a")}`},
+		{"map", map[string]bool{"b": true, "a": false}, `map[string]bool{"a": false, "b": true}`},
+		{"interface elems", []interface{}{int8(1), 2, "x", nil}, `[]interface{}{int8(1), 2, "x", nil}`},
+		{"pointer to int", &eight, "func() *int8 { var v int8 = 8; return &v }()"},
+		{
+			"struct",
+			&literalStruct{Name: "a", Data: []byte("b"), Next: &literalStruct{Count: 3}, Any: uint(4), private: 5},
+			`&randparam.literalStruct{Name: "a", Data: []byte("b"), Next: &randparam.literalStruct{Count: 3}, Any: uint(4)}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Literal(tt.v); got != tt.want {
+				t.Errorf("Literal() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
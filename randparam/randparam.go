@@ -2,75 +2,65 @@
 //
 // The primary use case is to allow fzgo to use dvyukov/go-fuzz to fuzz rich signatures such as:
 //    FuzzFunc(re string, input string, posix bool)
-// google/gofuzz is used to walk the structure of parameters, but randparam uses custom random generators,
+// randparam walks the structure of parameters via reflection and uses custom random generators,
 // including in the hopes of allowing dvyukov/go-fuzz literal injection to work,
 // as well as to better exploit the genetic mutations of dvyukov/go-fuzz, etc.
 package randparam
 
 import (
 	"fmt"
-	"math/rand"
-
-	gofuzz "github.com/google/gofuzz"
+	"math"
 )
 
 // Fuzzer generates random values for public members.
 // It wires together dvyukov/go-fuzz (for randomness, instrumentation, managing corpus, etc.)
-// with google/gofuzz (for walking a structure recursively), though it uses functions from
-// this package to actually fill in string, []byte, and number values.
+// with a reflection-based walker in this package (for walking a structure recursively),
+// which uses functions from this package to fill in string, []byte, and number values.
 type Fuzzer struct {
-	gofuzzFuzzer *gofuzz.Fuzzer
-}
+	fzgoSrc *randSource
+
+	// nilChance is the threshold for a byte drawn from the input below which
+	// a pointer, map, slice, chan or interface is left nil. Zero means never nil,
+	// in which case no byte is drawn, and 256 means always nil.
+	nilChance int
+
+	// minElements and maxElements bound the number of elements in a map, slice or chan.
+	// If they differ, a byte is drawn from the input to pick the number of elements.
+	minElements int
+	maxElements int
+
+	// maxDepth is how many pointers, maps, slices, chans and interfaces can be nested
+	// before values are left as their zero value, which bounds recursive types.
+	maxDepth int
 
-// randFuncs is a list of our custom variable generation functions
-// that tap into our custom random number generator to pull values from
-// the initial input []byte.
-var randFuncs = []interface{}{
-	randInt,
-	randInt8,
-	randInt16,
-	randInt32,
-	randInt64,
-	randUint,
-	randUint8,
-	randUint16,
-	randUint32,
-	randUint64,
-	randFloat32,
-	randFloat64,
-	randByte,
-	randRune,
+	// maxStringLength is the longest string or []byte. A longer length field is reduced to it.
+	maxStringLength int
+
+	// unexportedPkgs are the import paths of the packages whose unexported struct fields are filled.
+	unexportedPkgs map[string]bool
+
+	// mapKey is set while filling a map key, where an interface only holds comparable types.
+	mapKey bool
 }
 
-// NewFuzzer returns a *Fuzzer, initialized with the []byte as an input stream for drawing values via rand.Rand.
+const (
+	// defaultMaxDepth is the maximum nesting of pointers, maps, slices, chans and interfaces.
+	defaultMaxDepth = 10
+
+	// maxLength is the longest length field for a string or []byte. 0xFF encodes a zero length.
+	maxLength = 0xFE
+
+	// nilChance10 is a nilChance of roughly 10%.
+	nilChance10 = 26
+)
+
+// NewFuzzer returns a *Fuzzer, initialized with the []byte as an input stream for drawing values.
 func NewFuzzer(data []byte) *Fuzzer {
 	// create our random data stream that fill use data []byte for results.
 	fzgoSrc := &randSource{data}
-	randSrc := rand.New(fzgoSrc)
-
-	// create some closures for custom fuzzing (so that we have direct access to fzgoSrc).
-	randFuncsWithFzgoSrc := []interface{}{
-		func(ptr *[]byte, c gofuzz.Continue) {
-			randBytes(ptr, c, fzgoSrc)
-		},
-		func(ptr *string, c gofuzz.Continue) {
-			randString(ptr, c, fzgoSrc)
-		},
-		func(ptr *[]string, c gofuzz.Continue) {
-			randStringSlice(ptr, c, fzgoSrc)
-		},
-	}
-
-	// combine our two custom fuzz function lists.
-	funcs := append(randFuncs, randFuncsWithFzgoSrc...)
-
-	// create the google/gofuzz fuzzer
-	gofuzzFuzzer := gofuzz.New().RandSource(randSrc).Funcs(funcs...)
+	f := &Fuzzer{fzgoSrc: fzgoSrc, maxDepth: defaultMaxDepth, maxStringLength: maxLength}
 
-	// gofuzzFuzzer.NilChance(0).NumElements(2, 2)
-	// TODO: pick parameters for NilChance, NumElements, e.g.:
-	//     gofuzzFuzzer.NilChance(0.1).NumElements(0, 10)
-	// Initially allowing too much variability with NumElements seemed
+	// Initially allowing too much variability with the number of elements seemed
 	// to be a problem, but more likely that was an early indication of
 	// the need to better tune the exact string/[]byte encoding to work
 	// better with sonar.
@@ -79,15 +69,15 @@ func NewFuzzer(data []byte) *Fuzzer {
 	firstByte := fzgoSrc.Byte()
 	switch {
 	case firstByte < 32:
-		gofuzzFuzzer.NilChance(0).NumElements(2, 2)
+		f.nilChance, f.minElements, f.maxElements = 0, 2, 2
 	case firstByte < 64:
-		gofuzzFuzzer.NilChance(0).NumElements(1, 1)
+		f.nilChance, f.minElements, f.maxElements = 0, 1, 1
 	case firstByte < 96:
-		gofuzzFuzzer.NilChance(0).NumElements(3, 3)
+		f.nilChance, f.minElements, f.maxElements = 0, 3, 3
 	case firstByte < 128:
-		gofuzzFuzzer.NilChance(0).NumElements(4, 4)
+		f.nilChance, f.minElements, f.maxElements = 0, 4, 4
 	case firstByte <= 255:
-		gofuzzFuzzer.NilChance(0.1).NumElements(0, 10)
+		f.nilChance, f.minElements, f.maxElements = nilChance10, 0, 10
 	}
 
 	// TODO: probably delete the alternative string encoding code.
@@ -98,24 +88,114 @@ func NewFuzzer(data []byte) *Fuzzer {
 	// 	fzgoSrc.lengthEncodedStrings = false
 	// }
 
-	f := &Fuzzer{gofuzzFuzzer: gofuzzFuzzer}
 	return f
 }
 
-// Fuzz fills in public members of obj. For numbers, strings, []bytes, it tries to populate the
-// obj value with literals found in the initial input []byte.
+// Option configures a Fuzzer created by NewFuzzerWithOptions.
+type Option func(*Fuzzer)
+
+// NewFuzzerWithOptions returns a *Fuzzer like NewFuzzer, but configured by opts,
+// which take precedence over the configuration selected by the first byte of data.
+func NewFuzzerWithOptions(data []byte, opts ...Option) *Fuzzer {
+	f := NewFuzzer(data)
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// MaxElements limits the number of elements in maps, chans and slices other than []byte and []string
+// (which have a length field like a string) to n. When the first byte of the input selects a range
+// for the number of elements, the range becomes 0 to n.
+func MaxElements(n int) Option {
+	return func(f *Fuzzer) {
+		n = clamp(n, 0, 255)
+		if f.minElements == f.maxElements {
+			f.minElements = clamp(f.minElements, 0, n)
+			f.maxElements = f.minElements
+			return
+		}
+		f.minElements, f.maxElements = 0, n
+	}
+}
+
+// MaxDepth limits how many pointers, maps, slices, chans and interfaces are nested to n.
+// Values nested more deeply are left as their zero value.
+func MaxDepth(n int) Option {
+	return func(f *Fuzzer) {
+		if n < 0 {
+			n = 0
+		}
+		f.maxDepth = n
+	}
+}
+
+// NilChance sets the probability in [0, 1] that a pointer, map, slice, chan or interface is left nil,
+// where the decision is made by a byte drawn from the input unless p is 0.
+func NilChance(p float64) Option {
+	return func(f *Fuzzer) {
+		f.nilChance = clamp(int(math.Round(p*256)), 0, 256)
+	}
+}
+
+// MaxStringLength limits the length of strings and []byte to n, which is at most 254.
+// A longer length field drawn from the input is reduced to n.
+func MaxStringLength(n int) Option {
+	return func(f *Fuzzer) {
+		f.maxStringLength = clamp(n, 0, maxLength)
+	}
+}
+
+// FillUnexported fills the unexported struct fields declared in the packages with the given
+// import paths, such as the package under test, which reaches values that a package normally
+// only constructs internally. The fields are set via package unsafe. By default, unexported fields
+// are left as their zero values.
+func FillUnexported(pkgPaths ...string) Option {
+	return func(f *Fuzzer) {
+		if f.unexportedPkgs == nil {
+			f.unexportedPkgs = make(map[string]bool)
+		}
+		for _, path := range pkgPaths {
+			f.unexportedPkgs[path] = true
+		}
+	}
+}
+
+func clamp(n, lo, hi int) int {
+	switch {
+	case n < lo:
+		return lo
+	case n > hi:
+		return hi
+	}
+	return n
+}
+
+// Fuzz fills in public members of obj, which must be a non-nil pointer, as well as any unexported members
+// allowed by FillUnexported. For numbers, strings, []bytes,
+// it tries to populate the obj value with literals found in the initial input []byte.
 func (f *Fuzzer) Fuzz(obj interface{}) {
-	f.gofuzzFuzzer.Fuzz(obj)
+	f.fill(obj)
 }
 
-// Fill fills in public members of obj. For numbers, strings, []bytes, it tries to populate the
-// obj value with literals found in the initial input []byte.
+// Fill fills in public members of obj, which must be a non-nil pointer. For numbers, strings, []bytes,
+// it tries to populate the obj value with literals found in the initial input []byte.
 // TODO: decide to call this Fill or Fuzz or something else. We support both Fill and Fuzz for now.
 func (f *Fuzzer) Fill(obj interface{}) {
-	f.gofuzzFuzzer.Fuzz(obj)
+	f.fill(obj)
+}
+
+// Choose returns a value in [0, n) drawn from the initial input []byte, such as for
+// choosing among multiple implementations of an interface. It returns 0 without consuming
+// any input if n is 1 or less.
+func (f *Fuzzer) Choose(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	return int(f.fzgoSrc.Byte()) % n
 }
 
-// Override google/gofuzz fuzzing approach for strings, []byte, and numbers
+// Custom fill functions for strings, []byte, and numbers
 
 // randBytes is a custom fill function so that we have exact control over how
 // strings and []byte are encoded.
@@ -177,7 +257,9 @@ func (f *Fuzzer) Fill(obj interface{}) {
 //      * that non-zero byte is the actual length used, unless that non-zero byte
 //	      is 0xFF, in which case that signals a zero-length string/[]byte, and
 //      * the length value used must be able to draw enough real random bytes from the input []byte.
-func randBytes(ptr *[]byte, c gofuzz.Continue, fzgoSrc *randSource) {
+//
+// A length greater than maxLen is reduced to maxLen.
+func randBytes(ptr *[]byte, fzgoSrc *randSource, maxLen int) {
 	verbose := false // TODO: probably remove eventually.
 	if verbose {
 		fmt.Println("randBytes verbose:", verbose)
@@ -242,6 +324,9 @@ func randBytes(ptr *[]byte, c gofuzz.Continue, fzgoSrc *randSource) {
 		break
 	}
 
+	if size > maxLen {
+		size = maxLen
+	}
 	bs = make([]byte, size)
 	for i := range bs {
 		bs[i] = fzgoSrc.Byte()
@@ -251,15 +336,16 @@ func randBytes(ptr *[]byte, c gofuzz.Continue, fzgoSrc *randSource) {
 
 // randString is a custom fill function so that we have exact control over how
 // strings are encoded. It is a thin wrapper over randBytes.
-func randString(s *string, c gofuzz.Continue, fzgoSrc *randSource) {
+func randString(s *string, fzgoSrc *randSource, maxLen int) {
 	var bs []byte
-	randBytes(&bs, c, fzgoSrc)
+	randBytes(&bs, fzgoSrc, maxLen)
 	*s = string(bs)
 }
 
-// TODO: this might be temporary. Here we handle slices of strings as a preview of
-// improvements we might get by dropping google/gofuzz for walking some of the data structures.
-func randStringSlice(s *[]string, c gofuzz.Continue, fzgoSrc *randSource) {
+// randStringSlice fills a slice of strings, using a length field in the same way as randBytes
+// rather than the number of elements used for other slices, which works better with sonar
+// when hunting for a string in a slice.
+func randStringSlice(s *[]string, fzgoSrc *randSource, maxLen int) {
 	size, ok := calcSize(fzgoSrc)
 	if !ok {
 		*s = nil
@@ -268,7 +354,7 @@ func randStringSlice(s *[]string, c gofuzz.Continue, fzgoSrc *randSource) {
 	ss := make([]string, size)
 	for i := range ss {
 		var str string
-		randString(&str, c, fzgoSrc)
+		randString(&str, fzgoSrc, maxLen)
 		ss[i] = str
 	}
 	*s = ss
@@ -335,80 +421,37 @@ func calcSize(fzgoSrc *randSource) (size int, ok bool) {
 }
 
 // A set of custom numeric value filling funcs follows.
-// These are currently simple implementations that only use gofuzz.Continue
-// as a source for data, which means obtaining 64-bits of the input stream
-// at a time. For sizes < 64 bits, this could be tighted up to waste less of the input stream
-// by getting access to fzgo/randparam.randSource.
+// Each reads exactly the width of the numeric type from the input []byte
+// in little-endian order, such as 1 byte for an int8 or uint8, 2 bytes for
+// an int16 or uint16, and 8 bytes for an int or uint64 on a 64-bit platform.
+// Signed values use two's complement. This means a numeric value appears in the
+// input []byte exactly as it does in memory, which lets go-fuzz sonar find an observed
+// value in the input and replace it in place with a more interesting value of the same width,
+// without disturbing the values that follow, and means we do not waste input bytes.
 //
-// Once the end of the input []byte is reached, zeros are drawn, including
-// if in the middle of obtaining bytes for a >1 bye number.
-// Tt is probably ok to draw zeros past the end
-// for numbers because we use a little endian interpretation
-// for numbers (which means if we find byte 0x1 then that's the end
-// and we draw zeros for say a uint32, the result is 1; sonar
-// seems to guess the length of numeric values, so it likely
-// works end to end even if we draw zeros.
+// Once the end of the input []byte is reached, the remaining bytes are used,
+// which is the same as drawing zeros for the high-order bytes.
+// For example, if a single 0x1 remains for a uint32, the result is 1.
+// Sonar seems to guess the length of numeric values, so it likely works end to end
+// even with the missing bytes.
 // TODO: The next bytes appended (via some mutation) after a number can change
 // the result (e.g., if a 0x2 is appended in example above, result is no longer 1),
 // so maybe better to also not draw zeros for numeric values?
 
-func randInt(val *int, c gofuzz.Continue) {
-	*val = int(c.Rand.Uint64())
-}
-
-func randInt8(val *int8, c gofuzz.Continue) {
-	*val = int8(c.Rand.Uint64())
-}
-
-func randInt16(val *int16, c gofuzz.Continue) {
-	*val = int16(c.Rand.Uint64())
-}
-
-func randInt32(val *int32, c gofuzz.Continue) {
-	*val = int32(c.Rand.Uint64())
+// randInt returns a signed integer of the given width in bytes.
+func randInt(fzgoSrc *randSource, width int) int64 {
+	u := fzgoSrc.Uint(width)
+	// sign extend from width bytes.
+	shift := uint(64 - 8*width)
+	return int64(u<<shift) >> shift
 }
 
-func randInt64(val *int64, c gofuzz.Continue) {
-	*val = int64(c.Rand.Uint64())
+// randUint returns an unsigned integer of the given width in bytes.
+func randUint(fzgoSrc *randSource, width int) uint64 {
+	return fzgoSrc.Uint(width)
 }
 
-func randUint(val *uint, c gofuzz.Continue) {
-	*val = uint(c.Rand.Uint64())
+// randBool draws a byte, and is true if the low bit is set.
+func randBool(fzgoSrc *randSource) bool {
+	return fzgoSrc.Byte()&1 == 1
 }
-
-func randUint8(val *uint8, c gofuzz.Continue) {
-	*val = uint8(c.Rand.Uint64())
-}
-
-func randUint16(val *uint16, c gofuzz.Continue) {
-	*val = uint16(c.Rand.Uint64())
-}
-
-func randUint32(val *uint32, c gofuzz.Continue) {
-	*val = uint32(c.Rand.Uint64())
-}
-
-func randUint64(val *uint64, c gofuzz.Continue) {
-	*val = uint64(c.Rand.Uint64())
-}
-
-func randFloat32(val *float32, c gofuzz.Continue) {
-	*val = float32(c.Rand.Uint64())
-}
-
-func randFloat64(val *float64, c gofuzz.Continue) {
-	*val = float64(c.Rand.Uint64())
-}
-
-func randByte(val *byte, c gofuzz.Continue) {
-	*val = byte(c.Rand.Uint64())
-}
-
-func randRune(val *rune, c gofuzz.Continue) {
-	*val = rune(c.Rand.Uint64())
-}
-
-// Note: complex64, complex128, uintptr are not supported by google/gofuzz, I think.
-// TODO: Interfaces are also not currently supported by google/gofuzz, or at least not
-// easily as far as I am aware. That said, currently have most of the pieces elsewhere
-// for us to handle common interfaces like io.Writer, io.Reader, etc.
@@ -1,7 +1,11 @@
 package randparam
 
 import (
+	"bytes"
 	"encoding/binary"
+	"math"
+	"math/rand"
+	"reflect"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -191,4 +195,409 @@ func TestFuzzingParams(t *testing.T) {
 			t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
 		}
 	})
+
+	t.Run("choose - 1 byte input", func(t *testing.T) {
+		input := []byte{0x0, 0x05}
+		want := 2
+
+		fuzzer := NewFuzzer(input)
+		got := fuzzer.Choose(3)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fuzzer.Choose() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("choose - single choice does not consume input", func(t *testing.T) {
+		input := []byte{0x0, 0x42}
+		want := uint8(0x42)
+
+		fuzzer := NewFuzzer(input)
+		if got := fuzzer.Choose(1); got != 0 {
+			t.Errorf("fuzzer.Choose(1) = %d, want 0", got)
+		}
+		var got uint8
+		fuzzer.Fuzz(&got)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("complex128 - two floats", func(t *testing.T) {
+		input := []byte{0x0, 0x80, 0x3, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0xF1}
+		want := complex(3, math.Inf(1))
+
+		fuzzer := NewFuzzer(input)
+		var got complex128
+		fuzzer.Fuzz(&got)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("float64 - raw bits, whole number, special values", func(t *testing.T) {
+		input := []byte{0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}
+		binary.LittleEndian.PutUint64(input[2:], math.Float64bits(-1.5))
+		input = append(input, 0x80, 0xFE, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+		input = append(input, 0xF3, 0xF4, 0xF0)
+		want := []float64{-1.5, -2, math.Copysign(0, -1), 5e-324, math.NaN()}
+
+		fuzzer := NewFuzzer(input)
+		var got []float64
+		for range want {
+			var f float64
+			fuzzer.Fuzz(&f)
+			got = append(got, f)
+		}
+		for i := range want {
+			if math.Float64bits(got[i]) != math.Float64bits(want[i]) && !(math.IsNaN(got[i]) && math.IsNaN(want[i])) {
+				t.Errorf("fuzzer.Fuzz() float %d = %v, want %v", i, got[i], want[i])
+			}
+		}
+	})
+
+	t.Run("float32 - 4 bytes of raw bits", func(t *testing.T) {
+		input := []byte{0x0, 0x0, 0x0, 0x0, 0x0, 0x0}
+		binary.LittleEndian.PutUint32(input[2:], math.Float32bits(0.1))
+		want := float32(0.1)
+
+		fuzzer := NewFuzzer(input)
+		var got float32
+		fuzzer.Fuzz(&got)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("uintptr - 1 byte input", func(t *testing.T) {
+		input := []byte{0x0, 0x42}
+		want := uintptr(0x42)
+
+		fuzzer := NewFuzzer(input)
+		var got uintptr
+		fuzzer.Fuzz(&got)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("chan - buffered with elements", func(t *testing.T) {
+		// first byte of 0x20 selects exactly 1 element.
+		input := []byte{0x20, 0x1, 0x2}
+		want := []string{"\x02"}
+
+		fuzzer := NewFuzzer(input)
+		var ch <-chan string
+		fuzzer.Fuzz(&ch)
+		var got []string
+		for len(ch) > 0 {
+			got = append(got, <-ch)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("interface - chosen by 1 byte", func(t *testing.T) {
+		input := []byte{0x0, 0x2, 0x7}
+		var want interface{} = 7
+
+		fuzzer := NewFuzzer(input)
+		var got interface{}
+		fuzzer.Fuzz(&got)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("interface - map key only holds comparable types", func(t *testing.T) {
+		// first byte of 0x20 selects exactly 1 element. a choice of 1 is an int
+		// among the comparable types, rather than a []byte.
+		input := []byte{0x20, 0x1, 0x7, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1}
+		want := map[interface{}]bool{7: true}
+
+		fuzzer := NewFuzzer(input)
+		var got map[interface{}]bool
+		fuzzer.Fuzz(&got)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
+		}
+
+		// a key with an interface nested in an array or struct does not panic either.
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < 1000; i++ {
+			input := make([]byte, r.Intn(64))
+			r.Read(input)
+			fuzzer := NewFuzzer(input)
+			var m map[struct{ K [2]interface{} }]interface{}
+			fuzzer.Fuzz(&m)
+		}
+	})
+
+	t.Run("nested pointers - filled until max depth", func(t *testing.T) {
+		type list struct {
+			Next *list
+		}
+		input := []byte{0x0}
+
+		fuzzer := NewFuzzer(input)
+		var got *list
+		fuzzer.Fuzz(&got)
+		depth := 0
+		for l := got; l != nil; l = l.Next {
+			depth++
+		}
+		if depth != defaultMaxDepth {
+			t.Errorf("fuzzer.Fuzz() filled %d nested pointers, want %d", depth, defaultMaxDepth)
+		}
+	})
+
+	t.Run("slice - number of elements limited by remaining input", func(t *testing.T) {
+		// first byte of 0x80 allows 0 to 10 elements.
+		input := []byte{0x80, 0xFF, 0xA, 0x1, 0x2, 0x3}
+		want := []bool{true, false, true}
+
+		fuzzer := NewFuzzer(input)
+		var got []bool
+		fuzzer.Fuzz(&got)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("options - no nil decision and fewer elements", func(t *testing.T) {
+		// first byte of 0x80 allows 0 to 10 elements, which MaxElements reduces to 0 to 5.
+		// with NilChance(0), there is no byte for the nil decision.
+		input := []byte{0x80, 0x9, 0x1, 0x2, 0x3}
+		want := []bool{true, false, true}
+
+		fuzzer := NewFuzzerWithOptions(input, NilChance(0), MaxElements(5))
+		var got []bool
+		fuzzer.Fuzz(&got)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("options - length field reduced to max string length", func(t *testing.T) {
+		input := []byte{0x0, 0x5, 'a', 'b', 'c', 'd', 'e'}
+		want := "ab"
+
+		fuzzer := NewFuzzerWithOptions(input, MaxStringLength(2))
+		var got string
+		fuzzer.Fuzz(&got)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("options - unexported fields", func(t *testing.T) {
+		type inner struct {
+			Exported int8
+			secret   int8
+		}
+		type outer struct {
+			inner
+			count int8
+			Name  string
+		}
+		input := []byte{0x0, 0x1, 0x2, 0x3, 0x1, 'a'}
+		pkgPath := reflect.TypeOf(outer{}).PkgPath()
+
+		fuzzer := NewFuzzerWithOptions(input, FillUnexported(pkgPath))
+		var got outer
+		fuzzer.Fuzz(&got)
+		want := outer{inner: inner{Exported: 1, secret: 2}, count: 3, Name: "a"}
+		if diff := cmp.Diff(want, got, cmp.AllowUnexported(outer{}, inner{})); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
+		}
+
+		// by default, unexported fields are left as zero values, including an embedded
+		// struct with an unexported type, and are not drawn from the input.
+		fuzzer = NewFuzzer(input)
+		got = outer{}
+		fuzzer.Fuzz(&got)
+		want = outer{Name: "\x02"}
+		if diff := cmp.Diff(want, got, cmp.AllowUnexported(outer{}, inner{})); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("int8 - 1 byte per value", func(t *testing.T) {
+		input := []byte{0x0, 0xFF, 0x7F}
+		want := []int8{-1, 127}
+
+		fuzzer := NewFuzzer(input)
+		var got1, got2 int8
+		fuzzer.Fuzz(&got1)
+		fuzzer.Fuzz(&got2)
+		if diff := cmp.Diff(want, []int8{got1, got2}); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("uint16 - 2 bytes little-endian, then next value", func(t *testing.T) {
+		input := []byte{0x0, 0x34, 0x12, 0x42}
+		want1 := uint16(0x1234)
+		want2 := uint8(0x42)
+
+		fuzzer := NewFuzzer(input)
+		var got1 uint16
+		var got2 uint8
+		fuzzer.Fuzz(&got1)
+		fuzzer.Fuzz(&got2)
+		if diff := cmp.Diff(want1, got1); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want1 +got1):\n%s", diff)
+		}
+		if diff := cmp.Diff(want2, got2); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want2 +got2):\n%s", diff)
+		}
+	})
+
+	t.Run("int32 - negative, then string", func(t *testing.T) {
+		input := []byte{0x0, 0xFE, 0xFF, 0xFF, 0xFF, 0x2, 'h', 'i'}
+		want1 := int32(-2)
+		want2 := "hi"
+
+		fuzzer := NewFuzzer(input)
+		var got1 int32
+		var got2 string
+		fuzzer.Fuzz(&got1)
+		fuzzer.Fuzz(&got2)
+		if diff := cmp.Diff(want1, got1); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want1 +got1):\n%s", diff)
+		}
+		if diff := cmp.Diff(want2, got2); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want2 +got2):\n%s", diff)
+		}
+	})
+
+	t.Run("struct - fields read at their own widths", func(t *testing.T) {
+		type header struct {
+			Version uint8
+			Flags   uint16
+			Length  uint32
+		}
+		input := []byte{0x0, 0x2, 0x01, 0x80, 0x10, 0x00, 0x00, 0x00}
+		want := header{Version: 2, Flags: 0x8001, Length: 16}
+
+		fuzzer := NewFuzzer(input)
+		var got header
+		fuzzer.Fuzz(&got)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+// BenchmarkHardToGuessNumber measures the executions needed to solve a FuzzHardToGuessNumber-style
+// target, which panics if its numeric parameter equals a hard to guess value, using a simple loop
+// of random byte mutations plus a sonar-style step. Like go-fuzz sonar, the sonar step takes the
+// value the target compared against its hard to guess value, looks for that value in the input
+// in little-endian form, and replaces it in place. The execs/solve metric is the interesting result.
+// The uint64-draw sub-benchmarks use the prior encoding, which drew 8 bytes for every number
+// regardless of its width, for comparison with the exact-width encoding used by Fuzzer.
+func BenchmarkHardToGuessNumber(b *testing.B) {
+	type header struct {
+		Version uint8
+		Length  uint32
+	}
+	targets := []struct {
+		name    string
+		guessMe interface{}
+	}{
+		{"uint16", uint16(0xBEEF)},
+		{"int32", int32(-0x1234567)},
+		{"uint64", uint64(0x123456789)},
+		{"struct", header{Version: 3, Length: 0xCAFE}},
+	}
+	for _, target := range targets {
+		b.Run(target.name+"/exact-width", func(b *testing.B) {
+			benchmarkHardToGuess(b, target.guessMe, func(data []byte, v reflect.Value) { NewFuzzer(data).Fuzz(v.Interface()) })
+		})
+		b.Run(target.name+"/uint64-draw", func(b *testing.B) {
+			benchmarkHardToGuess(b, target.guessMe, fillUint64Draw)
+		})
+	}
+}
+
+func benchmarkHardToGuess(b *testing.B, guessMe interface{}, fill func(data []byte, v reflect.Value)) {
+	r := rand.New(rand.NewSource(1))
+	want := reflect.ValueOf(guessMe)
+	var execs int
+	for i := 0; i < b.N; i++ {
+		input := []byte{0x0}
+		for {
+			execs++
+			got := reflect.New(want.Type())
+			fill(input, got)
+			if got.Elem().Interface() == guessMe {
+				break
+			}
+			if !sonarReplace(input, got.Elem(), want) {
+				// mutate by changing or appending a random byte.
+				pos := 1 + r.Intn(len(input))
+				if pos == len(input) {
+					input = append(input, 0)
+				}
+				input[pos] = byte(r.Intn(256))
+			}
+		}
+	}
+	b.ReportMetric(float64(execs)/float64(b.N), "execs/solve")
+}
+
+// fillUint64Draw fills the integers and structs of integers pointed to by v using the prior encoding,
+// which skipped the first byte like Fuzzer and then drew 8 bytes via randSource.Uint64 for each number,
+// truncating the result to the width of the number.
+func fillUint64Draw(data []byte, v reflect.Value) {
+	src := &randSource{data}
+	src.Byte()
+	var fill func(v reflect.Value)
+	fill = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				fill(v.Field(i))
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.SetInt(int64(src.Uint64()))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.SetUint(src.Uint64())
+		default:
+			panic("fillUint64Draw: unsupported kind " + v.Kind().String())
+		}
+	}
+	fill(v.Elem())
+}
+
+// sonarReplace replaces the first number in got that differs from want, as observed in a comparison,
+// with the corresponding number from want, if the value from got is found in input at its width.
+// It reports whether a replacement was made.
+func sonarReplace(input []byte, got, want reflect.Value) bool {
+	if got.Kind() == reflect.Struct {
+		for i := 0; i < got.NumField(); i++ {
+			if got.Field(i).Interface() != want.Field(i).Interface() {
+				return sonarReplace(input, got.Field(i), want.Field(i))
+			}
+		}
+		return false
+	}
+	width := int(got.Type().Size())
+	le := func(v reflect.Value) []byte {
+		var u uint64
+		if v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64 {
+			u = uint64(v.Int())
+		} else {
+			u = v.Uint()
+		}
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, u)
+		return buf[:width]
+	}
+	i := bytes.Index(input[1:], le(got))
+	if i < 0 {
+		return false
+	}
+	copy(input[1+i:], le(want))
+	return true
 }
@@ -0,0 +1,312 @@
+package randparam
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Shape constrains the strings a Fuzzer fills in, such as to valid UTF-8 for a target
+// that immediately rejects anything else. A shape applies to strings, []byte and []string,
+// including those nested within pointers, slices, arrays, maps and interfaces,
+// but not to the fields of a struct, which take their shape from a struct tag such as:
+//
+//    Name string `fzgo:"ident"`
+//    Email string `fzgo:"regexp=^[a-z]+@[a-z]+\\.com$"`
+//
+// A string is first drawn from the input as usual, and kept as is if it already has the shape,
+// which means the input for a string of the shape is the same as without a shape, and sonar
+// and dictionaries work as usual. Otherwise, the string is transformed into one that has the shape.
+type Shape string
+
+const (
+	// AnyString is any sequence of bytes, including invalid UTF-8.
+	AnyString Shape = ""
+
+	// UTF8 is valid UTF-8. An invalid byte is treated as a Latin-1 character.
+	UTF8 Shape = "utf8"
+
+	// ASCII is 7-bit ASCII. A byte with the high bit set has it cleared.
+	ASCII Shape = "ascii"
+
+	// Ident is an identifier of ASCII letters, digits and underscores that does not start with a digit.
+	Ident Shape = "ident"
+
+	// Number is decimal integer text with an optional leading minus sign, such as "-42".
+	Number Shape = "number"
+
+	regexpShape = "regexp="
+)
+
+// Regexp is strings that match the regular expression expr in full,
+// as if it were anchored at both ends. A string that does not match is replaced with one
+// generated from the structure of expr using the string's bytes for the choices it makes.
+// Generating matches for empty-width assertions other than ^ and $ is best effort.
+func Regexp(expr string) Shape {
+	return Shape(regexpShape + expr)
+}
+
+// Validate returns an error if shape is not valid, such as a regexp that does not compile
+// or an unknown shape in a struct tag.
+func (shape Shape) Validate() error {
+	_, err := compileShape(shape)
+	return err
+}
+
+// FuzzShape fills in obj like Fuzz, with strings of the given shape.
+// It panics if shape is not valid, such as a regexp that does not compile.
+func (f *Fuzzer) FuzzShape(obj interface{}, shape Shape) {
+	sh, err := compileShape(shape)
+	if err != nil {
+		panic(fmt.Sprintf("randparam: %v", err))
+	}
+	f.fillShape(obj, sh)
+}
+
+// Shaped is an argument to Encode for a value filled in by FuzzShape with Shape.
+type Shaped struct {
+	Value interface{}
+	Shape Shape
+}
+
+// shaper applies a compiled Shape. A nil *shaper is AnyString.
+type shaper struct {
+	shape Shape
+	re    *syntax.Regexp // simplified, for generating matches for a regexp
+	match *regexp.Regexp // anchored, for checking matches for a regexp
+}
+
+// shapeCache holds the *shaper for each Shape that has been compiled,
+// given struct tags are read each time a struct is filled.
+var shapeCache sync.Map
+
+// compileShape returns the *shaper for shape, or nil for AnyString.
+func compileShape(shape Shape) (*shaper, error) {
+	if shape == AnyString {
+		return nil, nil
+	}
+	if sh, ok := shapeCache.Load(shape); ok {
+		return sh.(*shaper), nil
+	}
+	sh := &shaper{shape: shape}
+	switch {
+	case shape == UTF8, shape == ASCII, shape == Ident, shape == Number:
+	case strings.HasPrefix(string(shape), regexpShape):
+		expr := strings.TrimPrefix(string(shape), regexpShape)
+		re, err := syntax.Parse(expr, syntax.Perl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shape %q: %v", shape, err)
+		}
+		sh.re = re.Simplify()
+		if sh.match, err = regexp.Compile(`^(?:` + expr + `)$`); err != nil {
+			return nil, fmt.Errorf("invalid shape %q: %v", shape, err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown shape %q", shape)
+	}
+	shapeCache.Store(shape, sh)
+	return sh, nil
+}
+
+// tagShape returns the *shaper for the 'fzgo' tag of struct field sf, or nil if it has none.
+// It panics if the tag is not a valid shape. fzgo reports invalid tags when it creates
+// a rich signature wrapper, so this is only reachable when using a Fuzzer directly.
+func tagShape(sf reflect.StructField) *shaper {
+	sh, err := compileShape(Shape(sf.Tag.Get("fzgo")))
+	if err != nil {
+		panic(fmt.Sprintf("randparam: field %s: %v", sf.Name, err))
+	}
+	return sh
+}
+
+// has reports whether s has the shape.
+func (sh *shaper) has(s string) bool {
+	if sh == nil {
+		return true
+	}
+	switch sh.shape {
+	case UTF8:
+		return utf8.ValidString(s)
+	case ASCII:
+		for i := 0; i < len(s); i++ {
+			if s[i] >= utf8.RuneSelf {
+				return false
+			}
+		}
+		return true
+	case Ident:
+		if s == "" {
+			return false
+		}
+		for i := 0; i < len(s); i++ {
+			if !isIdentByte(s[i], i == 0) {
+				return false
+			}
+		}
+		return true
+	case Number:
+		digits := strings.TrimPrefix(s, "-")
+		if digits == "" {
+			return false
+		}
+		for i := 0; i < len(digits); i++ {
+			if digits[i] < '0' || digits[i] > '9' {
+				return false
+			}
+		}
+		return true
+	}
+	return sh.match.MatchString(s)
+}
+
+// apply returns s if it has the shape, and otherwise a string with the shape derived from s.
+func (sh *shaper) apply(s string) string {
+	if sh.has(s) {
+		return s
+	}
+	var b strings.Builder
+	switch sh.shape {
+	case UTF8:
+		for i := 0; i < len(s); {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				r = rune(s[i])
+			}
+			b.WriteRune(r)
+			i += size
+		}
+	case ASCII:
+		for i := 0; i < len(s); i++ {
+			b.WriteByte(s[i] &^ utf8.RuneSelf)
+		}
+	case Ident:
+		if s == "" {
+			return "x"
+		}
+		for i := 0; i < len(s); i++ {
+			c := s[i]
+			switch {
+			case isIdentByte(c, i == 0):
+			case i == 0:
+				c = identBytes[int(c)%identFirst]
+			default:
+				c = identBytes[int(c)%len(identBytes)]
+			}
+			b.WriteByte(c)
+		}
+	case Number:
+		if s == "" || s == "-" {
+			return "0"
+		}
+		for i := 0; i < len(s); i++ {
+			c := s[i]
+			switch {
+			case i == 0 && c == '-', c >= '0' && c <= '9':
+			default:
+				c = '0' + c%10
+			}
+			b.WriteByte(c)
+		}
+	default:
+		genRegexp(&b, sh.re, &randSource{[]byte(s)})
+	}
+	return b.String()
+}
+
+// identBytes are the bytes in an Ident, where the first identFirst can start one.
+const (
+	identBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_0123456789"
+	identFirst = 53
+)
+
+func isIdentByte(c byte, first bool) bool {
+	i := strings.IndexByte(identBytes, c)
+	return i >= 0 && (!first || i < identFirst)
+}
+
+// maxRepeat is the most repetitions generated for '*' or '+' in a regexp.
+const maxRepeat = 10
+
+// genRegexp writes a string that matches re to b, drawing its choices from src.
+// Running out of input means the shortest choices, such as zero repetitions
+// and the first alternative.
+func genRegexp(b *strings.Builder, re *syntax.Regexp, src *randSource) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			b.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		b.WriteRune(chooseRune(re.Rune, src))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		// printable ASCII, which covers the common case of '.' without going far afield.
+		b.WriteByte(' ' + src.Byte()%('~'-' '+1))
+	case syntax.OpCapture:
+		genRegexp(b, re.Sub[0], src)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest:
+		n := int(src.Byte())
+		switch re.Op {
+		case syntax.OpStar:
+			n %= maxRepeat + 1
+		case syntax.OpPlus:
+			n = 1 + n%maxRepeat
+		case syntax.OpQuest:
+			n %= 2
+		}
+		for i := 0; i < n; i++ {
+			genRegexp(b, re.Sub[0], src)
+		}
+	case syntax.OpRepeat:
+		// Simplify removes these, but handle them anyway.
+		n := re.Min
+		if re.Max > re.Min {
+			n += int(src.Byte()) % (re.Max - re.Min + 1)
+		}
+		for i := 0; i < n; i++ {
+			genRegexp(b, re.Sub[0], src)
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			genRegexp(b, sub, src)
+		}
+	case syntax.OpAlternate:
+		genRegexp(b, re.Sub[int(src.Byte())%len(re.Sub)], src)
+	default:
+		// empty-width assertions, and OpNoMatch, which nothing matches.
+	}
+}
+
+// chooseRune returns a rune from the ranges of a char class, which are pairs of
+// the lowest and highest runes in each range.
+func chooseRune(ranges []rune, src *randSource) rune {
+	var total uint64
+	for i := 0; i < len(ranges); i += 2 {
+		total += uint64(ranges[i+1]-ranges[i]) + 1
+	}
+	if total == 0 {
+		return utf8.RuneError
+	}
+	var n uint64
+	if total <= 256 {
+		n = uint64(src.Byte()) % total
+	} else {
+		n = src.Uint(4) % total
+	}
+	for i := 0; i < len(ranges); i += 2 {
+		size := uint64(ranges[i+1]-ranges[i]) + 1
+		if n < size {
+			r := ranges[i] + rune(n)
+			if !utf8.ValidRune(r) {
+				// a surrogate, which cannot be encoded in UTF-8.
+				return ranges[0]
+			}
+			return r
+		}
+		n -= size
+	}
+	return ranges[0]
+}
@@ -0,0 +1,146 @@
+package randparam
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestShapeApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		shape Shape
+		in    string
+		want  string
+	}{
+		{"utf8 - valid kept", UTF8, "héllo 世界", "héllo 世界"},
+		{"utf8 - invalid byte as Latin-1", UTF8, "a\xE9b\xFF", "aébÿ"},
+		{"ascii - valid kept", ASCII, "a+b\x00", "a+b\x00"},
+		{"ascii - high bit cleared", ASCII, "a\xE1\xFF", "aa\x7F"},
+		{"ident - valid kept", Ident, "_fooBar9", "_fooBar9"},
+		{"ident - empty", Ident, "", "x"},
+		{"ident - leading digit and punctuation", Ident, "9a-b", "eaTb"},
+		{"number - valid kept", Number, "-0042", "-0042"},
+		{"number - empty", Number, "", "0"},
+		{"number - minus only", Number, "-", "0"},
+		{"number - non-digits", Number, "1a-", "175"},
+		{"regexp - match kept", Regexp(`[a-z]+@[a-z]+\.com`), "bob@example.com", "bob@example.com"},
+		{"regexp - shortest when out of input", Regexp(`[a-z]+@[a-z]+\.com`), "", "a@a.com"},
+		{"regexp - choices from bytes", Regexp(`(GET|POST) /[0-9]{2}`), "\x01\x03\x04", "POST /34"},
+		{"regexp - anchors", Regexp(`^x?y$`), "\x01", "xy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh, err := compileShape(tt.shape)
+			if err != nil {
+				t.Fatalf("compileShape() error = %v", err)
+			}
+			got := sh.apply(tt.in)
+			if got != tt.want {
+				t.Errorf("apply(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if !sh.has(got) {
+				t.Errorf("has(%q) = false, want true", got)
+			}
+		})
+	}
+}
+
+func TestShapeApplyRandom(t *testing.T) {
+	shapes := []Shape{UTF8, ASCII, Ident, Number, Regexp(`[a-f0-9]{8}-[^x]*(\.(json|yaml))?`), Regexp(`(?i)k+|v*`)}
+	r := rand.New(rand.NewSource(1))
+	for _, shape := range shapes {
+		sh, err := compileShape(shape)
+		if err != nil {
+			t.Fatalf("compileShape(%q) error = %v", shape, err)
+		}
+		for i := 0; i < 1000; i++ {
+			b := make([]byte, r.Intn(20))
+			r.Read(b)
+			if got := sh.apply(string(b)); !sh.has(got) {
+				t.Fatalf("apply(%q) = %q, which does not have shape %q", b, got, shape)
+			}
+		}
+	}
+}
+
+func TestFuzzShape(t *testing.T) {
+	type account struct {
+		Name   string   `fzgo:"ident"`
+		Tags   []string `fzgo:"ascii"`
+		Amount string   `fzgo:"number"`
+		Note   string
+	}
+	input := []byte{0x0, 0x2, '9', 'a', 0x1, 0x1, 0xE1, 0x1, 'x', 0x2, 0xE1, 'x'}
+	want := account{Name: "ea", Tags: []string{"a"}, Amount: "0", Note: "\xE1x"}
+
+	fuzzer := NewFuzzer(input)
+	var got account
+	fuzzer.Fuzz(&got)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("fuzzer.Fuzz() mismatch (-want +got):\n%s", diff)
+	}
+
+	// a shape for a parameter applies within slices and maps, but not to struct fields.
+	fuzzer = NewFuzzer([]byte{0x20, 0x1, 0xE9, 0x1, 0xE9, 0x1, 0xE9, 0x1, 0xE9})
+	var m map[string][]account
+	fuzzer.FuzzShape(&m, UTF8)
+	if _, ok := m["é"]; !ok || len(m) != 1 {
+		t.Errorf("fuzzer.FuzzShape() = %q, want a single key of \"é\"", m)
+	}
+}
+
+func TestEncodeShapes(t *testing.T) {
+	type request struct {
+		Method string `fzgo:"regexp=GET|POST"`
+		Path   []byte `fzgo:"regexp=(/[a-z]+)+"`
+		ID     string `fzgo:"number"`
+		Body   string
+	}
+	args := []Shaped{
+		{"héllo", UTF8},
+		{[]string{"a", "b"}, Ident},
+		{map[string]int{"-1": 1}, Number},
+		{request{Method: "POST", Path: []byte("/a/bc"), ID: "-7", Body: "\xFF"}, AnyString},
+		{"2020-01-02", Regexp(`\d{4}-\d{2}-\d{2}`)},
+	}
+	var encodeArgs []interface{}
+	for _, arg := range args {
+		encodeArgs = append(encodeArgs, arg)
+	}
+	data, err := Encode(encodeArgs...)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	fuzzer := NewFuzzer(data)
+	for i, arg := range args {
+		got := reflect.New(reflect.TypeOf(arg.Value))
+		fuzzer.FuzzShape(got.Interface(), arg.Shape)
+		if diff := cmp.Diff(arg.Value, got.Elem().Interface()); diff != "" {
+			t.Errorf("arg %d mismatch after decoding %x (-want +got):\n%s", i, data, diff)
+		}
+	}
+
+	errTests := []struct {
+		name string
+		arg  interface{}
+		want string
+	}{
+		{"invalid utf8", Shaped{"\xFF", UTF8}, "does not have shape"},
+		{"not a number", Shaped{[]string{"12", "1.5"}, Number}, "does not have shape"},
+		{"regexp mismatch in tag", request{Method: "PUT"}, "field Method"},
+		{"unknown shape", Shaped{"x", "hex"}, "unknown shape"},
+		{"bad regexp", Shaped{"x", Regexp("(")}, "invalid shape"},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Encode(tt.arg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Encode() error = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
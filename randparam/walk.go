@@ -0,0 +1,220 @@
+package randparam
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+	"unsafe"
+)
+
+// interfaceValues are the types of the values we consider for an interface, in the order
+// they are chosen by a byte drawn from the input. Only the types that implement
+// a particular interface are considered, so for example an interface{} can hold any of them,
+// but a fmt.Stringer holds none of them and is left nil.
+var interfaceValues = []reflect.Type{
+	reflect.TypeOf(""),
+	reflect.TypeOf([]byte(nil)),
+	reflect.TypeOf(int(0)),
+	reflect.TypeOf(float64(0)),
+	reflect.TypeOf(false),
+	reflect.TypeOf([]interface{}(nil)),
+	reflect.TypeOf(map[string]interface{}(nil)),
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// fill fills in the value pointed to by obj.
+func (f *Fuzzer) fill(obj interface{}) {
+	f.fillShape(obj, nil)
+}
+
+// fillShape fills in the value pointed to by obj, with strings of shape sh.
+func (f *Fuzzer) fillShape(obj interface{}, sh *shaper) {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		panic(fmt.Sprintf("randparam: Fuzz requires a non-nil pointer, got %T", obj))
+	}
+	f.walk(v.Elem(), 0, sh)
+}
+
+// walk fills in v, which must be settable. depth is the number of pointers, maps, slices,
+// chans and interfaces we are nested within, and sh is the shape of any strings,
+// which a struct field replaces with the shape from its tag. The order of draws from the input here
+// must match encoder.encode.
+func (f *Fuzzer) walk(v reflect.Value, depth int, sh *shaper) {
+	t := v.Type()
+	if t == timeType {
+		// time.Time only has unexported fields, so we fill it via its seconds and nanoseconds.
+		sec, nsec := randInt(f.fzgoSrc, 8), randInt(f.fzgoSrc, 8)
+		v.Set(reflect.ValueOf(time.Unix(sec, nsec)))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(randBool(f.fzgoSrc))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(randInt(f.fzgoSrc, int(t.Size())))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(randUint(f.fzgoSrc, int(t.Size())))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(randFloat(f.fzgoSrc, int(t.Size())))
+	case reflect.Complex64, reflect.Complex128:
+		re := randFloat(f.fzgoSrc, int(t.Size())/2)
+		im := randFloat(f.fzgoSrc, int(t.Size())/2)
+		v.SetComplex(complex(re, im))
+	case reflect.String:
+		var s string
+		randString(&s, f.fzgoSrc, f.maxStringLength)
+		v.SetString(sh.apply(s))
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			f.walk(v.Index(i), depth, sh)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if field, ok := f.settableField(v, i); ok {
+				f.walk(field, depth, tagShape(t.Field(i)))
+			}
+		}
+	case reflect.Ptr:
+		if !f.shouldFill(depth) {
+			return
+		}
+		p := reflect.New(t.Elem())
+		f.walk(p.Elem(), depth+1, sh)
+		v.Set(p)
+	case reflect.Slice:
+		switch t.Elem().Kind() {
+		case reflect.Uint8:
+			// []byte and []string use a length field rather than the number of elements.
+			if depth < f.maxDepth {
+				var bs []byte
+				randBytes(&bs, f.fzgoSrc, f.maxStringLength)
+				if sh != nil {
+					bs = []byte(sh.apply(string(bs)))
+				}
+				v.SetBytes(bs)
+			}
+			return
+		case reflect.String:
+			if depth < f.maxDepth {
+				var ss []string
+				randStringSlice(&ss, f.fzgoSrc, f.maxStringLength)
+				s := reflect.MakeSlice(t, len(ss), len(ss))
+				for i := range ss {
+					s.Index(i).SetString(sh.apply(ss[i]))
+				}
+				v.Set(s)
+			}
+			return
+		}
+		if !f.shouldFill(depth) {
+			return
+		}
+		n := f.elementCount()
+		s := reflect.MakeSlice(t, n, n)
+		for i := 0; i < n; i++ {
+			f.walk(s.Index(i), depth+1, sh)
+		}
+		v.Set(s)
+	case reflect.Map:
+		if !f.shouldFill(depth) {
+			return
+		}
+		n := f.elementCount()
+		m := reflect.MakeMapWithSize(t, n)
+		for i := 0; i < n; i++ {
+			key := reflect.New(t.Key()).Elem()
+			mapKey := f.mapKey
+			f.mapKey = true
+			f.walk(key, depth+1, sh)
+			f.mapKey = mapKey
+			elem := reflect.New(t.Elem()).Elem()
+			f.walk(elem, depth+1, sh)
+			m.SetMapIndex(key, elem)
+		}
+		v.Set(m)
+	case reflect.Chan:
+		// a chan is buffered with room for its elements, and is not closed.
+		// A chan that can be received from starts with its buffer full.
+		if !f.shouldFill(depth) {
+			return
+		}
+		n := f.elementCount()
+		ch := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, t.Elem()), n)
+		if t.ChanDir()&reflect.RecvDir != 0 {
+			for i := 0; i < n; i++ {
+				elem := reflect.New(t.Elem()).Elem()
+				f.walk(elem, depth+1, sh)
+				ch.Send(elem)
+			}
+		}
+		v.Set(ch)
+	case reflect.Interface:
+		choices := interfaceChoices(t, f.mapKey)
+		if len(choices) == 0 || !f.shouldFill(depth) {
+			return
+		}
+		elem := reflect.New(choices[f.Choose(len(choices))]).Elem()
+		f.walk(elem, depth+1, sh)
+		v.Set(elem)
+	default:
+		// funcs and unsafe pointers are left as nil.
+	}
+}
+
+// settableField returns the ith field of the struct v, and reports whether we fill it.
+// An unexported field is only filled if FillUnexported allows its package, in which case
+// we set it via package unsafe.
+func (f *Fuzzer) settableField(v reflect.Value, i int) (reflect.Value, bool) {
+	field := v.Field(i)
+	if field.CanSet() {
+		return field, true
+	}
+	sf := v.Type().Field(i)
+	if !f.unexportedPkgs[sf.PkgPath] || !v.CanAddr() {
+		return field, false
+	}
+	return reflect.NewAt(sf.Type, unsafe.Pointer(field.UnsafeAddr())).Elem(), true
+}
+
+// shouldFill reports whether to fill a pointer, map, slice, chan or interface at depth,
+// rather than leave it nil. It draws a byte from the input if there is a nil chance.
+func (f *Fuzzer) shouldFill(depth int) bool {
+	if depth >= f.maxDepth {
+		return false
+	}
+	if f.nilChance == 0 {
+		return true
+	}
+	return int(f.fzgoSrc.Byte()) >= f.nilChance
+}
+
+// elementCount returns the number of elements for a map, slice or chan. It draws a byte
+// from the input if there is a range for the number of elements. The count is limited
+// to the remaining bytes in the input, which bounds the size of the values
+// we create from a short input.
+func (f *Fuzzer) elementCount() int {
+	n := f.minElements
+	if f.maxElements > f.minElements {
+		n += int(f.fzgoSrc.Byte()) % (f.maxElements - f.minElements + 1)
+	}
+	if n > f.fzgoSrc.Remaining() {
+		n = f.fzgoSrc.Remaining()
+	}
+	return n
+}
+
+// interfaceChoices returns the types from interfaceValues that implement the interface t.
+// For an interface within a map key, only the comparable types are returned,
+// given a map key holding a slice or map panics.
+func interfaceChoices(t reflect.Type, comparable bool) []reflect.Type {
+	var result []reflect.Type
+	for _, c := range interfaceValues {
+		if c.Implements(t) && (!comparable || c.Comparable()) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
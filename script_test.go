@@ -1,7 +1,10 @@
 package main
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/rogpeppe/go-internal/gotooltest"
@@ -24,9 +27,36 @@ func (m fzgoTestingMain) Run() int {
 }
 
 func TestScripts(t *testing.T) {
-	p := testscript.Params{Dir: "testscripts"}
+	p := testscript.Params{Dir: "testscripts", Setup: setupLocalRandparam}
 	if err := gotooltest.Setup(&p); err != nil {
 		t.Fatal(err)
 	}
 	testscript.Run(t, p)
 }
+
+// setupLocalRandparam places our local copy of fzgo/randparam in the test's GOPATH, which is what
+// rich signature wrappers import. Fetching it instead would get the published version,
+// which might not have the APIs the wrappers from this version of fzgo use.
+func setupLocalRandparam(env *testscript.Env) error {
+	dst := filepath.Join(env.WorkDir, "gopath", "src", "github.com", "thepudds", "fzgo", "randparam")
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join("randparam", "*.go"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dst, filepath.Base(file)), src, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}